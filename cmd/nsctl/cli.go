@@ -0,0 +1,170 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Nexenta/go-nexentastor/pkg/logger"
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+)
+
+// cli - state shared by nsctl commands
+type cli struct {
+	conn   connection
+	output string
+	log    *logrus.Entry
+	stdout io.Writer
+	stderr io.Writer
+
+	// created on first use, resolver is used for multi-address configuration only
+	resolver *ns.Resolver
+}
+
+// nodes returns providers for all configured NexentaStor addresses
+func (c *cli) nodes() ([]ns.ProviderInterface, error) {
+	if c.resolver != nil {
+		return c.resolver.Nodes, nil
+	}
+
+	profile, err := c.conn.load()
+	if err != nil {
+		return nil, err
+	}
+
+	args, err := profile.ResolverArgs(logger.NewLogrus(c.log))
+	if err != nil {
+		return nil, err
+	}
+
+	resolver, err := ns.NewResolver(args)
+	if err != nil {
+		return nil, err
+	}
+
+	c.resolver = resolver
+	return resolver.Nodes, nil
+}
+
+// provider returns NexentaStor which has the dataset (filesystem, volume group, volume or snapshot),
+// for a single configured address no lookup is made
+func (c *cli) provider(datasetPath string) (ns.ProviderInterface, error) {
+	nodes, err := c.nodes()
+	if err != nil {
+		return nil, err
+	} else if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+
+	// snapshot belongs to its filesystem or volume
+	datasetPath = strings.SplitN(datasetPath, "@", 2)[0]
+
+	if nsProvider, err := c.resolver.Resolve(datasetPath); err == nil && nsProvider != nil {
+		return nsProvider, nil
+	}
+	// dataset is a volume group or a volume
+	for _, vgPath := range []string{datasetPath, path.Dir(datasetPath)} {
+		if nsProvider, err := c.resolver.ResolveFromVg(vgPath); err == nil && nsProvider != nil {
+			return nsProvider, nil
+		}
+	}
+
+	return nil, fmt.Errorf(
+		"No NexentaStor found with dataset '%s' among: %s", datasetPath, strings.Join(c.conn.profile.Addresses, ","))
+}
+
+// flagSet creates flag set for a leaf command, usage describes positional arguments
+func (c *cli) flagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet("nsctl "+name, flag.ContinueOnError)
+	// errors and usage are printed by the caller, see flagSetUsage
+	fs.SetOutput(ioutil.Discard)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: nsctl %s [flags] %s\n", name, usage)
+	}
+	return fs
+}
+
+// parse parses flags and exactly argsCount positional arguments, flags may follow arguments
+func (c *cli) parse(fs *flag.FlagSet, args []string, argsCount int) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, &usageError{usage: flagSetUsage(fs), err: err}
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	if len(positional) != argsCount {
+		return nil, &usageError{
+			usage: flagSetUsage(fs),
+			err:   fmt.Errorf("expected %d argument(s), got %d", argsCount, len(positional)),
+		}
+	}
+
+	return positional, nil
+}
+
+// requireFlags returns usage error if any of the flags is not set
+func requireFlags(fs *flag.FlagSet, names ...string) error {
+	setFlags := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+
+	for _, name := range names {
+		if !setFlags[name] {
+			return &usageError{usage: flagSetUsage(fs), err: fmt.Errorf("flag -%s is required", name)}
+		}
+	}
+
+	return nil
+}
+
+// flagSetUsage returns usage and flag defaults of the flag set
+func flagSetUsage(fs *flag.FlagSet) string {
+	var b strings.Builder
+	output := fs.Output()
+	fs.SetOutput(&b)
+	fs.Usage()
+	fs.PrintDefaults()
+	fs.SetOutput(output)
+	return b.String()
+}
+
+// parseSize parses size in bytes, K, M, G and T suffixes are powers of 1024
+func parseSize(size string) (int64, error) {
+	multiplier := int64(1)
+	value := strings.ToUpper(strings.TrimSuffix(strings.ToUpper(size), "B"))
+	if value != "" {
+		switch value[len(value)-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		case 'T':
+			multiplier = 1 << 40
+		}
+		if multiplier != 1 {
+			value = value[:len(value)-1]
+		}
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("Invalid size '%s', expected bytes or a number with K, M, G or T suffix", size)
+	}
+
+	return n * multiplier, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"0":     0,
+		"512":   512,
+		"512B":  512,
+		"1K":    1 << 10,
+		"1kb":   1 << 10,
+		"10M":   10 << 20,
+		"10G":   10 << 30,
+		"2T":    2 << 40,
+		"100gB": 100 << 30,
+	}
+	for size, expected := range tests {
+		if n, err := parseSize(size); err != nil || n != expected {
+			t.Errorf("parseSize('%s'): expected %d, got %d, error: %v", size, expected, n, err)
+		}
+	}
+
+	for _, size := range []string{"", "G", "-1G", "1.5G", "10P", "ten"} {
+		if n, err := parseSize(size); err == nil {
+			t.Errorf("parseSize('%s'): expected an error, got %d", size, n)
+		}
+	}
+}
+
+func TestRequireFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("a", "", "")
+	fs.String("b", "", "")
+	if err := fs.Parse([]string{"-a", ""}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := requireFlags(fs, "a"); err != nil {
+		t.Errorf("expected flag set to an empty value to be accepted, got: %s", err)
+	}
+	if err := requireFlags(fs, "a", "b"); err == nil {
+		t.Error("expected an error for missing flag")
+	}
+}
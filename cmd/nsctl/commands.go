@@ -0,0 +1,907 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Nexenta/go-nexentastor/pkg/config"
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+)
+
+var filesystemCommand = &command{
+	name:        "filesystem",
+	description: "list, create and destroy filesystems",
+	subcommands: []*command{
+		{name: "list", description: "list children of a filesystem", run: filesystemList},
+		{name: "get", description: "show a filesystem", run: filesystemGet},
+		{name: "create", description: "create a filesystem", run: filesystemCreate},
+		{name: "update", description: "change filesystem properties", run: filesystemUpdate},
+		{name: "destroy", description: "destroy a filesystem", run: filesystemDestroy},
+	},
+}
+
+func printFilesystems(c *cli, filesystems []ns.Filesystem) error {
+	t := &table{header: []string{"PATH", "MOUNTPOINT", "USED", "AVAILABLE", "QUOTA", "NFS", "SMB"}}
+	for _, fs := range filesystems {
+		t.add(
+			fs.Path,
+			fs.MountPoint,
+			formatBytes(fs.BytesUsed),
+			formatBytes(fs.BytesAvailable),
+			formatBytes(fs.ReferencedQuotaSize),
+			fs.SharedOverNfs,
+			fs.SharedOverSmb,
+		)
+	}
+	return c.print(filesystems, t)
+}
+
+func filesystemList(c *cli, args []string) error {
+	fs := c.flagSet("filesystem list", "<parent>")
+	recursive := fs.Bool("recursive", false, "list all descendants, not only direct children")
+	name := fs.String("name", "", "filesystem name pattern, e.g. 'pvc-*'")
+	args, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	nsProvider, err := c.provider(args[0])
+	if err != nil {
+		return err
+	}
+
+	filesystems, err := nsProvider.ListFilesystems(args[0], ns.ListOptions{
+		Recursive:   *recursive,
+		NamePattern: *name,
+	})
+	if err != nil {
+		return err
+	}
+
+	return printFilesystems(c, filesystems)
+}
+
+func filesystemGet(c *cli, args []string) error {
+	args, err := c.parse(c.flagSet("filesystem get", "<path>"), args, 1)
+	if err != nil {
+		return err
+	}
+
+	nsProvider, err := c.provider(args[0])
+	if err != nil {
+		return err
+	}
+
+	filesystem, err := nsProvider.GetFilesystem(args[0])
+	if err != nil {
+		return err
+	}
+
+	return printFilesystems(c, []ns.Filesystem{filesystem})
+}
+
+func filesystemCreate(c *cli, args []string) error {
+	fs := c.flagSet("filesystem create", "<path>")
+	quota := fs.String("quota", "", "referenced quota size, e.g. 10G")
+	args, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	params := ns.CreateFilesystemParams{Path: args[0]}
+	if *quota != "" {
+		if params.ReferencedQuotaSize, err = parseSize(*quota); err != nil {
+			return err
+		}
+	}
+
+	nsProvider, err := c.provider(parentPath(args[0]))
+	if err != nil {
+		return err
+	}
+
+	if err := nsProvider.CreateFilesystem(params); err != nil {
+		return err
+	}
+
+	return c.printDone("filesystem '%s' created", args[0])
+}
+
+func filesystemUpdate(c *cli, args []string) error {
+	fs := c.flagSet("filesystem update", "<path>")
+	quota := fs.String("quota", "", "referenced quota size, e.g. 10G (required), the quota cannot be removed")
+	args, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if err := requireFlags(fs, "quota"); err != nil {
+		return err
+	}
+
+	params := ns.UpdateFilesystemParams{}
+	if params.ReferencedQuotaSize, err = parseSize(*quota); err != nil {
+		return err
+	} else if params.ReferencedQuotaSize == 0 {
+		// zero value is omitted from the request, so NexentaStor would keep the current quota
+		return fmt.Errorf("Quota must be greater than 0, the quota cannot be removed")
+	}
+
+	nsProvider, err := c.provider(args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := nsProvider.UpdateFilesystem(args[0], params); err != nil {
+		return err
+	}
+
+	return c.printDone("filesystem '%s' updated", args[0])
+}
+
+func filesystemDestroy(c *cli, args []string) error {
+	fs := c.flagSet("filesystem destroy", "<path>")
+	snapshots := fs.Bool("snapshots", false, "destroy filesystem snapshots as well")
+	promote := fs.Bool("promote", false, "promote the most recent snapshot clone, if any, to keep the snapshots")
+	args, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	nsProvider, err := c.provider(args[0])
+	if err != nil {
+		return err
+	}
+
+	err = nsProvider.DestroyFilesystem(args[0], ns.DestroyFilesystemParams{
+		DestroySnapshots:               *snapshots,
+		PromoteMostRecentCloneIfExists: *promote,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.printDone("filesystem '%s' destroyed", args[0])
+}
+
+var volumeCommand = &command{
+	name:        "volume",
+	description: "list, create, resize and destroy volumes",
+	subcommands: []*command{
+		{name: "list", description: "list volumes of a volume group", run: volumeList},
+		{name: "get", description: "show a volume", run: volumeGet},
+		{name: "create", description: "create a volume", run: volumeCreate},
+		{name: "resize", description: "change volume size", run: volumeResize},
+		{name: "destroy", description: "destroy a volume", run: volumeDestroy},
+	},
+}
+
+func printVolumes(c *cli, volumes []ns.Volume) error {
+	t := &table{header: []string{"PATH", "SIZE", "USED", "BLOCKSIZE", "SPARSE", "COMPRESSION"}}
+	for _, volume := range volumes {
+		t.add(
+			volume.Path,
+			formatBytes(volume.VolumeSize),
+			formatBytes(volume.BytesUsed),
+			formatBytes(volume.VolumeBlockSize),
+			volume.SparseVolume,
+			volume.CompressionMode,
+		)
+	}
+	return c.print(volumes, t)
+}
+
+func volumeList(c *cli, args []string) error {
+	fs := c.flagSet("volume list", "<volumeGroup>")
+	name := fs.String("name", "", "volume name pattern, e.g. 'pvc-*'")
+	args, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	nsProvider, err := c.provider(args[0])
+	if err != nil {
+		return err
+	}
+
+	volumes, err := nsProvider.ListVolumes(args[0], ns.ListOptions{NamePattern: *name})
+	if err != nil {
+		return err
+	}
+
+	return printVolumes(c, volumes)
+}
+
+func volumeGet(c *cli, args []string) error {
+	args, err := c.parse(c.flagSet("volume get", "<path>"), args, 1)
+	if err != nil {
+		return err
+	}
+
+	nsProvider, err := c.provider(args[0])
+	if err != nil {
+		return err
+	}
+
+	volume, err := nsProvider.GetVolume(args[0])
+	if err != nil {
+		return err
+	}
+
+	return printVolumes(c, []ns.Volume{volume})
+}
+
+func volumeCreate(c *cli, args []string) error {
+	fs := c.flagSet("volume create", "<path>")
+	size := fs.String("size", "", "volume size, e.g. 10G (required)")
+	blockSize := fs.String("block-size", "", "volume block size, e.g. 32K")
+	sparse := fs.Bool("sparse", false, "thin provisioned volume")
+	compression := fs.String("compression", "", "compression mode, e.g. lz4")
+	args, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if err := requireFlags(fs, "size"); err != nil {
+		return err
+	}
+
+	params := ns.CreateVolumeParams{
+		Path:            args[0],
+		SparseVolume:    *sparse,
+		CompressionMode: *compression,
+	}
+	if params.VolumeSize, err = parseSize(*size); err != nil {
+		return err
+	}
+	if *blockSize != "" {
+		if params.VolumeBlockSize, err = parseSize(*blockSize); err != nil {
+			return err
+		}
+	}
+
+	nsProvider, err := c.provider(parentPath(args[0]))
+	if err != nil {
+		return err
+	}
+
+	if err := nsProvider.CreateVolume(params); err != nil {
+		return err
+	}
+
+	return c.printDone("volume '%s' created", args[0])
+}
+
+func volumeResize(c *cli, args []string) error {
+	fs := c.flagSet("volume resize", "<path>")
+	size := fs.String("size", "", "new volume size, e.g. 20G (required)")
+	force := fs.Bool("force", false, "allow to shrink the volume")
+	args, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if err := requireFlags(fs, "size"); err != nil {
+		return err
+	}
+
+	newSize, err := parseSize(*size)
+	if err != nil {
+		return err
+	}
+
+	nsProvider, err := c.provider(args[0])
+	if err != nil {
+		return err
+	}
+
+	result, err := nsProvider.ResizeVolume(args[0], newSize, ns.ResizeVolumeParams{Force: *force})
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"PATH", "PREVIOUS SIZE", "SIZE", "LUN MAPPED"}}
+	t.add(args[0], formatBytes(result.PreviousSize), formatBytes(result.VolumeSize), result.IsLunMapped())
+	return c.print(result, t)
+}
+
+func volumeDestroy(c *cli, args []string) error {
+	fs := c.flagSet("volume destroy", "<path>")
+	snapshots := fs.Bool("snapshots", false, "destroy volume snapshots as well")
+	promote := fs.Bool("promote", false, "promote the most recent snapshot clone, if any, to keep the snapshots")
+	args, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	nsProvider, err := c.provider(args[0])
+	if err != nil {
+		return err
+	}
+
+	err = nsProvider.DestroyVolume(args[0], ns.DestroyVolumeParams{
+		DestroySnapshots:               *snapshots,
+		PromoteMostRecentCloneIfExists: *promote,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.printDone("volume '%s' destroyed", args[0])
+}
+
+var snapshotCommand = &command{
+	name:        "snapshot",
+	description: "list, create, clone and destroy snapshots",
+	subcommands: []*command{
+		{name: "list", description: "list snapshots of a filesystem or a volume", run: snapshotList},
+		{name: "create", description: "create a snapshot", run: snapshotCreate},
+		{name: "clone", description: "clone a filesystem snapshot to a new filesystem", run: snapshotClone},
+		{name: "destroy", description: "destroy a snapshot", run: snapshotDestroy},
+	},
+}
+
+func snapshotList(c *cli, args []string) error {
+	fs := c.flagSet("snapshot list", "<filesystem|volume>")
+	recursive := fs.Bool("recursive", false, "list snapshots of all descendants as well")
+	args, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	nsProvider, err := c.provider(args[0])
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := nsProvider.ListSnapshots(args[0], ns.ListOptions{Recursive: *recursive})
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"PATH", "CREATED", "CLONES"}}
+	for _, snapshot := range snapshots {
+		t.add(snapshot.Path, snapshot.CreationTime.Format("2006-01-02 15:04:05"), len(snapshot.Clones))
+	}
+	return c.print(snapshots, t)
+}
+
+func snapshotCreate(c *cli, args []string) error {
+	args, err := c.parse(c.flagSet("snapshot create", "<filesystem@snapshot>"), args, 1)
+	if err != nil {
+		return err
+	}
+
+	nsProvider, err := c.provider(args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := nsProvider.CreateSnapshot(ns.CreateSnapshotParams{Path: args[0]}); err != nil {
+		return err
+	}
+
+	return c.printDone("snapshot '%s' created", args[0])
+}
+
+func snapshotClone(c *cli, args []string) error {
+	fs := c.flagSet("snapshot clone", "<filesystem@snapshot> <targetFilesystem>")
+	quota := fs.String("quota", "", "referenced quota size of the clone, e.g. 10G")
+	args, err := c.parse(fs, args, 2)
+	if err != nil {
+		return err
+	}
+
+	params := ns.CloneSnapshotParams{TargetPath: args[1]}
+	if *quota != "" {
+		if params.ReferencedQuotaSize, err = parseSize(*quota); err != nil {
+			return err
+		}
+	}
+
+	nsProvider, err := c.provider(args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := nsProvider.CloneSnapshot(args[0], params); err != nil {
+		return err
+	}
+
+	return c.printDone("snapshot '%s' cloned to '%s'", args[0], args[1])
+}
+
+func snapshotDestroy(c *cli, args []string) error {
+	args, err := c.parse(c.flagSet("snapshot destroy", "<filesystem@snapshot>"), args, 1)
+	if err != nil {
+		return err
+	}
+
+	nsProvider, err := c.provider(args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := nsProvider.DestroySnapshot(args[0]); err != nil {
+		return err
+	}
+
+	return c.printDone("snapshot '%s' destroyed", args[0])
+}
+
+var shareCommand = &command{
+	name:        "share",
+	description: "share filesystems over NFS and SMB",
+	subcommands: []*command{
+		{
+			name:        "nfs",
+			description: "manage NFS shares",
+			subcommands: []*command{
+				{name: "create", description: "share a filesystem over NFS", run: shareNfsCreate},
+				{name: "delete", description: "stop sharing a filesystem over NFS", run: shareNfsDelete},
+			},
+		},
+		{
+			name:        "smb",
+			description: "manage SMB shares",
+			subcommands: []*command{
+				{name: "create", description: "share a filesystem over SMB", run: shareSmbCreate},
+				{name: "get", description: "show SMB share name of a filesystem", run: shareSmbGet},
+				{name: "delete", description: "stop sharing a filesystem over SMB", run: shareSmbDelete},
+			},
+		},
+	},
+}
+
+func shareNfsCreate(c *cli, args []string) error {
+	fs := c.flagSet("share nfs create", "<filesystem>")
+	readWrite := fs.String("rw", "", "comma-separated list of hosts or networks with read-write access")
+	readOnly := fs.String("ro", "", "comma-separated list of hosts or networks with read-only access")
+	args, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	params := ns.CreateNfsShareParams{Filesystem: args[0]}
+	if params.ReadWriteList, err = parseNfsRules(*readWrite); err != nil {
+		return err
+	}
+	if params.ReadOnlyList, err = parseNfsRules(*readOnly); err != nil {
+		return err
+	}
+
+	nsProvider, err := c.provider(args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := nsProvider.CreateNfsShare(params); err != nil {
+		return err
+	}
+
+	return c.printDone("filesystem '%s' shared over NFS", args[0])
+}
+
+// parseNfsRules parses comma-separated list of hosts ("host1", "10.3.1.1", "*") or networks ("10.3.0.0/16")
+func parseNfsRules(list string) ([]ns.NfsRuleList, error) {
+	var rules []ns.NfsRuleList
+	for _, entity := range config.SplitAddresses(list) {
+		rule := ns.NfsRuleList{Etype: "fqdn", Entity: entity}
+		if parts := strings.SplitN(entity, "/", 2); len(parts) == 2 {
+			mask, err := strconv.Atoi(parts[1])
+			if err != nil || mask < 0 || mask > 32 {
+				return nil, fmt.Errorf("Invalid network mask in NFS rule '%s'", entity)
+			}
+			rule = ns.NfsRuleList{Etype: "network", Entity: parts[0], Mask: mask}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func shareNfsDelete(c *cli, args []string) error {
+	args, err := c.parse(c.flagSet("share nfs delete", "<filesystem>"), args, 1)
+	if err != nil {
+		return err
+	}
+
+	nsProvider, err := c.provider(args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := nsProvider.DeleteNfsShare(args[0]); err != nil {
+		return err
+	}
+
+	return c.printDone("filesystem '%s' is not shared over NFS anymore", args[0])
+}
+
+func shareSmbCreate(c *cli, args []string) error {
+	fs := c.flagSet("share smb create", "<filesystem>")
+	shareName := fs.String("name", "", "share name, default one is generated from the filesystem path")
+	args, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	nsProvider, err := c.provider(args[0])
+	if err != nil {
+		return err
+	}
+
+	err = nsProvider.CreateSmbShare(ns.CreateSmbShareParams{Filesystem: args[0], ShareName: *shareName})
+	if err != nil {
+		return err
+	}
+
+	return c.printDone("filesystem '%s' shared over SMB", args[0])
+}
+
+func shareSmbGet(c *cli, args []string) error {
+	args, err := c.parse(c.flagSet("share smb get", "<filesystem>"), args, 1)
+	if err != nil {
+		return err
+	}
+
+	nsProvider, err := c.provider(args[0])
+	if err != nil {
+		return err
+	}
+
+	shareName, err := nsProvider.GetSmbShareName(args[0])
+	if err != nil {
+		return err
+	}
+
+	share := struct {
+		Filesystem string `json:"filesystem"`
+		ShareName  string `json:"shareName"`
+	}{args[0], shareName}
+
+	t := &table{header: []string{"FILESYSTEM", "SHARE NAME"}}
+	t.add(share.Filesystem, share.ShareName)
+	return c.print(share, t)
+}
+
+func shareSmbDelete(c *cli, args []string) error {
+	args, err := c.parse(c.flagSet("share smb delete", "<filesystem>"), args, 1)
+	if err != nil {
+		return err
+	}
+
+	nsProvider, err := c.provider(args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := nsProvider.DeleteSmbShare(args[0]); err != nil {
+		return err
+	}
+
+	return c.printDone("filesystem '%s' is not shared over SMB anymore", args[0])
+}
+
+var lunCommand = &command{
+	name:        "lun",
+	description: "list, create and destroy LUN mappings",
+	subcommands: []*command{
+		{name: "list", description: "list LUN mappings of all nodes", run: lunList},
+		{name: "create", description: "map a volume to a host group", run: lunCreate},
+		{name: "destroy", description: "unmap a volume", run: lunDestroy},
+	},
+}
+
+// nodeLunMapping - LUN mapping with NexentaStor address it's found on
+type nodeLunMapping struct {
+	Node string `json:"node"`
+	ns.LunMapping
+}
+
+func lunList(c *cli, args []string) error {
+	fs := c.flagSet("lun list", "")
+	params := ns.ListLunMappingsParams{}
+	fs.StringVar(&params.Volume, "volume", "", "volume path")
+	fs.StringVar(&params.HostGroup, "host-group", "", "host group name")
+	fs.StringVar(&params.TargetGroup, "target-group", "", "target group name")
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	nodes, err := c.nodes()
+	if err != nil {
+		return err
+	}
+
+	lunMappings := []nodeLunMapping{}
+	t := &table{header: []string{"NODE", "ID", "VOLUME", "TARGET GROUP", "HOST GROUP", "LUN"}}
+	for _, node := range nodes {
+		nodeLunMappings, err := node.ListLunMappings(params)
+		if err != nil {
+			return fmt.Errorf("%s: %s", node, err)
+		}
+		for _, lunMapping := range nodeLunMappings {
+			lunMappings = append(lunMappings, nodeLunMapping{Node: fmt.Sprint(node), LunMapping: lunMapping})
+			t.add(node, lunMapping.Id, lunMapping.Volume, lunMapping.TargetGroup, lunMapping.HostGroup, lunMapping.Lun)
+		}
+	}
+
+	return c.print(lunMappings, t)
+}
+
+func lunCreate(c *cli, args []string) error {
+	fs := c.flagSet("lun create", "<volume>")
+	hostGroup := fs.String("host-group", "", "host group name (required)")
+	targetGroup := fs.String("target-group", "", "target group name (required)")
+	lun := fs.Int("lun", -1, "LUN number, NexentaStor allocates one if not set")
+	args, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if err := requireFlags(fs, "host-group", "target-group"); err != nil {
+		return err
+	}
+
+	params := ns.CreateLunMappingParams{
+		Volume:      args[0],
+		HostGroup:   *hostGroup,
+		TargetGroup: *targetGroup,
+	}
+	if *lun >= 0 {
+		params.Lun = lun
+	}
+
+	nsProvider, err := c.provider(args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := nsProvider.CreateLunMapping(params); err != nil {
+		return err
+	}
+
+	lunMapping, err := findLunMapping(nsProvider, ns.ListLunMappingsParams{
+		Volume:      args[0],
+		HostGroup:   *hostGroup,
+		TargetGroup: *targetGroup,
+	}, "")
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"ID", "VOLUME", "TARGET GROUP", "HOST GROUP", "LUN"}}
+	t.add(lunMapping.Id, lunMapping.Volume, lunMapping.TargetGroup, lunMapping.HostGroup, lunMapping.Lun)
+	return c.print(lunMapping, t)
+}
+
+func lunDestroy(c *cli, args []string) error {
+	fs := c.flagSet("lun destroy", "<volume>")
+	params := ns.ListLunMappingsParams{}
+	fs.StringVar(&params.HostGroup, "host-group", "", "host group name (required if -id is not set)")
+	fs.StringVar(&params.TargetGroup, "target-group", "", "target group name")
+	id := fs.String("id", "", "LUN mapping ID")
+	args, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if *id == "" {
+		if err := requireFlags(fs, "host-group"); err != nil {
+			return err
+		}
+	}
+	params.Volume = args[0]
+
+	nsProvider, err := c.provider(args[0])
+	if err != nil {
+		return err
+	}
+
+	lunMapping, err := findLunMapping(nsProvider, params, *id)
+	if err != nil {
+		return err
+	}
+
+	if err := nsProvider.DestroyLunMapping(lunMapping.Id); err != nil {
+		return err
+	}
+
+	return c.printDone("LUN mapping '%s' of volume '%s' destroyed", lunMapping.Id, args[0])
+}
+
+// findLunMapping returns the only LUN mapping matching the filters and the ID (if set),
+// fails if the filters match more than one mapping
+func findLunMapping(
+	nsProvider ns.ProviderInterface, params ns.ListLunMappingsParams, id string,
+) (ns.LunMapping, error) {
+	lunMappings, err := nsProvider.ListLunMappings(params)
+	if err != nil {
+		return ns.LunMapping{}, err
+	}
+
+	var found []ns.LunMapping
+	for _, lunMapping := range lunMappings {
+		if id == "" || lunMapping.Id == id {
+			found = append(found, lunMapping)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return ns.LunMapping{}, fmt.Errorf(
+			"No LUN mapping of volume '%s' with %s found", params.Volume, formatLunFilters(params, id))
+	case 1:
+		return found[0], nil
+	}
+
+	ids := make([]string, len(found))
+	for i, lunMapping := range found {
+		ids[i] = lunMapping.Id
+	}
+	return ns.LunMapping{}, fmt.Errorf(
+		"%d LUN mappings of volume '%s' with %s found: %s, use -target-group or -id to select one",
+		len(found), params.Volume, formatLunFilters(params, id), strings.Join(ids, ", "))
+}
+
+// formatLunFilters returns LUN mapping filters for error messages
+func formatLunFilters(params ns.ListLunMappingsParams, id string) string {
+	var filters []string
+	if params.HostGroup != "" {
+		filters = append(filters, fmt.Sprintf("host group '%s'", params.HostGroup))
+	}
+	if params.TargetGroup != "" {
+		filters = append(filters, fmt.Sprintf("target group '%s'", params.TargetGroup))
+	}
+	if id != "" {
+		filters = append(filters, fmt.Sprintf("ID '%s'", id))
+	}
+	return strings.Join(filters, ", ")
+}
+
+var poolCommand = &command{
+	name:        "pool",
+	description: "list pools",
+	subcommands: []*command{
+		{name: "list", description: "list pools of all nodes", run: poolList},
+	},
+}
+
+// nodePool - pool with NexentaStor address it's found on
+type nodePool struct {
+	Node string `json:"node"`
+	ns.Pool
+}
+
+func poolList(c *cli, args []string) error {
+	if _, err := c.parse(c.flagSet("pool list", ""), args, 0); err != nil {
+		return err
+	}
+
+	nodes, err := c.nodes()
+	if err != nil {
+		return err
+	}
+
+	pools := []nodePool{}
+	t := &table{header: []string{"NODE", "NAME"}}
+	for _, node := range nodes {
+		nodePools, err := node.GetPools()
+		if err != nil {
+			return fmt.Errorf("%s: %s", node, err)
+		}
+		for _, pool := range nodePools {
+			pools = append(pools, nodePool{Node: fmt.Sprint(node), Pool: pool})
+			t.add(node, pool.Name)
+		}
+	}
+
+	return c.print(pools, t)
+}
+
+var clusterCommand = &command{
+	name:        "cluster",
+	description: "show RSF clusters",
+	subcommands: []*command{
+		{name: "list", description: "list RSF clusters of all nodes", run: clusterList},
+		{name: "check", description: "check that all configured nodes belong to one cluster", run: clusterCheck},
+	},
+}
+
+// nodeRSFCluster - RSF cluster with NexentaStor address it's found on
+type nodeRSFCluster struct {
+	Node string `json:"node"`
+	ns.RSFCluster
+}
+
+func clusterList(c *cli, args []string) error {
+	if _, err := c.parse(c.flagSet("cluster list", ""), args, 0); err != nil {
+		return err
+	}
+
+	nodes, err := c.nodes()
+	if err != nil {
+		return err
+	}
+
+	clusters := []nodeRSFCluster{}
+	t := &table{header: []string{"NODE", "CLUSTER"}}
+	for _, node := range nodes {
+		nodeClusters, err := node.GetRSFClusters()
+		if err != nil {
+			return fmt.Errorf("%s: %s", node, err)
+		}
+		for _, cluster := range nodeClusters {
+			clusters = append(clusters, nodeRSFCluster{Node: fmt.Sprint(node), RSFCluster: cluster})
+			t.add(node, cluster.Name)
+		}
+	}
+
+	return c.print(clusters, t)
+}
+
+func clusterCheck(c *cli, args []string) error {
+	if _, err := c.parse(c.flagSet("cluster check", ""), args, 0); err != nil {
+		return err
+	}
+
+	if _, err := c.nodes(); err != nil {
+		return err
+	}
+
+	isCluster, err := c.resolver.IsCluster()
+	if err != nil {
+		return err
+	}
+
+	result := struct {
+		Nodes     []string `json:"nodes"`
+		IsCluster bool     `json:"isCluster"`
+	}{c.conn.profile.Addresses, isCluster}
+
+	t := &table{header: []string{"NODES", "CLUSTER"}}
+	t.add(strings.Join(result.Nodes, ","), result.IsCluster)
+	return c.print(result, t)
+}
+
+var systemCommand = &command{
+	name:        "system",
+	description: "show appliance information",
+	subcommands: []*command{
+		{name: "info", description: "show version, hostname and GUID of all nodes", run: systemInfo},
+	},
+}
+
+// nodeSystemInfo - system information with NexentaStor address it's received from
+type nodeSystemInfo struct {
+	Node string `json:"node"`
+	ns.SystemInfo
+}
+
+func systemInfo(c *cli, args []string) error {
+	if _, err := c.parse(c.flagSet("system info", ""), args, 0); err != nil {
+		return err
+	}
+
+	nodes, err := c.nodes()
+	if err != nil {
+		return err
+	}
+
+	infos := []nodeSystemInfo{}
+	t := &table{header: []string{"NODE", "VERSION", "HOSTNAME", "GUID"}}
+	for _, node := range nodes {
+		info, err := node.GetSystemInfo()
+		if err != nil {
+			return fmt.Errorf("%s: %s", node, err)
+		}
+		infos = append(infos, nodeSystemInfo{Node: fmt.Sprint(node), SystemInfo: info})
+		t.add(node, info.Version, info.Hostname, info.GUID)
+	}
+
+	return c.print(infos, t)
+}
+
+// parentPath returns parent dataset path, used to find NexentaStor to create a dataset on
+func parentPath(datasetPath string) string {
+	if i := strings.LastIndex(datasetPath, "/"); i > 0 {
+		return datasetPath[:i]
+	}
+	return datasetPath
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Nexenta/go-nexentastor/pkg/config"
+)
+
+// default config file in user's home directory, used if exists
+const defaultConfigFile = ".nsctl.yaml"
+
+// profile name used when there is no config file, so connection settings come from environment and flags
+const noConfigProfile = "default"
+
+// connection - NexentaStor connection flags, applied on top of the profile loaded by config package
+type connection struct {
+	flags *flag.FlagSet
+
+	file               string
+	profileName        string
+	address            string
+	username           string
+	password           string
+	insecureSkipVerify bool
+
+	// loaded on first use, see load()
+	profile *config.Profile
+}
+
+func (conn *connection) registerFlags(fs *flag.FlagSet) {
+	conn.flags = fs
+	fs.StringVar(&conn.file, "config", "", fmt.Sprintf(
+		"config file with connection profiles, default: $%s or ~/%s", config.EnvConfig, defaultConfigFile))
+	fs.StringVar(&conn.profileName, "profile", "", fmt.Sprintf(
+		"connection profile of the config file ($%s)", config.EnvProfile))
+	fs.StringVar(&conn.address, "address", "", fmt.Sprintf(
+		"comma-separated NexentaStor addresses, e.g. https://10.3.199.252:8443 ($%s)", config.EnvAddress))
+	fs.StringVar(&conn.username, "username", "", fmt.Sprintf(
+		"NexentaStor API username ($%s)", config.EnvUsername))
+	fs.StringVar(&conn.password, "password", "", fmt.Sprintf(
+		"NexentaStor API password ($%s)", config.EnvPassword))
+	fs.BoolVar(&conn.insecureSkipVerify, "insecure", false, fmt.Sprintf(
+		"skip NexentaStor TLS certificate verification ($%s)", config.EnvInsecureSkipVerify))
+}
+
+// load reads the profile from config file, $NEXENTASTOR_* environment variables and flags,
+// flags have the highest priority
+func (conn *connection) load() (*config.Profile, error) {
+	if conn.profile != nil {
+		return conn.profile, nil
+	}
+
+	cfg, err := conn.readFile()
+	if err != nil {
+		return nil, err
+	}
+
+	setFlags := map[string]bool{}
+	conn.flags.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+
+	profile, err := cfg.ProfileWithOverrides(conn.profileName, func(p *config.Profile) {
+		if setFlags["address"] {
+			p.Addresses = config.SplitAddresses(conn.address)
+		}
+		if setFlags["username"] || setFlags["password"] {
+			// credentials given on the command line replace the credentials file
+			p.CredentialsFile = ""
+		}
+		if setFlags["username"] {
+			p.Username = conn.username
+		}
+		if setFlags["password"] {
+			p.Password = conn.password
+		}
+		if setFlags["insecure"] {
+			p.TLS.InsecureSkipVerify = conn.insecureSkipVerify
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	conn.profile = profile
+	return profile, nil
+}
+
+// readFile reads config file if specified or if default one exists,
+// config with a single empty profile is returned otherwise
+func (conn *connection) readFile() (*config.Config, error) {
+	path := conn.file
+	if path == "" {
+		path = os.Getenv(config.EnvConfig)
+	}
+	if path == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, defaultConfigFile)
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				path = ""
+			}
+		}
+	}
+
+	if path == "" {
+		return &config.Config{Profiles: map[string]config.Profile{noConfigProfile: {}}}, nil
+	}
+
+	return config.Load(path)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Nexenta/go-nexentastor/pkg/config"
+)
+
+const testConfig = `
+defaultProfile: single
+profiles:
+  single:
+    addresses: [https://10.3.199.254:8443]
+    username: file-user
+    password: file-pass
+  cluster:
+    addresses: [https://10.3.199.252:8443, https://10.3.199.253:8443]
+    credentialsFile: credentials.yaml
+`
+
+// loadTestConnection parses global flags and loads the connection profile
+func loadTestConnection(t *testing.T, args ...string) (*config.Profile, error) {
+	conn := &connection{}
+	fs := flag.NewFlagSet("nsctl", flag.ContinueOnError)
+	conn.registerFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return conn.load()
+}
+
+// setEnv sets environment variables for the test, unsets them when the test is done
+func setEnv(t *testing.T, env map[string]string) {
+	for key, value := range env {
+		os.Setenv(key, value)
+	}
+	t.Cleanup(func() {
+		for key := range env {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func TestConnection(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nsctl-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"config.yaml":      testConfig,
+		"credentials.yaml": "username: operator\npassword: secret\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	configFile := filepath.Join(dir, "config.yaml")
+
+	// default config file is looked up in the home directory
+	setEnv(t, map[string]string{"HOME": dir})
+
+	t.Run("config file", func(t *testing.T) {
+		profile, err := loadTestConnection(t, "-config", configFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(profile.Addresses, []string{"https://10.3.199.254:8443"}) ||
+			profile.Username != "file-user" || profile.Password != "file-pass" {
+			t.Errorf("expected default profile of the config file, got: %+v", profile)
+		}
+	})
+
+	t.Run("environment overrides config file", func(t *testing.T) {
+		setEnv(t, map[string]string{
+			config.EnvConfig:   configFile,
+			config.EnvProfile:  "cluster",
+			config.EnvAddress:  "https://10.3.199.251:8443",
+			config.EnvPassword: "env-pass",
+		})
+
+		profile, err := loadTestConnection(t)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// credentials file of the profile overrides credentials, like in config package
+		if !reflect.DeepEqual(profile.Addresses, []string{"https://10.3.199.251:8443"}) ||
+			profile.Username != "operator" || profile.Password != "secret" {
+			t.Errorf("expected environment variables to be applied, got: %+v", profile)
+		}
+	})
+
+	t.Run("flags override environment", func(t *testing.T) {
+		setEnv(t, map[string]string{
+			config.EnvConfig:             configFile,
+			config.EnvAddress:            "https://10.3.199.251:8443",
+			config.EnvUsername:           "env-user",
+			config.EnvInsecureSkipVerify: "false",
+		})
+
+		profile, err := loadTestConnection(t,
+			"-profile", "cluster",
+			"-address", "https://10.3.199.1:8443, https://10.3.199.2:8443",
+			"-password", "flag-pass",
+			"-insecure",
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedAddresses := []string{"https://10.3.199.1:8443", "https://10.3.199.2:8443"}
+		// credentials flags replace credentials file, values not set by flags are kept
+		if !reflect.DeepEqual(profile.Addresses, expectedAddresses) ||
+			profile.Username != "operator" || profile.Password != "flag-pass" ||
+			profile.CredentialsFile != "" ||
+			!profile.TLS.InsecureSkipVerify {
+			t.Errorf("expected flags to be applied, got: %+v", profile)
+		}
+	})
+
+	t.Run("no config file", func(t *testing.T) {
+		setEnv(t, map[string]string{config.EnvUsername: "env-user"})
+
+		profile, err := loadTestConnection(t, "-address", "https://10.3.199.254:8443", "-password", "flag-pass")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(profile.Addresses, []string{"https://10.3.199.254:8443"}) ||
+			profile.Username != "env-user" || profile.Password != "flag-pass" {
+			t.Errorf("expected environment and flags to be used without config file, got: %+v", profile)
+		}
+
+		if _, err := loadTestConnection(t, "-password", "flag-pass"); err == nil {
+			t.Error("expected an error without NexentaStor address")
+		}
+	})
+
+	t.Run("default config file", func(t *testing.T) {
+		data, err := ioutil.ReadFile(configFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, defaultConfigFile), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(filepath.Join(dir, defaultConfigFile))
+
+		profile, err := loadTestConnection(t)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if profile.Username != "file-user" {
+			t.Errorf("expected ~/%s to be used, got: %+v", defaultConfigFile, profile)
+		}
+	})
+}
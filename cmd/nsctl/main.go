@@ -0,0 +1,143 @@
+// nsctl - command-line tool for NexentaStor operators, built on top of the "ns" package
+//
+// Usage:
+//
+//	nsctl [global flags] <command> <subcommand> [flags] [args]
+//
+// Connection settings are taken from a profile of the config file (see config package), NEXENTASTOR_*
+// environment variables and global flags, in order of increasing priority (see config.go).
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// command - node of nsctl commands tree, either runs a leaf command or holds subcommands
+type command struct {
+	name        string
+	description string
+	run         func(c *cli, args []string) error
+	subcommands []*command
+}
+
+var commands = []*command{
+	filesystemCommand,
+	volumeCommand,
+	snapshotCommand,
+	shareCommand,
+	lunCommand,
+	poolCommand,
+	clusterCommand,
+	systemCommand,
+}
+
+// usageError - invalid command line, usage is printed along with the error
+type usageError struct {
+	usage string
+	err   error
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	c := &cli{stdout: stdout, stderr: stderr}
+
+	globalFlags := flag.NewFlagSet("nsctl", flag.ContinueOnError)
+	globalFlags.SetOutput(stderr)
+	c.conn.registerFlags(globalFlags)
+	output := globalFlags.String("o", outputTable, "output format: table, json or yaml")
+	verbose := globalFlags.Bool("v", false, "log NexentaStor API requests to stderr")
+	globalFlags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: nsctl [global flags] <command> <subcommand> [flags] [args]\n\n")
+		printCommands(stderr, commands)
+		fmt.Fprintf(stderr, "\nGlobal flags:\n")
+		globalFlags.PrintDefaults()
+	}
+
+	if err := globalFlags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	switch *output {
+	case outputTable, outputJSON, outputYAML:
+		c.output = *output
+	default:
+		fmt.Fprintf(stderr, "Error: unknown output format '%s', expected: table, json or yaml\n", *output)
+		return 2
+	}
+
+	log := logrus.New()
+	log.SetOutput(stderr)
+	log.SetLevel(logrus.ErrorLevel)
+	if *verbose {
+		log.SetLevel(logrus.DebugLevel)
+	}
+	c.log = log.WithField("cmp", "nsctl")
+
+	if err := dispatch(c, commands, globalFlags.Args(), "nsctl"); err != nil {
+		if usageErr, ok := err.(*usageError); ok {
+			if usageErr.err != flag.ErrHelp {
+				fmt.Fprintf(stderr, "Error: %s\n\n", usageErr.err)
+			}
+			fmt.Fprint(stderr, usageErr.usage)
+			if usageErr.err == flag.ErrHelp {
+				return 0
+			}
+			return 2
+		}
+		fmt.Fprintf(stderr, "Error: %s\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// dispatch finds command by the first argument and runs it with the rest of arguments
+func dispatch(c *cli, list []*command, args []string, prefix string) error {
+	usage := func() string {
+		var b strings.Builder
+		fmt.Fprintf(&b, "Usage: %s <command>\n\n", prefix)
+		printCommands(&b, list)
+		return b.String()
+	}
+
+	if len(args) == 0 {
+		return &usageError{usage: usage(), err: fmt.Errorf("command is required")}
+	} else if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		return &usageError{usage: usage(), err: flag.ErrHelp}
+	}
+
+	for _, cmd := range list {
+		if cmd.name != args[0] {
+			continue
+		}
+		if cmd.run != nil {
+			return cmd.run(c, args[1:])
+		}
+		return dispatch(c, cmd.subcommands, args[1:], prefix+" "+cmd.name)
+	}
+
+	return &usageError{usage: usage(), err: fmt.Errorf("unknown command '%s'", args[0])}
+}
+
+func printCommands(w io.Writer, list []*command) {
+	fmt.Fprintf(w, "Commands:\n")
+	for _, cmd := range list {
+		fmt.Fprintf(w, "  %-12s %s\n", cmd.name, cmd.description)
+	}
+}
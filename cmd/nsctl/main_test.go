@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/ns/nsfake"
+)
+
+// newTestCLI returns cli connected to a single fake NexentaStor, command output is written to the buffer
+func newTestCLI(output string, node ns.ProviderInterface) (*cli, *bytes.Buffer) {
+	l := logrus.New()
+	l.SetOutput(ioutil.Discard)
+
+	stdout := &bytes.Buffer{}
+	return &cli{
+		output:   output,
+		log:      l.WithField("cmp", "nsctl"),
+		stdout:   stdout,
+		stderr:   ioutil.Discard,
+		resolver: &ns.Resolver{Nodes: []ns.ProviderInterface{node}},
+	}, stdout
+}
+
+func TestRun_Usage(t *testing.T) {
+	tests := map[string]struct {
+		args     []string
+		code     int
+		expected string
+	}{
+		"no command":         {args: []string{}, code: 2, expected: "command is required"},
+		"help":               {args: []string{"help"}, code: 0, expected: "Commands:"},
+		"subcommand help":    {args: []string{"filesystem", "-h"}, code: 0, expected: "Usage: nsctl filesystem"},
+		"unknown command":    {args: []string{"filesystem", "rename"}, code: 2, expected: "unknown command 'rename'"},
+		"unknown output":     {args: []string{"-o", "xml", "pool", "list"}, code: 2, expected: "unknown output format"},
+		"missing argument":   {args: []string{"filesystem", "get"}, code: 2, expected: "expected 1 argument(s), got 0"},
+		"unknown flag":       {args: []string{"filesystem", "get", "-size", "1G", "poolA"}, code: 2, expected: "-size"},
+		"command help":       {args: []string{"volume", "create", "-h"}, code: 0, expected: "-sparse"},
+		"invalid flag value": {args: []string{"volume", "create", "-size", "1X", "v"}, code: 1, expected: "Invalid size"},
+		"missing size":       {args: []string{"volume", "create", "poolA/vg/v"}, code: 2, expected: "flag -size is required"},
+		"missing new size":   {args: []string{"volume", "resize", "poolA/vg/v"}, code: 2, expected: "flag -size is required"},
+		"missing quota":      {args: []string{"filesystem", "update", "poolA/fs"}, code: 2, expected: "flag -quota"},
+		"zero quota":         {args: []string{"filesystem", "update", "-quota", "0", "fs"}, code: 1, expected: "Quota must"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			stderr := &bytes.Buffer{}
+			if code := run(test.args, stdout, stderr); code != test.code {
+				t.Errorf("expected exit code %d, got %d, stderr: %s", test.code, code, stderr)
+			}
+			if !strings.Contains(stderr.String(), test.expected) {
+				t.Errorf("expected stderr to contain '%s', got: %s", test.expected, stderr)
+			}
+		})
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	node := nsfake.NewProvider(nsfake.ProviderArgs{Pools: []string{"poolA"}})
+	c, stdout := newTestCLI(outputTable, node)
+
+	if err := dispatch(c, commands, []string{"filesystem", "create", "poolA/fs", "-quota", "1G"}, "nsctl"); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "filesystem 'poolA/fs' created\n" {
+		t.Errorf("unexpected output: '%s'", stdout)
+	}
+
+	filesystem, err := node.GetFilesystem("poolA/fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filesystem.ReferencedQuotaSize != 1<<30 {
+		t.Errorf("expected quota flag to be applied, got: %+v", filesystem)
+	}
+
+	err = dispatch(c, commands, []string{"filesystem", "destroy"}, "nsctl")
+	if _, ok := err.(*usageError); !ok {
+		t.Errorf("expected usage error for missing argument, got: %v", err)
+	}
+}
+
+func TestPrint(t *testing.T) {
+	node := nsfake.NewProvider(nsfake.ProviderArgs{Pools: []string{"poolA"}})
+	err := node.CreateFilesystem(ns.CreateFilesystemParams{Path: "poolA/fs", ReferencedQuotaSize: 1 << 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list := func(output string) string {
+		c, stdout := newTestCLI(output, node)
+		if err := dispatch(c, commands, []string{"filesystem", "list", "poolA"}, "nsctl"); err != nil {
+			t.Fatal(err)
+		}
+		return stdout.String()
+	}
+
+	t.Run("table", func(t *testing.T) {
+		lines := strings.Split(strings.TrimSpace(list(outputTable)), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("expected header and a row, got: %q", lines)
+		}
+		if fields := strings.Fields(lines[0]); fields[0] != "PATH" || fields[len(fields)-1] != "SMB" {
+			t.Errorf("unexpected header: '%s'", lines[0])
+		}
+		if fields := strings.Fields(lines[1]); fields[0] != "poolA/fs" || fields[4] != "1.0G" {
+			t.Errorf("unexpected row: '%s'", lines[1])
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var filesystems []map[string]interface{}
+		if err := json.Unmarshal([]byte(list(outputJSON)), &filesystems); err != nil {
+			t.Fatal(err)
+		}
+		if len(filesystems) != 1 ||
+			filesystems[0]["path"] != "poolA/fs" ||
+			filesystems[0]["referencedQuotaSize"] != float64(1<<30) {
+			t.Errorf("unexpected JSON output: %+v", filesystems)
+		}
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		output := list(outputYAML)
+		// NexentaStor API field names are used in the original order
+		if !strings.HasPrefix(output, "- path: poolA/fs\n  mountPoint: ") ||
+			!strings.Contains(output, "\n  referencedQuotaSize: 1073741824\n") {
+			t.Errorf("unexpected YAML output:\n%s", output)
+		}
+	})
+
+	t.Run("no data in json output of commands without result", func(t *testing.T) {
+		c, stdout := newTestCLI(outputJSON, node)
+		if err := dispatch(c, commands, []string{"filesystem", "create", "poolA/fs2"}, "nsctl"); err != nil {
+			t.Fatal(err)
+		}
+		if stdout.Len() != 0 {
+			t.Errorf("expected no output, got: '%s'", stdout)
+		}
+	})
+}
+
+// duplicateLunMappings - provider which returns every LUN mapping twice with different IDs,
+// like a volume mapped to a host group through two target groups
+type duplicateLunMappings struct {
+	*nsfake.Provider
+}
+
+func (p duplicateLunMappings) ListLunMappings(params ns.ListLunMappingsParams) ([]ns.LunMapping, error) {
+	lunMappings, err := p.Provider.ListLunMappings(params)
+	if err != nil {
+		return nil, err
+	}
+	for _, lunMapping := range lunMappings {
+		lunMapping.Id += "-copy"
+		lunMappings = append(lunMappings, lunMapping)
+	}
+	return lunMappings, nil
+}
+
+func TestLun(t *testing.T) {
+	node := nsfake.NewProvider(nsfake.ProviderArgs{Pools: []string{"poolA"}})
+	if err := node.CreateVolumeGroup(ns.CreateVolumeGroupParams{Path: "poolA/vg"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := node.CreateVolume(ns.CreateVolumeParams{Path: "poolA/vg/v", VolumeSize: 1 << 20}); err != nil {
+		t.Fatal(err)
+	}
+	for _, group := range []string{"hgA", "hgB"} {
+		hostGroup := ns.CreateHostGroupParams{Name: group, Members: []string{"iqn.2005-07.com.nexenta:" + group}}
+		if err := node.CreateUpdateHostGroup(hostGroup); err != nil {
+			t.Fatal(err)
+		}
+	}
+	targetGroup := ns.CreateTargetGroupParams{Name: "tg", Members: []string{"iqn.2005-07.com.nexenta:tg"}}
+	if err := node.CreateUpdateTargetGroup(targetGroup); err != nil {
+		t.Fatal(err)
+	}
+
+	runLun := func(node ns.ProviderInterface, args ...string) (string, error) {
+		c, stdout := newTestCLI(outputJSON, node)
+		err := dispatch(c, commands, append([]string{"lun"}, args...), "nsctl")
+		return stdout.String(), err
+	}
+
+	t.Run("host group is required", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"create", "poolA/vg/v", "-target-group", "tg"},
+			{"destroy", "poolA/vg/v"},
+		} {
+			_, err := runLun(node, args...)
+			if usageErr, ok := err.(*usageError); !ok || usageErr.err.Error() != "flag -host-group is required" {
+				t.Errorf("%v: expected host group usage error, got: %v", args, err)
+			}
+		}
+	})
+
+	lunMappings := map[string]ns.LunMapping{}
+	for _, group := range []string{"hgA", "hgB"} {
+		output, err := runLun(node, "create", "poolA/vg/v", "-host-group", group, "-target-group", "tg")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var lunMapping ns.LunMapping
+		if err := json.Unmarshal([]byte(output), &lunMapping); err != nil {
+			t.Fatal(err)
+		}
+		if lunMapping.HostGroup != group {
+			t.Errorf("expected created mapping of host group '%s' to be printed, got: %+v", group, lunMapping)
+		}
+		lunMappings[group] = lunMapping
+	}
+
+	t.Run("more than one mapping matches", func(t *testing.T) {
+		_, err := runLun(duplicateLunMappings{node}, "destroy", "poolA/vg/v", "-host-group", "hgB")
+		if err == nil || !strings.Contains(err.Error(), "2 LUN mappings") {
+			t.Errorf("expected an error about 2 matching mappings, got: %v", err)
+		}
+	})
+
+	t.Run("destroy mapping of the host group", func(t *testing.T) {
+		if _, err := runLun(node, "destroy", "poolA/vg/v", "-host-group", "hgB"); err != nil {
+			t.Fatal(err)
+		}
+		left, err := node.ListLunMappings(ns.ListLunMappingsParams{Volume: "poolA/vg/v"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(left) != 1 || left[0].HostGroup != "hgA" {
+			t.Errorf("expected only mapping to host group 'hgA' to be left, got: %+v", left)
+		}
+	})
+
+	t.Run("destroy mapping by ID", func(t *testing.T) {
+		if _, err := runLun(node, "destroy", "poolA/vg/v", "-id", "unknown"); err == nil {
+			t.Error("expected an error for unknown mapping ID")
+		}
+		if _, err := runLun(node, "destroy", "poolA/vg/v", "-id", lunMappings["hgA"].Id); err != nil {
+			t.Fatal(err)
+		}
+		if left, _ := node.ListLunMappings(ns.ListLunMappingsParams{Volume: "poolA/vg/v"}); len(left) != 0 {
+			t.Errorf("expected no mappings to be left, got: %+v", left)
+		}
+	})
+}
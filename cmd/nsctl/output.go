@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v2"
+)
+
+// output formats
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// table - tabular representation of command result
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+func (t *table) add(row ...interface{}) {
+	cells := make([]string, len(row))
+	for i, cell := range row {
+		cells[i] = fmt.Sprint(cell)
+	}
+	t.rows = append(t.rows, cells)
+}
+
+// print writes command result in the selected output format,
+// JSON and YAML use NexentaStor API field names, table is used for human readable output
+func (c *cli) print(value interface{}, t *table) error {
+	switch c.output {
+	case outputJSON:
+		data, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(c.stdout, string(data))
+	case outputYAML:
+		data, err := toYAML(value)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(c.stdout, string(data))
+	default:
+		w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, strings.Join(t.header, "\t"))
+		for _, row := range t.rows {
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+		return w.Flush()
+	}
+
+	return nil
+}
+
+// printDone prints result of a command which doesn't return data
+func (c *cli) printDone(format string, args ...interface{}) error {
+	if c.output == outputTable {
+		fmt.Fprintf(c.stdout, format+"\n", args...)
+	}
+	return nil
+}
+
+// toYAML converts value to YAML through JSON, so json tags of "ns" types are respected
+// and fields keep their order
+func toYAML(value interface{}) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var document interface{}
+	if strings.HasPrefix(string(data), "[") {
+		list := []yaml.MapSlice{}
+		err = yaml.Unmarshal(data, &list)
+		document = list
+	} else {
+		object := yaml.MapSlice{}
+		err = yaml.Unmarshal(data, &object)
+		document = object
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(document)
+}
+
+// formatBytes formats size with a binary unit suffix
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%c", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,446 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/Nexenta/go-nexentastor/pkg/logger"
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/rest"
+)
+
+// environment variables to override profile values
+const (
+	// EnvConfig - config file path, used by tools which load the config on their own, e.g. nsctl
+	EnvConfig             = "NEXENTASTOR_CONFIG"
+	EnvProfile            = "NEXENTASTOR_PROFILE"
+	EnvAddress            = "NEXENTASTOR_ADDRESS"
+	EnvUsername           = "NEXENTASTOR_USERNAME"
+	EnvPassword           = "NEXENTASTOR_PASSWORD"
+	EnvCredentialsFile    = "NEXENTASTOR_CREDENTIALS_FILE"
+	EnvInsecureSkipVerify = "NEXENTASTOR_INSECURE_SKIP_VERIFY"
+	EnvCAFile             = "NEXENTASTOR_CA_FILE"
+	EnvTimeout            = "NEXENTASTOR_TIMEOUT"
+	EnvDefaultPool        = "NEXENTASTOR_DEFAULT_POOL"
+	EnvDefaultDataset     = "NEXENTASTOR_DEFAULT_DATASET"
+)
+
+// Config - NexentaStor connection profiles, loaded from YAML or JSON
+//
+// Example:
+//
+//	defaultProfile: production
+//	profiles:
+//	  production:
+//	    addresses:
+//	      - https://10.3.199.252:8443
+//	      - https://10.3.199.253:8443
+//	    credentialsFile: /etc/nexentastor/credentials.yaml
+//	    tls:
+//	      caFile: /etc/nexentastor/ca.pem
+//	      minVersion: "1.2"
+//	    timeout: 60s
+//	    defaultPool: poolA
+//	    defaultDataset: poolA/datasetA
+type Config struct {
+	// profile to use if no profile name is given and $NEXENTASTOR_PROFILE is not set
+	DefaultProfile string `yaml:"defaultProfile"`
+
+	Profiles map[string]Profile `yaml:"profiles"`
+
+	// directory of the config file, relative file paths in profiles are resolved against it
+	dir string
+}
+
+// Profile - settings to connect to a single NexentaStor or a cluster
+type Profile struct {
+	// NexentaStor addresses, more than one address makes a cluster (see ns.Resolver)
+	Addresses []string `yaml:"addresses"`
+
+	Username string `yaml:"username"`
+	Password string `yaml:"password" secret:"true"`
+
+	// YAML or JSON file with "username" and "password", or a directory with "username" and "password" files
+	// (e.g. a mounted k8s secret), its values override Username and Password, rotated values are picked up
+	// on the next login (see ns.FileCredentialSource)
+	CredentialsFile string `yaml:"credentialsFile"`
+
+	TLS TLSConfig `yaml:"tls"`
+
+	// overall NexentaStor API request timeout, e.g. "60s", default one is used if not set
+	Timeout time.Duration `yaml:"timeout"`
+
+	// proxy URL, e.g. "http://proxy:3128", $HTTPS_PROXY is used if not set
+	Proxy string `yaml:"proxy"`
+
+	// pool and dataset to use when none is specified by the caller
+	DefaultPool    string `yaml:"defaultPool"`
+	DefaultDataset string `yaml:"defaultDataset"`
+}
+
+// TLSConfig - TLS settings of a profile
+type TLSConfig struct {
+	// InsecureSkipVerify controls whether a client verifies the server's certificate chain and host name.
+	InsecureSkipVerify bool `yaml:"insecureSkipVerify"`
+
+	// PEM file with CA certificates to verify NexentaStor certificate, system CAs are used if not set
+	CAFile string `yaml:"caFile"`
+
+	// client certificate and key PEM files for mutual TLS
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+
+	// server name to verify NexentaStor certificate against, host of the address is used if not set
+	ServerName string `yaml:"serverName"`
+
+	// base64 encoded SHA-256 fingerprints of allowed NexentaStor public keys, see rest.TLSOptions.SPKIPins
+	SPKIPins []string `yaml:"spkiPins"`
+
+	// minimum TLS version: "1.2" or "1.3", Go default if not set
+	MinVersion string `yaml:"minVersion"`
+}
+
+// TLS versions allowed in TLSConfig.MinVersion
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Load reads config from YAML or JSON file
+func Load(file string) (*Config, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read config file '%s': %s", file, err)
+	}
+
+	config, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("Config file '%s': %s", file, err)
+	}
+
+	config.dir = filepath.Dir(file)
+	return config, nil
+}
+
+// Parse parses config from YAML or JSON, relative file paths are resolved against the current directory
+func Parse(data []byte) (*Config, error) {
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("Cannot parse config: %s", err)
+	}
+
+	if config.DefaultProfile != "" {
+		if _, ok := config.Profiles[config.DefaultProfile]; !ok {
+			return nil, fmt.Errorf("Default profile '%s' is not defined", config.DefaultProfile)
+		}
+	}
+
+	return config, nil
+}
+
+// ProfileNames returns sorted names of all profiles
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profile returns validated profile with environment variable overrides and credentials file applied
+// If name is empty, $NEXENTASTOR_PROFILE, DefaultProfile or the only defined profile is used.
+func (c *Config) Profile(name string) (*Profile, error) {
+	return c.ProfileWithOverrides(name, nil)
+}
+
+// ProfileWithOverrides returns profile like Profile(), override is called after environment variables and
+// credentials file are applied and before the profile is validated, e.g. to apply command line flags
+func (c *Config) ProfileWithOverrides(name string, override func(p *Profile)) (*Profile, error) {
+	if name == "" {
+		name = os.Getenv(EnvProfile)
+	}
+	if name == "" {
+		name = c.DefaultProfile
+	}
+	if name == "" {
+		if len(c.Profiles) != 1 {
+			return nil, fmt.Errorf(
+				"Profile name is required, set $%s or defaultProfile, available profiles: %s",
+				EnvProfile,
+				strings.Join(c.ProfileNames(), ", "),
+			)
+		}
+		name = c.ProfileNames()[0]
+	}
+
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf(
+			"Profile '%s' not found, available profiles: %s", name, strings.Join(c.ProfileNames(), ", "))
+	}
+
+	profile.Addresses = append([]string{}, profile.Addresses...)
+	profile.resolvePaths(c.dir)
+
+	if err := profile.applyEnv(); err != nil {
+		return nil, fmt.Errorf("Profile '%s': %s", name, err)
+	}
+
+	if err := profile.loadCredentials(); err != nil {
+		return nil, fmt.Errorf("Profile '%s': %s", name, err)
+	}
+
+	if override != nil {
+		override(&profile)
+	}
+
+	if err := profile.Validate(); err != nil {
+		return nil, fmt.Errorf("Profile '%s': %s", name, err)
+	}
+
+	return &profile, nil
+}
+
+// applyEnv overrides profile values by environment variables
+func (p *Profile) applyEnv() error {
+	stringFields := map[string]*string{
+		EnvUsername:        &p.Username,
+		EnvPassword:        &p.Password,
+		EnvCredentialsFile: &p.CredentialsFile,
+		EnvCAFile:          &p.TLS.CAFile,
+		EnvDefaultPool:     &p.DefaultPool,
+		EnvDefaultDataset:  &p.DefaultDataset,
+	}
+	for env, field := range stringFields {
+		if value, ok := os.LookupEnv(env); ok {
+			*field = value
+		}
+	}
+
+	if value, ok := os.LookupEnv(EnvAddress); ok {
+		p.Addresses = SplitAddresses(value)
+	}
+
+	if value, ok := os.LookupEnv(EnvInsecureSkipVerify); ok {
+		insecureSkipVerify, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("Invalid $%s value '%s': %s", EnvInsecureSkipVerify, value, err)
+		}
+		p.TLS.InsecureSkipVerify = insecureSkipVerify
+	}
+
+	if value, ok := os.LookupEnv(EnvTimeout); ok {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("Invalid $%s value '%s': %s", EnvTimeout, value, err)
+		}
+		p.Timeout = timeout
+	}
+
+	return nil
+}
+
+// resolvePaths makes relative file paths relative to the config file directory
+func (p *Profile) resolvePaths(dir string) {
+	for _, path := range []*string{&p.CredentialsFile, &p.TLS.CAFile, &p.TLS.CertFile, &p.TLS.KeyFile} {
+		if *path != "" && dir != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(dir, *path)
+		}
+	}
+}
+
+// loadCredentials reads username and password from the credentials file, if set
+func (p *Profile) loadCredentials() error {
+	if p.CredentialsFile == "" {
+		return nil
+	}
+
+	creds, err := ns.NewFileCredentialSource(p.CredentialsFile).GetCredentials()
+	if err != nil {
+		return err
+	}
+
+	p.Username = creds.Username
+	p.Password = creds.Password
+
+	return nil
+}
+
+// Validate checks that profile has everything to connect to NexentaStor
+func (p *Profile) Validate() error {
+	if len(p.Addresses) == 0 {
+		return fmt.Errorf("at least one NexentaStor address is required")
+	}
+	for _, address := range p.Addresses {
+		u, err := url.Parse(address)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("invalid NexentaStor address '%s', expected 'https://host:port'", address)
+		}
+	}
+
+	if p.Username == "" || p.Password == "" {
+		return fmt.Errorf("username and password are required")
+	}
+
+	if (p.TLS.CertFile == "") != (p.TLS.KeyFile == "") {
+		return fmt.Errorf("both tls.certFile and tls.keyFile are required for client certificate")
+	}
+
+	if _, ok := tlsVersions[p.TLS.MinVersion]; p.TLS.MinVersion != "" && !ok {
+		return fmt.Errorf("unknown tls.minVersion '%s', expected one of: 1.0, 1.1, 1.2, 1.3", p.TLS.MinVersion)
+	}
+
+	if p.Proxy != "" {
+		if u, err := url.Parse(p.Proxy); err != nil || u.Host == "" {
+			return fmt.Errorf("invalid proxy '%s', expected 'http://host:port'", p.Proxy)
+		}
+	}
+
+	if p.Timeout < 0 {
+		return fmt.Errorf("timeout cannot be negative: %s", p.Timeout)
+	}
+
+	if p.DefaultDataset != "" && p.DefaultPool != "" && !strings.HasPrefix(p.DefaultDataset, p.DefaultPool+"/") {
+		return fmt.Errorf("default dataset '%s' is not in default pool '%s'", p.DefaultDataset, p.DefaultPool)
+	}
+
+	return nil
+}
+
+// IsCluster returns true if profile has more than one NexentaStor address
+func (p *Profile) IsCluster() bool {
+	return len(p.Addresses) > 1
+}
+
+// TLSOptions returns rest client TLS options, CA and client certificate files are only checked to exist here,
+// they are loaded by the rest client and re-read after rotation
+func (p *Profile) TLSOptions() (rest.TLSOptions, error) {
+	for _, file := range []string{p.TLS.CAFile, p.TLS.CertFile, p.TLS.KeyFile} {
+		if file == "" {
+			continue
+		}
+		if _, err := os.Stat(file); err != nil {
+			return rest.TLSOptions{}, fmt.Errorf("Cannot read TLS file: %s", err)
+		}
+	}
+
+	return rest.TLSOptions{
+		CAFile:     p.TLS.CAFile,
+		CertFile:   p.TLS.CertFile,
+		KeyFile:    p.TLS.KeyFile,
+		SPKIPins:   p.TLS.SPKIPins,
+		MinVersion: tlsVersions[p.TLS.MinVersion],
+		ServerName: p.TLS.ServerName,
+	}, nil
+}
+
+// ProviderArgs returns params to create provider for a single NexentaStor profile,
+// log is passed as ns.ProviderArgs.Logger, nothing is logged if it's nil
+func (p *Profile) ProviderArgs(log logger.Logger) (ns.ProviderArgs, error) {
+	if p.IsCluster() {
+		return ns.ProviderArgs{}, fmt.Errorf(
+			"Profile has %d NexentaStor addresses, use a resolver for a cluster", len(p.Addresses))
+	}
+
+	tlsOptions, err := p.TLSOptions()
+	if err != nil {
+		return ns.ProviderArgs{}, err
+	}
+
+	return ns.ProviderArgs{
+		Address:            p.Addresses[0],
+		Username:           p.Username,
+		Password:           p.Password,
+		Credentials:        p.credentialSource(),
+		Logger:             log,
+		InsecureSkipVerify: p.TLS.InsecureSkipVerify,
+		TLS:                tlsOptions,
+		Timeout:            p.Timeout,
+		Transport:          rest.TransportOptions{Proxy: p.Proxy},
+	}, nil
+}
+
+// ResolverArgs returns params to create resolver for all NexentaStor addresses of the profile,
+// log is passed as ns.ResolverArgs.Logger, nothing is logged if it's nil
+func (p *Profile) ResolverArgs(log logger.Logger) (ns.ResolverArgs, error) {
+	tlsOptions, err := p.TLSOptions()
+	if err != nil {
+		return ns.ResolverArgs{}, err
+	}
+
+	return ns.ResolverArgs{
+		Address:            strings.Join(p.Addresses, ","),
+		Username:           p.Username,
+		Password:           p.Password,
+		Credentials:        p.credentialSource(),
+		Logger:             log,
+		InsecureSkipVerify: p.TLS.InsecureSkipVerify,
+		TLS:                tlsOptions,
+		Timeout:            p.Timeout,
+		Transport:          rest.TransportOptions{Proxy: p.Proxy},
+	}, nil
+}
+
+// credentialSource returns source which re-reads the credentials file when it's rotated,
+// nil if the profile has no credentials file, so Username and Password are used
+func (p *Profile) credentialSource() ns.CredentialSource {
+	if p.CredentialsFile == "" {
+		return nil
+	}
+	return ns.NewFileCredentialSource(p.CredentialsFile)
+}
+
+// NewProvider creates NexentaStor provider from the profile, see Config.Profile() for name resolution
+func (c *Config) NewProvider(profileName string, log logger.Logger) (ns.ProviderInterface, error) {
+	profile, err := c.Profile(profileName)
+	if err != nil {
+		return nil, err
+	}
+
+	args, err := profile.ProviderArgs(log)
+	if err != nil {
+		return nil, err
+	}
+
+	return ns.NewProvider(args)
+}
+
+// NewResolver creates NexentaStor resolver from the profile, see Config.Profile() for name resolution
+func (c *Config) NewResolver(profileName string, log logger.Logger) (*ns.Resolver, error) {
+	profile, err := c.Profile(profileName)
+	if err != nil {
+		return nil, err
+	}
+
+	args, err := profile.ResolverArgs(log)
+	if err != nil {
+		return nil, err
+	}
+
+	return ns.NewResolver(args)
+}
+
+// SplitAddresses splits comma-separated list of NexentaStor addresses, e.g. $NEXENTASTOR_ADDRESS value,
+// spaces around addresses and empty items are dropped
+func SplitAddresses(address string) []string {
+	var addresses []string
+	for _, a := range strings.Split(address, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			addresses = append(addresses, a)
+		}
+	}
+	return addresses
+}
@@ -0,0 +1,54 @@
+// Package logger - minimal logging interface the library writes to, with logrus, slog, zap and no-op adapters
+package logger
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// Fields - log entry fields
+type Fields map[string]interface{}
+
+// Logger - leveled logger with fields, implementations must be safe for concurrent use
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+
+	// WithField and WithFields return a logger which adds the fields to each log line
+	WithField(key string, value interface{}) Logger
+	WithFields(fields Fields) Logger
+}
+
+// Nop - Logger implementation which drops all log lines
+type Nop struct{}
+
+// Debugf does nothing
+func (Nop) Debugf(format string, args ...interface{}) {}
+
+// Infof does nothing
+func (Nop) Infof(format string, args ...interface{}) {}
+
+// Warnf does nothing
+func (Nop) Warnf(format string, args ...interface{}) {}
+
+// Errorf does nothing
+func (Nop) Errorf(format string, args ...interface{}) {}
+
+// WithField returns the same no-op logger
+func (n Nop) WithField(key string, value interface{}) Logger { return n }
+
+// WithFields returns the same no-op logger
+func (n Nop) WithFields(fields Fields) Logger { return n }
+
+// Pick returns logger to use from ProviderArgs-like params: l if set, logrus adapter of the entry otherwise,
+// or Nop if neither is set. Returned logger always redacts secrets, see NewRedacting().
+func Pick(l Logger, entry *logrus.Entry) Logger {
+	if l == nil {
+		if entry == nil {
+			return Nop{}
+		}
+		l = NewLogrus(entry)
+	}
+	return NewRedacting(l)
+}
@@ -0,0 +1,233 @@
+package logger
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Mask - replacement of secret values in log lines
+const Mask = "*****"
+
+// SecretTag - struct field tag marking fields which must never be logged:
+//
+//	Password string `json:"password" secret:"true"`
+const SecretTag = "secret"
+
+// field and map key names which values are considered secret, compared case-insensitively by substring
+var secretKeys = []string{"password", "secret", "token"}
+
+// IsSecretKey returns true if field or map key name looks like a secret one, e.g. "password" or "chapSecret"
+func IsSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, secretKey := range secretKeys {
+		if strings.Contains(key, secretKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact returns copy of the value with secrets masked: struct fields tagged with `secret:"true"`
+// and map values with secret keys (see IsSecretKey()), nested structs, pointers, slices and maps are
+// redacted too. Values without secrets are returned as is. Values with pointer cycles are not supported.
+func Redact(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	v := reflect.ValueOf(value)
+	if !typeMayContainSecrets(v.Type()) {
+		return value
+	}
+	return redact(v).Interface()
+}
+
+// mayContainSecrets checks type recursively, so most of the values are logged without copying
+func mayContainSecrets(t reflect.Type, visited map[reflect.Type]bool) bool {
+	if visited[t] {
+		return false
+	}
+	visited[t] = true
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String || mayContainSecrets(t.Elem(), visited)
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return mayContainSecrets(t.Elem(), visited)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.Tag.Get(SecretTag) == "true" || mayContainSecrets(field.Type, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func redact(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		return redact(v.Elem())
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		redacted := reflect.New(v.Type().Elem())
+		redacted.Elem().Set(redact(v.Elem()))
+		return redacted
+	case reflect.Struct:
+		redacted := reflect.New(v.Type()).Elem()
+		redacted.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			field := redacted.Field(i)
+			if !field.CanSet() { // unexported field
+				continue
+			}
+			if v.Type().Field(i).Tag.Get(SecretTag) == "true" {
+				field.Set(mask(field.Type(), v.Field(i)))
+			} else {
+				field.Set(redact(v.Field(i)))
+			}
+		}
+		return redacted
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		redacted := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key, value := iter.Key(), iter.Value()
+			if key.Kind() == reflect.String && IsSecretKey(key.String()) {
+				redacted.SetMapIndex(key, mask(v.Type().Elem(), value))
+			} else {
+				redacted.SetMapIndex(key, redact(value))
+			}
+		}
+		return redacted
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		redacted := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			redacted.Index(i).Set(redact(v.Index(i)))
+		}
+		return redacted
+	case reflect.Array:
+		redacted := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			redacted.Index(i).Set(redact(v.Index(i)))
+		}
+		return redacted
+	}
+	return v
+}
+
+// mask returns Mask for string values, zero value for others, empty values stay empty
+func mask(t reflect.Type, v reflect.Value) reflect.Value {
+	if v.IsZero() {
+		return reflect.Zero(t)
+	}
+	masked := reflect.ValueOf(Mask)
+	if masked.Type().AssignableTo(t) {
+		return masked
+	}
+	if masked.Type().ConvertibleTo(t) && t.Kind() == reflect.String {
+		return masked.Convert(t)
+	}
+	return reflect.Zero(t)
+}
+
+// redactingLogger - Logger which redacts secrets in message arguments and field values
+type redactingLogger struct {
+	l Logger
+}
+
+// NewRedacting wraps the logger, so secrets in message arguments and field values are masked, see Redact().
+// Values of fields with secret names (see IsSecretKey()) are masked entirely.
+func NewRedacting(l Logger) Logger {
+	if _, ok := l.(*redactingLogger); ok {
+		return l
+	}
+	return &redactingLogger{l: l}
+}
+
+// redactedArg - message argument which is redacted when the message is formatted,
+// so nothing is copied if the log level is disabled
+type redactedArg struct {
+	value interface{}
+}
+
+// Format formats redacted value with the same verb and flags
+func (a redactedArg) Format(f fmt.State, verb rune) {
+	fmt.Fprintf(f, fmt.FormatString(f, verb), Redact(a.value))
+}
+
+// types checked by mayContainSecrets(), reflect.Type -> bool
+var secretTypes sync.Map
+
+func typeMayContainSecrets(t reflect.Type) bool {
+	if cached, ok := secretTypes.Load(t); ok {
+		return cached.(bool)
+	}
+	result := mayContainSecrets(t, map[reflect.Type]bool{})
+	secretTypes.Store(t, result)
+	return result
+}
+
+// redactArgs wraps arguments which may contain secrets, see redactedArg
+func redactArgs(args []interface{}) []interface{} {
+	redacted := make([]interface{}, len(args))
+	for i, arg := range args {
+		if arg != nil && typeMayContainSecrets(reflect.TypeOf(arg)) {
+			redacted[i] = redactedArg{arg}
+		} else {
+			redacted[i] = arg
+		}
+	}
+	return redacted
+}
+
+func redactField(key string, value interface{}) interface{} {
+	if IsSecretKey(key) {
+		return Mask
+	}
+	return Redact(value)
+}
+
+func (l *redactingLogger) Debugf(format string, args ...interface{}) {
+	l.l.Debugf(format, redactArgs(args)...)
+}
+
+func (l *redactingLogger) Infof(format string, args ...interface{}) {
+	l.l.Infof(format, redactArgs(args)...)
+}
+
+func (l *redactingLogger) Warnf(format string, args ...interface{}) {
+	l.l.Warnf(format, redactArgs(args)...)
+}
+
+func (l *redactingLogger) Errorf(format string, args ...interface{}) {
+	l.l.Errorf(format, redactArgs(args)...)
+}
+
+func (l *redactingLogger) WithField(key string, value interface{}) Logger {
+	return &redactingLogger{l: l.l.WithField(key, redactField(key, value))}
+}
+
+func (l *redactingLogger) WithFields(fields Fields) Logger {
+	redacted := make(Fields, len(fields))
+	for key, value := range fields {
+		redacted[key] = redactField(key, value)
+	}
+	return &redactingLogger{l: l.l.WithFields(redacted)}
+}
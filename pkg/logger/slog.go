@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+)
+
+// slogLogger - Logger backed by log/slog
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// NewSlog creates Logger which writes to slog logger, fields are added as attributes
+func NewSlog(l *slog.Logger) Logger {
+	return &slogLogger{l: l}
+}
+
+func (l *slogLogger) log(level slog.Level, format string, args []interface{}) {
+	ctx := context.Background()
+	if l.l.Enabled(ctx, level) {
+		l.l.Log(ctx, level, fmt.Sprintf(format, args...))
+	}
+}
+
+func (l *slogLogger) Debugf(format string, args ...interface{}) { l.log(slog.LevelDebug, format, args) }
+
+func (l *slogLogger) Infof(format string, args ...interface{}) { l.log(slog.LevelInfo, format, args) }
+
+func (l *slogLogger) Warnf(format string, args ...interface{}) { l.log(slog.LevelWarn, format, args) }
+
+func (l *slogLogger) Errorf(format string, args ...interface{}) { l.log(slog.LevelError, format, args) }
+
+func (l *slogLogger) WithField(key string, value interface{}) Logger {
+	return &slogLogger{l: l.l.With(key, value)}
+}
+
+func (l *slogLogger) WithFields(fields Fields) Logger {
+	// sorted, so attributes order doesn't change from line to line
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	args := make([]interface{}, 0, 2*len(fields))
+	for _, key := range keys {
+		args = append(args, key, fields[key])
+	}
+	return &slogLogger{l: l.l.With(args...)}
+}
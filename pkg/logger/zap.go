@@ -0,0 +1,43 @@
+package logger
+
+// SugaredLogger - methods of zap.SugaredLogger used by the zap adapter,
+// declared here to keep zap out of the library dependencies
+type SugaredLogger[T any] interface {
+	Debugf(template string, args ...interface{})
+	Infof(template string, args ...interface{})
+	Warnf(template string, args ...interface{})
+	Errorf(template string, args ...interface{})
+	With(args ...interface{}) T
+}
+
+// zapLogger - Logger backed by zap sugared logger
+type zapLogger[T SugaredLogger[T]] struct {
+	l T
+}
+
+// NewZap creates Logger which writes to zap sugared logger, e.g.:
+//
+//	logger.NewZap(zap.NewExample().Sugar())
+func NewZap[T SugaredLogger[T]](l T) Logger {
+	return &zapLogger[T]{l: l}
+}
+
+func (l *zapLogger[T]) Debugf(format string, args ...interface{}) { l.l.Debugf(format, args...) }
+
+func (l *zapLogger[T]) Infof(format string, args ...interface{}) { l.l.Infof(format, args...) }
+
+func (l *zapLogger[T]) Warnf(format string, args ...interface{}) { l.l.Warnf(format, args...) }
+
+func (l *zapLogger[T]) Errorf(format string, args ...interface{}) { l.l.Errorf(format, args...) }
+
+func (l *zapLogger[T]) WithField(key string, value interface{}) Logger {
+	return &zapLogger[T]{l: l.l.With(key, value)}
+}
+
+func (l *zapLogger[T]) WithFields(fields Fields) Logger {
+	args := make([]interface{}, 0, 2*len(fields))
+	for key, value := range fields {
+		args = append(args, key, value)
+	}
+	return &zapLogger[T]{l: l.l.With(args...)}
+}
@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"strings"
+	"time"
+)
+
+// async job outcomes, see Metrics.ObserveJob()
+const (
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobTimedOut  = "timeout"
+)
+
+// request retry reasons, see Metrics.ObserveRetry()
+const (
+	// request is sent again after re-login because auth token has been rejected
+	RetryAuth = "auth"
+	// dataset deletion is retried after the most recent clone promotion
+	RetryDependentClones = "dependentClones"
+)
+
+// CodeError - status code label of requests which got no response
+const CodeError = "error"
+
+// Metrics - receives NexentaStor client measurements, implementations must be safe for concurrent use
+type Metrics interface {
+	// ObserveRequest - REST API request, endpoint is a path template (see EndpointTemplate()),
+	// code is HTTP status code or CodeError if no response has been received
+	ObserveRequest(address, method, endpoint, code string, duration time.Duration)
+
+	// ObserveJob - wait for NexentaStor async job completion, outcome is one of Job* constants
+	ObserveJob(address, outcome string, duration time.Duration)
+
+	// ObserveLogin - login request, err is nil for successful login
+	ObserveLogin(address string, err error)
+
+	// ObserveRetry - repeated request, reason is one of Retry* constants
+	ObserveRetry(address, reason string)
+}
+
+// Nop - Metrics implementation which drops all measurements
+type Nop struct{}
+
+// ObserveRequest does nothing
+func (Nop) ObserveRequest(address, method, endpoint, code string, duration time.Duration) {}
+
+// ObserveJob does nothing
+func (Nop) ObserveJob(address, outcome string, duration time.Duration) {}
+
+// ObserveLogin does nothing
+func (Nop) ObserveLogin(address string, err error) {}
+
+// ObserveRetry does nothing
+func (Nop) ObserveRetry(address, reason string) {}
+
+// NexentaStor API collections, path segment following one of them is an item name or ID
+var collections = map[string]bool{
+	"filesystems":  true,
+	"volumes":      true,
+	"volumeGroups": true,
+	"snapshots":    true,
+	"pools":        true,
+	"jobStatus":    true,
+	"nfs":          true,
+	"smb":          true,
+	"lunMappings":  true,
+	"hostgroups":   true,
+	"targetgroups": true,
+	"targets":      true,
+	"ports":        true,
+	"clusters":     true,
+}
+
+// EndpointTemplate collapses item names and IDs in request path to keep metric label cardinality low:
+//
+//	/storage/filesystems/pool%2Ffs/acl?fields=path -> /storage/filesystems/{id}/acl
+func EndpointTemplate(path string) string {
+	if i := strings.IndexByte(path, '?'); i != -1 {
+		path = path[:i]
+	}
+
+	// backwards, so the previous segment is not replaced yet
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		isItem := i > 0 && collections[segments[i-1]]
+		if isItem || strings.Contains(segments[i], "%") || isNumber(segments[i]) {
+			segments[i] = "{id}"
+		}
+	}
+
+	return "/" + strings.Join(segments, "/")
+}
+
+func isNumber(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
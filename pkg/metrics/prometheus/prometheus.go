@@ -0,0 +1,130 @@
+// Package prometheus exposes NexentaStor client metrics (see metrics.Metrics) as Prometheus collectors
+package prometheus
+
+import (
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// default metric name prefix
+const defaultNamespace = "nexentastor"
+
+// Metrics - metrics.Metrics implementation backed by Prometheus collectors:
+//
+//	nexentastor_requests_total{address, method, endpoint, code}
+//	nexentastor_request_duration_seconds{address, method, endpoint, code}
+//	nexentastor_job_wait_duration_seconds{address, outcome}
+//	nexentastor_logins_total{address, result}
+//	nexentastor_request_retries_total{address, reason}
+type Metrics struct {
+	requests        *prom.CounterVec
+	requestDuration *prom.HistogramVec
+	jobDuration     *prom.HistogramVec
+	logins          *prom.CounterVec
+	retries         *prom.CounterVec
+}
+
+// MetricsArgs - params to create Metrics instance
+type MetricsArgs struct {
+	// Namespace - metric name prefix, "nexentastor" if not set
+	Namespace string
+
+	// Registerer - registry to register collectors in, prometheus.DefaultRegisterer if not set
+	Registerer prom.Registerer
+
+	// RequestBuckets and JobBuckets - histogram buckets in seconds, prometheus.DefBuckets
+	// and prometheus.ExponentialBuckets(1, 2, 8) (1s..128s) are used if not set
+	RequestBuckets []float64
+	JobBuckets     []float64
+}
+
+// NewMetrics creates Metrics and registers its collectors
+func NewMetrics(args MetricsArgs) (*Metrics, error) {
+	namespace := args.Namespace
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+
+	registerer := args.Registerer
+	if registerer == nil {
+		registerer = prom.DefaultRegisterer
+	}
+
+	requestBuckets := args.RequestBuckets
+	if len(requestBuckets) == 0 {
+		requestBuckets = prom.DefBuckets
+	}
+
+	jobBuckets := args.JobBuckets
+	if len(jobBuckets) == 0 {
+		jobBuckets = prom.ExponentialBuckets(1, 2, 8)
+	}
+
+	m := &Metrics{
+		requests: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "NexentaStor REST API requests by endpoint and status code, code is 'error' if no response received.",
+		}, []string{"address", "method", "endpoint", "code"}),
+		requestDuration: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "NexentaStor REST API request latency.",
+			Buckets:   requestBuckets,
+		}, []string{"address", "method", "endpoint", "code"}),
+		jobDuration: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: namespace,
+			Name:      "job_wait_duration_seconds",
+			Help:      "Time spent waiting for NexentaStor async jobs by outcome.",
+			Buckets:   jobBuckets,
+		}, []string{"address", "outcome"}),
+		logins: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "logins_total",
+			Help:      "NexentaStor login requests by result: 'success' or 'failure'.",
+		}, []string{"address", "result"}),
+		retries: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "request_retries_total",
+			Help:      "Repeated NexentaStor requests by reason.",
+		}, []string{"address", "reason"}),
+	}
+
+	collectors := []prom.Collector{m.requests, m.requestDuration, m.jobDuration, m.logins, m.retries}
+	for i, collector := range collectors {
+		if err := registerer.Register(collector); err != nil {
+			for _, registered := range collectors[:i] {
+				registerer.Unregister(registered)
+			}
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// ObserveRequest counts the request and records its latency
+func (m *Metrics) ObserveRequest(address, method, endpoint, code string, duration time.Duration) {
+	m.requests.WithLabelValues(address, method, endpoint, code).Inc()
+	m.requestDuration.WithLabelValues(address, method, endpoint, code).Observe(duration.Seconds())
+}
+
+// ObserveJob records async job wait duration
+func (m *Metrics) ObserveJob(address, outcome string, duration time.Duration) {
+	m.jobDuration.WithLabelValues(address, outcome).Observe(duration.Seconds())
+}
+
+// ObserveLogin counts the login
+func (m *Metrics) ObserveLogin(address string, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	m.logins.WithLabelValues(address, result).Inc()
+}
+
+// ObserveRetry counts the retry
+func (m *Metrics) ObserveRetry(address, reason string) {
+	m.retries.WithLabelValues(address, reason).Inc()
+}
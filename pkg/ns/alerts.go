@@ -0,0 +1,281 @@
+package ns
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Nexenta/go-nexentastor/pkg/logger"
+)
+
+// default interval between alert list requests of AlertWatcher
+const defaultAlertWatchInterval = 30 * time.Second
+
+// nefAlertsURIs - NS lists of each alert source
+var nefAlertsURIs = map[AlertSource]string{
+	AlertSourceFault: "/faults",
+	AlertSourceAlert: "/alerts",
+}
+
+// ListAlertsParams - filters of alert lists, empty filters are not applied
+type ListAlertsParams struct {
+	Severity  AlertSeverity
+	Component string
+
+	// true - only acknowledged alerts, false - only not acknowledged ones
+	Acknowledged *bool
+}
+
+func alertsURI(source AlertSource) (string, error) {
+	uri, ok := nefAlertsURIs[source]
+	if !ok {
+		return "", fmt.Errorf(
+			"Unknown alert source '%s', expected '%s' or '%s'", source, AlertSourceFault, AlertSourceAlert)
+	}
+	return uri, nil
+}
+
+func (p *Provider) listAlerts(source AlertSource, params ListAlertsParams) ([]Alert, error) {
+	uri, err := alertsURI(source)
+	if err != nil {
+		return nil, err
+	}
+
+	query := map[string]string{
+		"severity":  string(params.Severity),
+		"component": params.Component,
+		"fields":    "id,severity,component,message,time,acknowledged",
+	}
+	if params.Acknowledged != nil {
+		query["acknowledged"] = strconv.FormatBool(*params.Acknowledged)
+	}
+
+	response := nefAlertsResponse{}
+	err = p.sendRequestWithStruct(http.MethodGet, p.RestClient.BuildURI(uri, query), nil, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := []Alert{}
+	for _, alert := range response.Data {
+		alert.Source = source
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, nil
+}
+
+// ListFaults returns NexentaStor faults matching the params, e.g. degraded pools and failed disks
+func (p *Provider) ListFaults(params ListAlertsParams) (alerts []Alert, err error) {
+	p, end := p.startSpan("ListFaults")
+	defer end(&err)
+
+	return p.listAlerts(AlertSourceFault, params)
+}
+
+// ListAlerts returns NexentaStor alerts matching the params, e.g. RSF service failovers
+func (p *Provider) ListAlerts(params ListAlertsParams) (alerts []Alert, err error) {
+	p, end := p.startSpan("ListAlerts")
+	defer end(&err)
+
+	return p.listAlerts(AlertSourceAlert, params)
+}
+
+// AcknowledgeAlert marks the fault or alert as acknowledged, it stays in the list
+func (p *Provider) AcknowledgeAlert(source AlertSource, id string) (err error) {
+	p, end := p.startSpan("AcknowledgeAlert")
+	defer end(&err)
+
+	if id == "" {
+		return fmt.Errorf("Alert id is required")
+	}
+	uri, err := alertsURI(source)
+	if err != nil {
+		return err
+	}
+
+	return p.sendRequest(http.MethodPost, fmt.Sprintf("%s/%s/acknowledge", uri, url.PathEscape(id)), nil)
+}
+
+// ClearAlert removes the fault or alert from the list
+func (p *Provider) ClearAlert(source AlertSource, id string) (err error) {
+	p, end := p.startSpan("ClearAlert")
+	defer end(&err)
+
+	if id == "" {
+		return fmt.Errorf("Alert id is required")
+	}
+	uri, err := alertsURI(source)
+	if err != nil {
+		return err
+	}
+
+	return p.sendRequest(http.MethodDelete, fmt.Sprintf("%s/%s", uri, url.PathEscape(id)), nil)
+}
+
+// AlertEvent - new fault or alert found by AlertWatcher, or a failed poll if Err is set
+type AlertEvent struct {
+	Alert Alert
+	Err   error
+}
+
+type alertKey struct {
+	source AlertSource
+	id     string
+}
+
+// AlertWatcher - polls NexentaStor faults and alerts, each alert is reported once while it's in the list
+type AlertWatcher struct {
+	Provider ProviderInterface
+	Log      logger.Logger
+
+	// interval between polls
+	Interval time.Duration
+
+	// sources to poll
+	Sources []AlertSource
+
+	// filters of polled lists
+	Params ListAlertsParams
+}
+
+// Watch polls alerts until the context is done, then the channel is closed
+// Alerts found by the first poll are sent too, so already existing problems are not missed.
+// Alerts removed from the list are forgotten and reported again if they reappear.
+// Failed polls are sent as events with Err set, polling continues.
+func (w *AlertWatcher) Watch(ctx context.Context) <-chan AlertEvent {
+	events := make(chan AlertEvent)
+
+	go func() {
+		defer close(events)
+
+		l := w.Log.WithField("func", "AlertWatcher.Watch()")
+		seen := map[alertKey]bool{}
+
+		ticker := time.NewTicker(w.Interval)
+		defer ticker.Stop()
+
+		for {
+			if !w.poll(ctx, events, seen) {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				l.Debugf("stopped: %v", ctx.Err())
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return events
+}
+
+// poll sends new alerts of all sources, returns false if the context is done
+func (w *AlertWatcher) poll(ctx context.Context, events chan<- AlertEvent, seen map[alertKey]bool) bool {
+	send := func(event AlertEvent) bool {
+		select {
+		case events <- event:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for _, source := range w.Sources {
+		var alerts []Alert
+		var err error
+		switch source {
+		case AlertSourceFault:
+			alerts, err = w.Provider.ListFaults(w.Params)
+		case AlertSourceAlert:
+			alerts, err = w.Provider.ListAlerts(w.Params)
+		default:
+			_, err = alertsURI(source)
+		}
+		if err != nil {
+			if !send(AlertEvent{Err: err}) {
+				return false
+			}
+			continue
+		}
+
+		listed := map[alertKey]bool{}
+		for _, alert := range alerts {
+			key := alertKey{source, alert.Id}
+			listed[key] = true
+			if seen[key] {
+				continue
+			}
+			if !send(AlertEvent{Alert: alert}) {
+				return false
+			}
+			seen[key] = true
+		}
+
+		// forget alerts of the source which are not in the list anymore
+		for key := range seen {
+			if key.source == source && !listed[key] {
+				delete(seen, key)
+			}
+		}
+	}
+
+	return true
+}
+
+// AlertWatcherArgs - params to create AlertWatcher instance
+type AlertWatcherArgs struct {
+	Provider ProviderInterface
+
+	// Interval - interval between polls, defaultAlertWatchInterval if not set
+	Interval time.Duration
+
+	// Sources - sources to poll, faults and alerts if not set
+	Sources []AlertSource
+
+	// Params - filters of polled lists
+	Params ListAlertsParams
+
+	// Log - logrus entry to write to, not used if Logger is set
+	Log *logrus.Entry
+
+	// Logger - logger to write to, see ProviderArgs
+	Logger logger.Logger
+}
+
+// NewAlertWatcher creates NexentaStor faults and alerts watcher
+func NewAlertWatcher(args AlertWatcherArgs) (*AlertWatcher, error) {
+	if args.Provider == nil {
+		return nil, fmt.Errorf("NexentaStor provider not specified")
+	}
+
+	interval := args.Interval
+	if interval <= 0 {
+		interval = defaultAlertWatchInterval
+	}
+
+	sources := args.Sources
+	if len(sources) == 0 {
+		sources = []AlertSource{AlertSourceFault, AlertSourceAlert}
+	}
+	for _, source := range sources {
+		if _, err := alertsURI(source); err != nil {
+			return nil, err
+		}
+	}
+
+	return &AlertWatcher{
+		Provider: args.Provider,
+		Log:      logger.Pick(args.Logger, args.Log).WithField("cmp", "AlertWatcher"),
+		Interval: interval,
+		Sources:  sources,
+		Params:   args.Params,
+	}, nil
+}
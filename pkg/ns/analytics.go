@@ -0,0 +1,201 @@
+package ns
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// default interval between points of analytics time series
+const defaultAnalyticsResolution = time.Minute
+
+// Validate checks the time range and returns the query with defaults set
+func (q AnalyticsQuery) Validate() (AnalyticsQuery, error) {
+	if q.Start.IsZero() {
+		return q, fmt.Errorf("Parameter 'AnalyticsQuery.Start' is required")
+	}
+	if q.End.IsZero() {
+		q.End = time.Now()
+	}
+	if !q.End.After(q.Start) {
+		return q, fmt.Errorf("Analytics query end %s must be after start %s", q.End, q.Start)
+	}
+
+	if q.Resolution <= 0 {
+		q.Resolution = defaultAnalyticsResolution
+	} else if rest := q.Resolution % time.Second; rest != 0 {
+		q.Resolution += time.Second - rest
+	}
+
+	return q, nil
+}
+
+// queryParams returns NS query parameters of the time range
+func (q AnalyticsQuery) queryParams() map[string]string {
+	return map[string]string{
+		"startTime":  q.Start.UTC().Format(time.RFC3339),
+		"endTime":    q.End.UTC().Format(time.RFC3339),
+		"resolution": strconv.FormatInt(int64(q.Resolution/time.Second), 10),
+	}
+}
+
+// getIOStats requests I/O performance counters of the analytics resource
+func (p *Provider) getIOStats(path, resource string, query AnalyticsQuery) (IOTimeSeries, error) {
+	query, err := query.Validate()
+	if err != nil {
+		return IOTimeSeries{}, err
+	}
+
+	response := nefIOStatsResponse{}
+	err = p.sendRequestWithStruct(http.MethodGet, p.RestClient.BuildURI(path, query.queryParams()), nil, &response)
+	if err != nil {
+		return IOTimeSeries{}, err
+	}
+
+	points := response.Data
+	if points == nil {
+		points = []IOStats{}
+	}
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+
+	return IOTimeSeries{
+		Resource:   resource,
+		Resolution: query.Resolution,
+		Points:     points,
+	}, nil
+}
+
+// GetPoolStats returns I/O performance time series of the pool
+func (p *Provider) GetPoolStats(pool string, query AnalyticsQuery) (series IOTimeSeries, err error) {
+	p, end := p.startSpan("GetPoolStats", attrPath.String(pool))
+	defer end(&err)
+
+	if pool == "" {
+		return series, fmt.Errorf("Pool name is required")
+	}
+
+	return p.getIOStats(fmt.Sprintf("/analytics/pools/%s", url.PathEscape(pool)), pool, query)
+}
+
+// GetVdevStats returns I/O performance time series of the pool vdev, e.g. "mirror-0" or "c1t0d0"
+func (p *Provider) GetVdevStats(pool, vdev string, query AnalyticsQuery) (series IOTimeSeries, err error) {
+	p, end := p.startSpan("GetVdevStats", attrPath.String(pool))
+	defer end(&err)
+
+	if pool == "" || vdev == "" {
+		return series, fmt.Errorf("Pool name and vdev name are required")
+	}
+
+	return p.getIOStats(
+		fmt.Sprintf("/analytics/pools/%s/vdevs/%s", url.PathEscape(pool), url.PathEscape(vdev)),
+		fmt.Sprintf("%s/%s", pool, vdev),
+		query,
+	)
+}
+
+// GetDatasetStats returns I/O performance time series of the filesystem or volume
+func (p *Provider) GetDatasetStats(path string, query AnalyticsQuery) (series IOTimeSeries, err error) {
+	p, end := p.startSpan("GetDatasetStats", attrPath.String(path))
+	defer end(&err)
+
+	if path == "" {
+		return series, fmt.Errorf("Dataset path is required")
+	}
+
+	return p.getIOStats(fmt.Sprintf("/analytics/datasets/%s", url.PathEscape(path)), path, query)
+}
+
+// GetNICStats returns performance time series of the network interface, e.g. "ixgbe0"
+func (p *Provider) GetNICStats(name string, query AnalyticsQuery) (series NICTimeSeries, err error) {
+	p, end := p.startSpan("GetNICStats")
+	defer end(&err)
+
+	if name == "" {
+		return series, fmt.Errorf("Network interface name is required")
+	}
+	query, err = query.Validate()
+	if err != nil {
+		return series, err
+	}
+
+	uri := p.RestClient.BuildURI(
+		fmt.Sprintf("/analytics/network/interfaces/%s", url.PathEscape(name)),
+		query.queryParams(),
+	)
+
+	response := nefNICStatsResponse{}
+	err = p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
+	if err != nil {
+		return series, err
+	}
+
+	points := response.Data
+	if points == nil {
+		points = []NICStats{}
+	}
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+
+	return NICTimeSeries{
+		Interface:  name,
+		Resolution: query.Resolution,
+		Points:     points,
+	}, nil
+}
+
+// Average returns average of each counter over all points, Time is the time of the first point
+func (s IOTimeSeries) Average() IOStats {
+	average := IOStats{}
+	if len(s.Points) == 0 {
+		return average
+	}
+
+	for _, point := range s.Points {
+		average.ReadIOPS += point.ReadIOPS
+		average.WriteIOPS += point.WriteIOPS
+		average.ReadBandwidth += point.ReadBandwidth
+		average.WriteBandwidth += point.WriteBandwidth
+		average.ReadLatency += point.ReadLatency
+		average.WriteLatency += point.WriteLatency
+	}
+
+	n := float64(len(s.Points))
+	average.Time = s.Points[0].Time
+	average.ReadIOPS /= n
+	average.WriteIOPS /= n
+	average.ReadBandwidth /= n
+	average.WriteBandwidth /= n
+	average.ReadLatency /= n
+	average.WriteLatency /= n
+
+	return average
+}
+
+// Peak returns maximum of each counter over all points, Time is the time of the first point
+func (s IOTimeSeries) Peak() IOStats {
+	peak := IOStats{}
+	if len(s.Points) == 0 {
+		return peak
+	}
+
+	peak.Time = s.Points[0].Time
+	for _, point := range s.Points {
+		peak.ReadIOPS = maxFloat(peak.ReadIOPS, point.ReadIOPS)
+		peak.WriteIOPS = maxFloat(peak.WriteIOPS, point.WriteIOPS)
+		peak.ReadBandwidth = maxFloat(peak.ReadBandwidth, point.ReadBandwidth)
+		peak.WriteBandwidth = maxFloat(peak.WriteBandwidth, point.WriteBandwidth)
+		peak.ReadLatency = maxFloat(peak.ReadLatency, point.ReadLatency)
+		peak.WriteLatency = maxFloat(peak.WriteLatency, point.WriteLatency)
+	}
+
+	return peak
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
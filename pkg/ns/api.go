@@ -5,23 +5,72 @@ import (
     "fmt"
     "net/http"
     "net/url"
+    "regexp"
     "strconv"
+    "strings"
+
+    "github.com/Nexenta/go-nexentastor/pkg/metrics"
 )
 
 // NexentaStor filesystem list limit (<=)
 // TODO change this limit base on specified NS version
 const nsFilesystemListLimit = 100
 
+// MaxLun - the highest LUN number NexentaStor can assign to a mapping
+const MaxLun = 16383
+
+// Volume block size limits (ZFS volblocksize)
+const (
+    MinVolumeBlockSize = 512
+    MaxVolumeBlockSize = 128 * 1024
+)
+
+// filesystem fields requested from NexentaStor by default
+const nsFilesystemFields = "path,mountPoint,bytesAvailable,bytesUsed,referencedQuotaSize,sharedOverNfs,sharedOverSmb"
+
+// volume fields requested from NexentaStor by default
+const nsVolumeFields = "path,bytesAvailable,bytesUsed,volumeSize,volumeBlockSize,sparseVolume," +
+    "compressionMode,syncMode,logBias,reservationSize"
+
+var volumeCompressionModeRegexp = regexp.MustCompile("^(on|off|lz4|lzjb|zle|gzip|gzip-[1-9])$")
+
 // LogIn logs in to NexentaStor API and get auth token
-func (p *Provider) LogIn() error {
-    l := p.Log.WithField("func", "LogIn()")
+// Concurrent logins to the same NexentaStor as the same user are coalesced into one login request.
+func (p *Provider) LogIn() (err error) {
+    p, end := p.startSpan("LogIn")
+    defer end(&err)
+
+    if p.auth != nil {
+        _, err := p.renewAuthToken("")
+        return err
+    }
+
+    _, token, err := p.sendLogInRequest()
+    if err != nil {
+        return err
+    }
+
+    p.RestClient.SetAuthToken(token)
+    return nil
+}
+
+// sendLogInRequest sends login request, returns used credentials and new auth token
+func (p *Provider) sendLogInRequest() (credentials Credentials, token string, err error) {
+    l := p.logger().WithField("func", "LogIn()")
+
+    defer func() { p.observeLogin(err) }()
+
+    credentials, err = p.getCredentials()
+    if err != nil {
+        return credentials, "", err
+    }
 
     data := nefAuthLoginRequest{
-        Username: p.Username,
-        Password: p.Password,
+        Username: credentials.Username,
+        Password: credentials.Password,
     }
 
-    _, bodyBytes, err := p.RestClient.Send(http.MethodPost, "auth/login", data)
+    _, bodyBytes, err := p.send(http.MethodPost, "auth/login", data)
     if err != nil {
         // try to parse error from rest response
         nefError := p.parseNefError(bodyBytes, "Login request")
@@ -31,40 +80,45 @@ func (p *Provider) LogIn() error {
                     "login to NexentaStor %s failed (username: '%s'), "+
                         "please make sure to use correct address and password",
                     p.Address,
-                    p.Username)
+                    credentials.Username)
             }
-            return nefError
+            return credentials, "", nefError
         }
 
-        return fmt.Errorf("Login request: failed, response: %s; error: %s", bodyBytes, err)
+        return credentials, "", fmt.Errorf("Login request: failed, response: %s; error: %s", bodyBytes, err)
     }
 
     response := nefAuthLoginResponse{}
     if err := json.Unmarshal(bodyBytes, &response); err != nil {
-        return fmt.Errorf("Login request: cannot unmarshal JSON from: '%s' to '%+v': %s", bodyBytes, response, err)
+        return credentials, "", fmt.Errorf("Login request: cannot unmarshal JSON from: '%s' to '%+v': %s", bodyBytes, response, err)
     } else if response.Token == "" {
-        return fmt.Errorf("Login request: token not found in response: '%s'", bodyBytes)
+        return credentials, "", fmt.Errorf("Login request: token not found in response: '%s'", bodyBytes)
     }
 
-    p.RestClient.SetAuthToken(response.Token)
     l.Debugf("login token has been updated")
-    return nil
+    return credentials, response.Token, nil
 }
 
 // GetLicense returns NexentaStor license
 func (p *Provider) GetLicense() (license License, err error) {
+    p, end := p.startSpan("GetLicense")
+    defer end(&err)
+
     err = p.sendRequestWithStruct(http.MethodGet, "/settings/license", nil, &license)
     return license, err
 }
 
 // GetPools returns NexentaStor pools
-func (p *Provider) GetPools() ([]Pool, error) {
+func (p *Provider) GetPools() (pools []Pool, err error) {
+    p, end := p.startSpan("GetPools")
+    defer end(&err)
+
     uri := p.RestClient.BuildURI("/storage/pools", map[string]string{
         "fields": "poolName,health,status",
     })
 
     response := nefStoragePoolsResponse{}
-    err := p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
+    err = p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
     if err != nil {
         return nil, err
     }
@@ -73,14 +127,17 @@ func (p *Provider) GetPools() ([]Pool, error) {
 }
 
 // GetFilesystemAvailableCapacity returns NexentaStor filesystem available size by its path
-func (p *Provider) GetFilesystemAvailableCapacity(path string) (int64, error) {
+func (p *Provider) GetFilesystemAvailableCapacity(path string) (available int64, err error) {
+    p, end := p.startSpan("GetFilesystemAvailableCapacity", attrPath.String(path))
+    defer end(&err)
+
     uri := p.RestClient.BuildURI("/storage/filesystems", map[string]string{
         "path":   path,
         "fields": "bytesAvailable",
     })
 
     response := nefStorageFilesystemsResponse{}
-    err := p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
+    err = p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
     if err != nil {
         return 0, err
     }
@@ -95,13 +152,16 @@ func (p *Provider) GetFilesystemAvailableCapacity(path string) (int64, error) {
 
 // GetFilesystem returns NexentaStor filesystem by its path
 func (p *Provider) GetFilesystem(path string) (filesystem Filesystem, err error) {
+    p, end := p.startSpan("GetFilesystem", attrPath.String(path))
+    defer end(&err)
+
     if path == "" {
         return filesystem, fmt.Errorf("Filesystem path is empty")
     }
 
     uri := p.RestClient.BuildURI("/storage/filesystems", map[string]string{
         "path":   path,
-        "fields": "path,mountPoint,bytesAvailable,bytesUsed,sharedOverNfs,sharedOverSmb",
+        "fields": nsFilesystemFields,
     })
 
     response := nefStorageFilesystemsResponse{}
@@ -119,7 +179,7 @@ func (p *Provider) GetFilesystem(path string) (filesystem Filesystem, err error)
 
 // GetVolumesWithStartingToken returns volumes by parent volumeGroup after specified starting token
 // parent - parent volumeGroup's path
-// startingToken - a path to a specific volume to start AFTER this token
+// startingToken - a token returned by previous call to start AFTER the last returned volume
 // limit - the maximum count of volumes to return in the list
 // Function may return nextToken if there is more volumes than limit value
 func (p *Provider) GetVolumesWithStartingToken(parent string, startingToken string, limit int) (
@@ -127,78 +187,88 @@ func (p *Provider) GetVolumesWithStartingToken(parent string, startingToken stri
     nextToken string,
     err error,
 ) {
-    startingTokenFound := false
-    if startingToken == "" {
-        // if no startingToken set then filesystem list should starts with the first one
-        startingTokenFound = true
-    }
+    p, end := p.startSpan("GetVolumesWithStartingToken", attrPath.String(parent))
+    defer end(&err)
 
-    // if no limit set then all filesystem after startingToken should be in the response
-    noLimit := limit == 0
+    it := p.GetVolumeIterator(parent, IteratorParams{PageSize: limit, StartingToken: startingToken})
+    ctx := p.context()
 
-    // load volumes using slice requests
-    offset := 0
-    lastResultCount := nsFilesystemListLimit
-    for (noLimit || len(volumes) < limit) && lastResultCount >= nsFilesystemListLimit {
-        volumesSlice, err := p.GetVolumesSlice(parent, nsFilesystemListLimit-1, offset)
+    // if no limit set then all volumes after startingToken should be in the response
+    for !it.Done() && (limit == 0 || len(volumes) < limit) {
+        pageSize := it.pageSize
+        if limit != 0 && limit-len(volumes) < pageSize {
+            pageSize = limit - len(volumes)
+        }
+        volumesPage, err := it.nextN(ctx, pageSize)
         if err != nil {
             return nil, "", err
         }
-        for _, fs := range volumesSlice {
-            if startingTokenFound {
-                volumes = append(volumes, fs)
-                if len(volumes) == limit {
-                    nextToken = fs.Path
-                    break
-                }
-            } else if fs.Path == startingToken {
-                startingTokenFound = true
-            }
-        }
-        lastResultCount = len(volumesSlice)
-        offset += lastResultCount
+        volumes = append(volumes, volumesPage...)
+    }
+
+    if limit != 0 && len(volumes) == limit {
+        nextToken = it.Token()
     }
 
     return volumes, nextToken, nil
 }
 
 // GetVolumes returns all NexentaStor volumes by parent volumeGroup
-func (p *Provider) GetVolumes(parent string) ([]Volume, error) {
-    volumes := []Volume{}
+func (p *Provider) GetVolumes(parent string) (volumes []Volume, err error) {
+    p, end := p.startSpan("GetVolumes", attrPath.String(parent))
+    defer end(&err)
+
+    return p.ListVolumes(parent, ListOptions{})
+}
+
+// GetVolumeIterator returns iterator over volumes of parent volumeGroup
+func (p *Provider) GetVolumeIterator(parent string, params IteratorParams) *VolumeIterator {
+    return NewVolumeIterator(params, func(limit, offset int) ([]Volume, error) {
+        return p.getVolumesPage(parent, limit, offset, params.ListOptions)
+    })
+}
 
-    offset := 0
-    lastResultCount := nsFilesystemListLimit
-    for lastResultCount >= nsFilesystemListLimit {
-        volumesSlice, err := p.GetVolumesSlice(parent, nsFilesystemListLimit-1, offset)
+// ListVolumes returns all volumes of parent volumeGroup matching the options
+func (p *Provider) ListVolumes(parent string, options ListOptions) (volumes []Volume, err error) {
+    p, end := p.startSpan("ListVolumes", attrPath.String(parent))
+    defer end(&err)
+
+    volumes = []Volume{}
+
+    it := p.GetVolumeIterator(parent, IteratorParams{ListOptions: options})
+    for !it.Done() {
+        volumesPage, err := it.Next(p.context())
         if err != nil {
             return nil, err
         }
-        for _, vol := range volumesSlice {
-            volumes = append(volumes, vol)
-        }
-        lastResultCount = len(volumesSlice)
-        offset += lastResultCount
+        volumes = append(volumes, volumesPage...)
     }
 
     return volumes, nil
 }
 
 // GetFilesystems returns all NexentaStor filesystems by parent filesystem
-func (p *Provider) GetFilesystems(parent string) ([]Filesystem, error) {
-    filesystems := []Filesystem{}
+func (p *Provider) GetFilesystems(parent string) (filesystems []Filesystem, err error) {
+    p, end := p.startSpan("GetFilesystems", attrPath.String(parent))
+    defer end(&err)
+
+    return p.ListFilesystems(parent, ListOptions{})
+}
+
+// ListFilesystems returns all filesystems of parent filesystem matching the options
+func (p *Provider) ListFilesystems(parent string, options ListOptions) (filesystems []Filesystem, err error) {
+    p, end := p.startSpan("ListFilesystems", attrPath.String(parent))
+    defer end(&err)
+
+    filesystems = []Filesystem{}
 
-    offset := 1
-    lastResultCount := nsFilesystemListLimit
-    for lastResultCount >= nsFilesystemListLimit {
-        filesystemsSlice, err := p.GetFilesystemsSlice(parent, nsFilesystemListLimit-1, offset)
+    it := p.GetFilesystemIterator(parent, IteratorParams{ListOptions: options})
+    for !it.Done() {
+        filesystemsPage, err := it.Next(p.context())
         if err != nil {
             return nil, err
         }
-        for _, fs := range filesystemsSlice {
-            filesystems = append(filesystems, fs)
-        }
-        lastResultCount = len(filesystemsSlice)
-        offset += lastResultCount
+        filesystems = append(filesystems, filesystemsPage...)
     }
 
     return filesystems, nil
@@ -206,7 +276,7 @@ func (p *Provider) GetFilesystems(parent string) ([]Filesystem, error) {
 
 // GetFilesystemsWithStartingToken returns filesystems by parent filesystem after specified starting token
 // parent - parent filesystem's path
-// startingToken - a path to a specific filesystem to start AFTER this token
+// startingToken - a token returned by previous call to start AFTER the last returned filesystem
 // limit - the maximum count of filesystems to return in the list
 // Function may return nextToken if there is more filesystems than limit value
 func (p *Provider) GetFilesystemsWithStartingToken(parent string, startingToken string, limit int) (
@@ -214,44 +284,45 @@ func (p *Provider) GetFilesystemsWithStartingToken(parent string, startingToken
     nextToken string,
     err error,
 ) {
-    startingTokenFound := false
-    if startingToken == "" {
-        // if no startingToken set then filesystem list should starts with the first one
-        startingTokenFound = true
-    }
+    p, end := p.startSpan("GetFilesystemsWithStartingToken", attrPath.String(parent))
+    defer end(&err)
 
-    // if no limit set then all filesystem after startingToken should be in the response
-    noLimit := limit == 0
+    it := p.GetFilesystemIterator(parent, IteratorParams{PageSize: limit, StartingToken: startingToken})
+    ctx := p.context()
 
-    // load filesystems using slice requests
-    offset := 1
-    lastResultCount := nsFilesystemListLimit
-    for (noLimit || len(filesystems) < limit) && lastResultCount >= nsFilesystemListLimit {
-        filesystemsSlice, err := p.GetFilesystemsSlice(parent, nsFilesystemListLimit-1, offset)
+    // if no limit set then all filesystems after startingToken should be in the response
+    for !it.Done() && (limit == 0 || len(filesystems) < limit) {
+        pageSize := it.pageSize
+        if limit != 0 && limit-len(filesystems) < pageSize {
+            pageSize = limit - len(filesystems)
+        }
+        filesystemsPage, err := it.nextN(ctx, pageSize)
         if err != nil {
             return nil, "", err
         }
-        for _, fs := range filesystemsSlice {
-            if startingTokenFound {
-                filesystems = append(filesystems, fs)
-                if len(filesystems) == limit {
-                    nextToken = fs.Path
-                    break
-                }
-            } else if fs.Path == startingToken {
-                startingTokenFound = true
-            }
-        }
-        lastResultCount = len(filesystemsSlice)
-        offset += lastResultCount
+        filesystems = append(filesystems, filesystemsPage...)
+    }
+
+    if limit != 0 && len(filesystems) == limit {
+        nextToken = it.Token()
     }
 
     return filesystems, nextToken, nil
 }
 
+// GetFilesystemIterator returns iterator over filesystems of parent filesystem, parent itself is excluded
+func (p *Provider) GetFilesystemIterator(parent string, params IteratorParams) *FilesystemIterator {
+    return NewFilesystemIterator(parent, params, func(limit, offset int) ([]Filesystem, error) {
+        return p.getFilesystemsPage(parent, limit, offset, params.ListOptions)
+    })
+}
+
 // GetFilesystemsSlice returns a slice of filesystems by parent filesystem with specified limit and offset
 // offset - the first record number of collection, that would be included in result
-func (p *Provider) GetFilesystemsSlice(parent string, limit, offset int) ([]Filesystem, error) {
+func (p *Provider) GetFilesystemsSlice(parent string, limit, offset int) (filesystems []Filesystem, err error) {
+    p, end := p.startSpan("GetFilesystemsSlice", attrPath.String(parent))
+    defer end(&err)
+
     if limit <= 0 || limit >= nsFilesystemListLimit {
         return nil, fmt.Errorf(
             "GetFilesystemsSlice(): parameter 'limit' must be greater that 0 and less than %d, got: %d",
@@ -265,21 +336,14 @@ func (p *Provider) GetFilesystemsSlice(parent string, limit, offset int) ([]File
         )
     }
 
-    uri := p.RestClient.BuildURI("/storage/filesystems", map[string]string{
-        "parent": parent,
-        "limit":  fmt.Sprint(limit + 1), // the result includes parent itself
-        "offset": fmt.Sprint(offset),
-        "fields": "path,mountPoint,bytesAvailable,bytesUsed,sharedOverNfs,sharedOverSmb",
-    })
-
-    response := nefStorageFilesystemsResponse{}
-    err := p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
+    // the result includes parent itself
+    filesystemsPage, err := p.getFilesystemsPage(parent, limit+1, offset, ListOptions{})
     if err != nil {
         return nil, err
     }
 
-    filesystems := []Filesystem{}
-    for _, fs := range response.Data {
+    filesystems = []Filesystem{}
+    for _, fs := range filesystemsPage {
         if fs.Path != parent { // exclude parent filesystem from the list
             filesystems = append(filesystems, fs)
         }
@@ -288,6 +352,27 @@ func (p *Provider) GetFilesystemsSlice(parent string, limit, offset int) ([]File
     return filesystems, nil
 }
 
+// getFilesystemsPage returns filesystems as NS lists them, including parent filesystem
+func (p *Provider) getFilesystemsPage(parent string, limit, offset int, options ListOptions) ([]Filesystem, error) {
+    params, err := options.listQueryParams(nsFilesystemFields)
+    if err != nil {
+        return nil, err
+    }
+    params["parent"] = parent
+    params["limit"] = fmt.Sprint(limit)
+    params["offset"] = fmt.Sprint(offset)
+
+    uri := p.RestClient.BuildURI("/storage/filesystems", params)
+
+    response := nefStorageFilesystemsResponse{}
+    err = p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
+    if err != nil {
+        return nil, err
+    }
+
+    return response.Data, nil
+}
+
 // GetVolumesSlice returns a slice of volumes by parent volumeGroup with specified limit and offset
 // offset - the first record number of collection, that would be included in result
 func (p *Provider) GetVolumesSlice(parent string, limit, offset int) ([]Volume, error) {
@@ -304,21 +389,29 @@ func (p *Provider) GetVolumesSlice(parent string, limit, offset int) ([]Volume,
         )
     }
 
-    uri := p.RestClient.BuildURI("/storage/volumes", map[string]string{
-        "parent": parent,
-        "limit":  fmt.Sprint(limit),
-        "offset": fmt.Sprint(offset),
-    })
+    return p.getVolumesPage(parent, limit, offset, ListOptions{})
+}
+
+func (p *Provider) getVolumesPage(parent string, limit, offset int, options ListOptions) ([]Volume, error) {
+    params, err := options.listQueryParams(nsVolumeFields)
+    if err != nil {
+        return nil, err
+    }
+    params["parent"] = parent
+    params["limit"] = fmt.Sprint(limit)
+    params["offset"] = fmt.Sprint(offset)
+
+    uri := p.RestClient.BuildURI("/storage/volumes", params)
 
     response := nefStorageVolumesResponse{}
-    err := p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
+    err = p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
     if err != nil {
         return nil, err
     }
 
     volumes := []Volume{}
-    for _, fs := range response.Data {
-        volumes = append(volumes, fs)
+    for _, volume := range response.Data {
+        volumes = append(volumes, volume)
     }
 
     return volumes, nil
@@ -333,7 +426,10 @@ type CreateFilesystemParams struct {
 }
 
 // CreateFilesystem creates filesystem by path
-func (p *Provider) CreateFilesystem(params CreateFilesystemParams) error {
+func (p *Provider) CreateFilesystem(params CreateFilesystemParams) (err error) {
+    p, end := p.startSpan("CreateFilesystem", attrPath.String(params.Path))
+    defer end(&err)
+
     if params.Path == "" {
         return fmt.Errorf("Parameter 'CreateFilesystemParams.Path' is required")
     }
@@ -350,7 +446,10 @@ type UpdateFilesystemParams struct {
 }
 
 // UpdateFilesystem updates filesystem by path
-func (p *Provider) UpdateFilesystem(path string, params UpdateFilesystemParams) error {
+func (p *Provider) UpdateFilesystem(path string, params UpdateFilesystemParams) (err error) {
+    p, end := p.startSpan("UpdateFilesystem", attrPath.String(path))
+    defer end(&err)
+
     if path == "" {
         return fmt.Errorf("Parameter 'path' is required")
     }
@@ -392,22 +491,49 @@ type DestroyFilesystemParams struct {
 
 // DestroyFilesystem destroys filesystem on NS, may destroy snapshots and promote clones (see DestroyFilesystemParams)
 // Path format: 'pool/dataset/filesystem'
-func (p *Provider) DestroyFilesystem(path string, params DestroyFilesystemParams) error {
-    err := p.destroyFilesystem(path, params.DestroySnapshots)
+func (p *Provider) DestroyFilesystem(path string, params DestroyFilesystemParams) (err error) {
+    p, end := p.startSpan("DestroyFilesystem", attrPath.String(path))
+    defer end(&err)
+
+    err = p.destroyFilesystem(path, params.DestroySnapshots)
     if err == nil {
         return nil
     } else if !params.PromoteMostRecentCloneIfExists || !IsAlreadyExistNefError(err) {
         return err
     }
 
-    // If here then filesystem deletion request has failed because
-    // the filesystem has dependent clones (EEXIST error code), trying
-    // to promote the most recent clone to make the filesystem independent:
+    err = p.promoteMostRecentCloneAndDestroy(
+        path,
+        p.PromoteFilesystem,
+        func() error { return p.destroyFilesystem(path, params.DestroySnapshots) },
+    )
+
+    // if not a NefError, wrap it into an explanation
+    if err != nil && !IsNefError(err) {
+        return fmt.Errorf("Failed to delete filesystem '%s': %s", path, err)
+    }
+
+    return err
+}
+
+// promoteMostRecentCloneAndDestroy promotes the most recent clone of dataset's snapshots
+// and retries dataset deletion, used when deletion fails because dataset has dependent clones
+func (p *Provider) promoteMostRecentCloneAndDestroy(
+    path string,
+    promote func(clonePath string) error,
+    destroy func() error,
+) error {
+    // Dataset deletion request has failed because the dataset has dependent
+    // clones (EEXIST error code), trying to promote the most recent clone
+    // to make the dataset independent:
 
     maxAttemptCount := 3
     var mostRecentError error
 
     for i := 0; i < maxAttemptCount; i++ {
+        if i > 0 {
+            p.observeRetry(metrics.RetryDependentClones)
+        }
         mostRecentError = nil
 
         snapshots, err := p.GetSnapshots(path, true)
@@ -448,26 +574,21 @@ func (p *Provider) DestroyFilesystem(path string, params DestroyFilesystemParams
         }
 
         if mostRecentClone != "" {
-            err := p.PromoteFilesystem(mostRecentClone)
+            err := promote(mostRecentClone)
             if err != nil {
                 mostRecentError = fmt.Errorf("failed to promote clone '%s': %s", mostRecentClone, err)
                 continue
             }
         }
 
-        mostRecentError = p.destroyFilesystem(path, params.DestroySnapshots)
+        mostRecentError = destroy()
         if mostRecentError == nil {
             return nil
-        } else if !IsAlreadyExistNefError(mostRecentError) { // if EEXIST code - filesystem still has dependent clones
+        } else if !IsAlreadyExistNefError(mostRecentError) { // if EEXIST code - dataset still has dependent clones
             break
         }
     }
 
-    // if not a NefError, wrap it into an explanation
-    if !IsNefError(mostRecentError) {
-        return fmt.Errorf("Failed to delete filesystem '%s': %s", path, mostRecentError)
-    }
-
     return mostRecentError
 }
 
@@ -488,7 +609,10 @@ func (p *Provider) destroyFilesystem(path string, destroySnapshots bool) error {
 }
 
 // PromoteFilesystem promotes a cloned filesystem to be no longer dependent on its original snapshot
-func (p *Provider) PromoteFilesystem(path string) error {
+func (p *Provider) PromoteFilesystem(path string) (err error) {
+    p, end := p.startSpan("PromoteFilesystem", attrPath.String(path))
+    defer end(&err)
+
     if path == "" {
         return fmt.Errorf("Filesystem path is required")
     }
@@ -506,41 +630,40 @@ type CreateNfsShareParams struct {
     ReadOnlyList        []NfsRuleList       `json:"readOnlyList"`
 }
 
-// CreateNfsShare creates NFS share on specified filesystem
-// CLI test:
-//   showmount -e HOST
-//   mkdir -p /mnt/test && sudo mount -v -t nfs HOST:/pool/fs /mnt/test
-//   findmnt /mnt/test
-func (p *Provider) CreateNfsShare(params CreateNfsShareParams) error {
-    if params.Filesystem == "" {
-        return fmt.Errorf("CreateNfsShareParams.Filesystem is required")
+// SecurityContext returns security context the share is created with,
+// access is denied to everyone ("none") in an empty list, read-write access is allowed to all if both are empty
+func (params CreateNfsShareParams) SecurityContext() NfsSecurityContext {
+    defaultEtype := "fqdn"
+    context := NfsSecurityContext{
+        SecurityModes: []string{"sys"},
+        ReadWriteList: params.ReadWriteList,
+        ReadOnlyList:  params.ReadOnlyList,
     }
 
-    defaultEtype := "fqdn"
-    if len(params.ReadWriteList) == 0 {
-        if len(params.ReadOnlyList) == 0 {
-            params.ReadOnlyList = []NfsRuleList{
+    if len(context.ReadWriteList) == 0 {
+        if len(context.ReadOnlyList) == 0 {
+            context.ReadOnlyList = []NfsRuleList{
                 {
                     Entity: "none",
                     Etype: defaultEtype,
                 },
             }
-            params.ReadWriteList = []NfsRuleList{
+            context.ReadWriteList = []NfsRuleList{
                 {
                     Entity: "*",
                     Etype: defaultEtype,
                 },
             }
         } else {
-            params.ReadWriteList = []NfsRuleList{
+            context.ReadWriteList = []NfsRuleList{
                 {
                     Entity: "none",
                     Etype: defaultEtype,
                 },
             }
         }
-    } else if len(params.ReadOnlyList) == 0 {
-        params.ReadOnlyList = []NfsRuleList{
+    } else if len(context.ReadOnlyList) == 0 {
+        context.ReadOnlyList = []NfsRuleList{
             {
                 Entity: "none",
                 Etype: defaultEtype,
@@ -548,23 +671,54 @@ func (p *Provider) CreateNfsShare(params CreateNfsShareParams) error {
         }
     }
 
+    return context
+}
+
+// CreateNfsShare creates NFS share on specified filesystem
+// CLI test:
+//   showmount -e HOST
+//   mkdir -p /mnt/test && sudo mount -v -t nfs HOST:/pool/fs /mnt/test
+//   findmnt /mnt/test
+func (p *Provider) CreateNfsShare(params CreateNfsShareParams) (err error) {
+    p, end := p.startSpan("CreateNfsShare", attrPath.String(params.Filesystem))
+    defer end(&err)
+
+    if params.Filesystem == "" {
+        return fmt.Errorf("CreateNfsShareParams.Filesystem is required")
+    }
+
     data := nefNasNfsRequest{
-        Filesystem: params.Filesystem,
-        Anon:       "root",
-        SecurityContexts: []nefNasNfsRequestSecurityContext{
-            {
-                SecurityModes: []string{"sys"},
-                ReadWriteList: params.ReadWriteList,
-                ReadOnlyList: params.ReadOnlyList,
-            },
-        },
+        Filesystem:       params.Filesystem,
+        Anon:             "root",
+        SecurityContexts: []NfsSecurityContext{params.SecurityContext()},
     }
 
     return p.sendRequest(http.MethodPost, "nas/nfs", data)
 }
 
+// GetNfsShare returns NFS share of the filesystem
+func (p *Provider) GetNfsShare(path string) (share NfsShare, err error) {
+    p, end := p.startSpan("GetNfsShare", attrPath.String(path))
+    defer end(&err)
+
+    if path == "" {
+        return share, fmt.Errorf("Filesystem path is required")
+    }
+
+    uri := p.RestClient.BuildURI(
+        fmt.Sprintf("/nas/nfs/%s", url.PathEscape(path)),
+        map[string]string{"fields": "filesystem,anon,securityContexts"},
+    )
+
+    err = p.sendRequestWithStruct(http.MethodGet, uri, nil, &share)
+    return share, err
+}
+
 // DeleteNfsShare destroys NFS chare by filesystem path
-func (p *Provider) DeleteNfsShare(path string) error {
+func (p *Provider) DeleteNfsShare(path string) (err error) {
+    p, end := p.startSpan("DeleteNfsShare", attrPath.String(path))
+    defer end(&err)
+
     if path == "" {
         return fmt.Errorf("Filesystem path is empty")
     }
@@ -587,7 +741,10 @@ type CreateSmbShareParams struct {
 // CLI test:
 //   mkdir -p /mnt/test && sudo mount -v -t cifs -o username=admin,password=Nexenta@1 //HOST//pool_fs /mnt/test
 //   findmnt /mnt/test
-func (p *Provider) CreateSmbShare(params CreateSmbShareParams) error {
+func (p *Provider) CreateSmbShare(params CreateSmbShareParams) (err error) {
+    p, end := p.startSpan("CreateSmbShare", attrPath.String(params.Filesystem))
+    defer end(&err)
+
     if params.Filesystem == "" {
         return fmt.Errorf("CreateSmbShareParams.Filesystem is required")
     }
@@ -596,7 +753,10 @@ func (p *Provider) CreateSmbShare(params CreateSmbShareParams) error {
 }
 
 // GetSmbShareName returns share name for filesystem that shared over SMB
-func (p *Provider) GetSmbShareName(path string) (string, error) {
+func (p *Provider) GetSmbShareName(path string) (shareName string, err error) {
+    p, end := p.startSpan("GetSmbShareName", attrPath.String(path))
+    defer end(&err)
+
     if path == "" {
         return "", fmt.Errorf("Filesystem path is required")
     }
@@ -607,7 +767,7 @@ func (p *Provider) GetSmbShareName(path string) (string, error) {
     )
 
     response := nefNasSmbResponse{}
-    err := p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
+    err = p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
     if err != nil {
         return "", err
     }
@@ -616,7 +776,10 @@ func (p *Provider) GetSmbShareName(path string) (string, error) {
 }
 
 // DeleteSmbShare destroys SMB share by filesystem path
-func (p *Provider) DeleteSmbShare(path string) error {
+func (p *Provider) DeleteSmbShare(path string) (err error) {
+    p, end := p.startSpan("DeleteSmbShare", attrPath.String(path))
+    defer end(&err)
+
     if path == "" {
         return fmt.Errorf("Filesystem path is empty")
     }
@@ -627,7 +790,10 @@ func (p *Provider) DeleteSmbShare(path string) error {
 }
 
 // SetFilesystemACL sets filesystem ACL, so NFS share can allow user to write w/o checking UNIX user uid
-func (p *Provider) SetFilesystemACL(path string, aclRuleSet ACLRuleSet) error {
+func (p *Provider) SetFilesystemACL(path string, aclRuleSet ACLRuleSet) (err error) {
+    p, end := p.startSpan("SetFilesystemACL", attrPath.String(path))
+    defer end(&err)
+
     if path == "" {
         return fmt.Errorf("Filesystem path is required")
     }
@@ -654,6 +820,40 @@ func (p *Provider) SetFilesystemACL(path string, aclRuleSet ACLRuleSet) error {
     return p.sendRequest(http.MethodPost, uri, data)
 }
 
+// GetFilesystemACL returns ACL entries of the filesystem in order of evaluation
+func (p *Provider) GetFilesystemACL(path string) (rules []ACLRule, err error) {
+    p, end := p.startSpan("GetFilesystemACL", attrPath.String(path))
+    defer end(&err)
+
+    if path == "" {
+        return nil, fmt.Errorf("Filesystem path is required")
+    }
+
+    uri := fmt.Sprintf("/storage/filesystems/%s/acl", url.PathEscape(path))
+
+    response := nefStorageFilesystemsACLResponse{}
+    err = p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
+    if err != nil {
+        return nil, err
+    }
+
+    return response.Data, nil
+}
+
+// DeleteFilesystemACLRule deletes ACL entry of the filesystem by its index, see ACLRule.Index
+func (p *Provider) DeleteFilesystemACLRule(path string, index int) (err error) {
+    p, end := p.startSpan("DeleteFilesystemACLRule", attrPath.String(path))
+    defer end(&err)
+
+    if path == "" {
+        return fmt.Errorf("Filesystem path is required")
+    }
+
+    uri := fmt.Sprintf("/storage/filesystems/%s/acl/%d", url.PathEscape(path), index)
+
+    return p.sendRequest(http.MethodDelete, uri, nil)
+}
+
 // CreateSnapshotParams - params to create snapshot
 type CreateSnapshotParams struct {
     // snapshot path w/o leading slash
@@ -661,7 +861,10 @@ type CreateSnapshotParams struct {
 }
 
 // CreateSnapshot creates snapshot by filesystem path
-func (p *Provider) CreateSnapshot(params CreateSnapshotParams) error {
+func (p *Provider) CreateSnapshot(params CreateSnapshotParams) (err error) {
+    p, end := p.startSpan("CreateSnapshot", attrPath.String(params.Path))
+    defer end(&err)
+
     if params.Path == "" {
         return fmt.Errorf("Parameter 'CreateSnapshotParams.Path' is required")
     }
@@ -672,6 +875,9 @@ func (p *Provider) CreateSnapshot(params CreateSnapshotParams) error {
 // GetSnapshot returns snapshot by its path
 // path - full path to snapshot w/o leading slash (e.g. "p/d/fs@s")
 func (p *Provider) GetSnapshot(path string) (snapshot Snapshot, err error) {
+    p, end := p.startSpan("GetSnapshot", attrPath.String(path))
+    defer end(&err)
+
     if path == "" {
         return snapshot, fmt.Errorf("Snapshot path is empty")
     }
@@ -687,28 +893,65 @@ func (p *Provider) GetSnapshot(path string) (snapshot Snapshot, err error) {
 }
 
 // GetSnapshots returns snapshots by volume path
-func (p *Provider) GetSnapshots(volumePath string, recursive bool) ([]Snapshot, error) {
+func (p *Provider) GetSnapshots(volumePath string, recursive bool) (snapshots []Snapshot, err error) {
+    p, end := p.startSpan("GetSnapshots", attrPath.String(volumePath))
+    defer end(&err)
+
+    return p.ListSnapshots(volumePath, ListOptions{Recursive: recursive})
+}
+
+// ListSnapshots returns snapshots of the volume or filesystem matching the options
+func (p *Provider) ListSnapshots(volumePath string, options ListOptions) (snapshots []Snapshot, err error) {
+    p, end := p.startSpan("ListSnapshots", attrPath.String(volumePath))
+    defer end(&err)
+
     if volumePath == "" {
         return []Snapshot{}, fmt.Errorf("Snapshots volume path is empty")
     }
 
-    uri := p.RestClient.BuildURI("/storage/snapshots", map[string]string{
-        "parent":    volumePath,
-        "fields":    "path,name,parent,creationTime",
-        "recursive": strconv.FormatBool(recursive),
-    })
+    snapshots = []Snapshot{}
 
-    response := nefStorageSnapshotsResponse{}
-    err := p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
-    if err != nil {
-        return []Snapshot{}, err
+    it := p.GetSnapshotIterator(volumePath, options.Recursive, IteratorParams{ListOptions: options})
+    for !it.Done() {
+        snapshotsPage, err := it.Next(p.context())
+        if err != nil {
+            return []Snapshot{}, err
+        }
+        snapshots = append(snapshots, snapshotsPage...)
     }
 
-    return response.Data, nil
+    return snapshots, nil
+}
+
+// GetSnapshotIterator returns iterator over snapshots of the volume or filesystem
+func (p *Provider) GetSnapshotIterator(volumePath string, recursive bool, params IteratorParams) *SnapshotIterator {
+    return NewSnapshotIterator(params, func(limit, offset int) ([]Snapshot, error) {
+        query, err := params.listQueryParams("path,name,parent,creationTime")
+        if err != nil {
+            return nil, err
+        }
+        query["parent"] = volumePath
+        query["recursive"] = strconv.FormatBool(recursive || params.Recursive)
+        query["limit"] = fmt.Sprint(limit)
+        query["offset"] = fmt.Sprint(offset)
+
+        uri := p.RestClient.BuildURI("/storage/snapshots", query)
+
+        response := nefStorageSnapshotsResponse{}
+        err = p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
+        if err != nil {
+            return nil, err
+        }
+
+        return response.Data, nil
+    })
 }
 
 // DestroySnapshot destroys snapshot by path
-func (p *Provider) DestroySnapshot(path string) error {
+func (p *Provider) DestroySnapshot(path string) (err error) {
+    p, end := p.startSpan("DestroySnapshot", attrPath.String(path))
+    defer end(&err)
+
     if path == "" {
         return fmt.Errorf("Snapshot path is required")
     }
@@ -726,7 +969,10 @@ type CloneSnapshotParams struct {
 }
 
 // CloneSnapshot clones snapshot to FS
-func (p *Provider) CloneSnapshot(path string, params CloneSnapshotParams) error {
+func (p *Provider) CloneSnapshot(path string, params CloneSnapshotParams) (err error) {
+    p, end := p.startSpan("CloneSnapshot", attrPath.String(path))
+    defer end(&err)
+
     if path == "" {
         return fmt.Errorf("Snapshot path is required")
     }
@@ -741,13 +987,16 @@ func (p *Provider) CloneSnapshot(path string, params CloneSnapshotParams) error
 }
 
 // GetRSFClusters returns RSF clusters from NS
-func (p *Provider) GetRSFClusters() ([]RSFCluster, error) {
+func (p *Provider) GetRSFClusters() (clusters []RSFCluster, err error) {
+    p, end := p.startSpan("GetRSFClusters")
+    defer end(&err)
+
     uri := p.RestClient.BuildURI("/rsf/clusters", map[string]string{
         "fields": "clusterName,nodes",
     })
 
     response := nefRsfClustersResponse{}
-    err := p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
+    err = p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
     if err != nil {
         return nil, err
     }
@@ -756,10 +1005,13 @@ func (p *Provider) GetRSFClusters() ([]RSFCluster, error) {
 }
 
 // IsJobDone checks if job is done by jobId
-func (p *Provider) IsJobDone(jobID string) (bool, error) {
+func (p *Provider) IsJobDone(jobID string) (done bool, err error) {
+    p, end := p.startSpan("IsJobDone", attrJobID.String(jobID))
+    defer end(&err)
+
     uri := fmt.Sprintf("/jobStatus/%s", jobID)
 
-    statusCode, bodyBytes, err := p.RestClient.Send(http.MethodGet, uri, nil)
+    statusCode, bodyBytes, err := p.send(http.MethodGet, uri, nil)
     if err != nil { // request failed
         return false, err
     } else if statusCode == http.StatusOK || statusCode == http.StatusCreated { // job is completed
@@ -785,29 +1037,36 @@ func (p *Provider) IsJobDone(jobID string) (bool, error) {
 
 // GetVolume - returns NexentaStor volume properties
 func (p *Provider) GetVolume(path string) (volume Volume, err error) {
+    p, end := p.startSpan("GetVolume", attrPath.String(path))
+    defer end(&err)
+
     if path == "" {
         return volume, fmt.Errorf("Volume path is empty")
     }
 
     uri := p.RestClient.BuildURI("/storage/volumes", map[string]string{
         "path":   path,
+        "fields": nsVolumeFields,
     })
 
     response := nefStorageVolumesResponse{}
     err = p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
     if err != nil {
-        return response.Data[0], err
+        return volume, err
     }
 
     if len(response.Data) == 0 {
-        return volume, &NefError{Code: "ENOENT", Err: fmt.Errorf("VolumeGroup '%s' not found", path)}
+        return volume, &NefError{Code: "ENOENT", Err: fmt.Errorf("Volume '%s' not found", path)}
     }
 
     return response.Data[0], nil
 }
 
 // GetVolumeGroup returns NexentaStor volumeGroup by its path
-func (p *Provider) GetVolumeGroup(path string) (volumeGroup VolumeGroup,err error) {
+func (p *Provider) GetVolumeGroup(path string) (volumeGroup VolumeGroup, err error) {
+    p, end := p.startSpan("GetVolumeGroup", attrPath.String(path))
+    defer end(&err)
+
     if path == "" {
         return volumeGroup, fmt.Errorf("VolumeGroup path is empty")
     }
@@ -829,19 +1088,78 @@ func (p *Provider) GetVolumeGroup(path string) (volumeGroup VolumeGroup,err erro
     return response.Data[0], nil
 }
 
+// GetVolumeGroups returns all volumeGroups of the pool, including ones nested in filesystems
+func (p *Provider) GetVolumeGroups(pool string) (volumeGroups []VolumeGroup, err error) {
+    p, end := p.startSpan("GetVolumeGroups", attrPath.String(pool))
+    defer end(&err)
+
+    if pool == "" {
+        return nil, fmt.Errorf("Pool name is required")
+    }
+
+    limit := nsFilesystemListLimit
+    volumeGroups = []VolumeGroup{}
+    for offset := 0; ; offset += limit {
+        uri := p.RestClient.BuildURI("/storage/volumeGroups", map[string]string{
+            "pool":   pool,
+            "limit":  fmt.Sprint(limit),
+            "offset": fmt.Sprint(offset),
+        })
+
+        response := nefStorageVolumeGroupsResponse{}
+        err = p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
+        if err != nil {
+            return nil, err
+        }
+
+        volumeGroups = append(volumeGroups, response.Data...)
+        if len(response.Data) < limit {
+            return volumeGroups, nil
+        }
+    }
+}
+
 // CreateVolumeParams - params to create a volume
 type CreateVolumeParams struct {
     // volume path w/o leading slash
     Path        string `json:"path"`
     VolumeSize  int64  `json:"volumeSize"`
+    // volume block size in bytes, power of two between MinVolumeBlockSize and MaxVolumeBlockSize,
+    // cannot be changed after creation
+    VolumeBlockSize int64 `json:"volumeBlockSize,omitempty"`
+    // thin provisioned volume, no space is reserved for it on creation
+    SparseVolume bool `json:"sparseVolume,omitempty"`
+    // "on", "off", "lz4", "gzip", "gzip-[1-9]", "zle" or "lzjb"
+    CompressionMode string `json:"compressionMode,omitempty"`
+    // "standard", "always" or "disabled"
+    SyncMode string `json:"syncMode,omitempty"`
+    // "latency" or "throughput"
+    LogBias string `json:"logBias,omitempty"`
+    // space in bytes reserved for the volume, cannot be used with SparseVolume
+    ReservationSize int64 `json:"reservationSize,omitempty"`
 }
 
 // CreateVolume creates volume by path and size
-func (p *Provider) CreateVolume(params CreateVolumeParams) error {
+func (p *Provider) CreateVolume(params CreateVolumeParams) (err error) {
+    p, end := p.startSpan("CreateVolume", attrPath.String(params.Path))
+    defer end(&err)
+
     if params.Path == "" {
         return fmt.Errorf(
             "Parameters 'Volume.Path' is required, received %+v", params)
     }
+    if params.VolumeBlockSize != 0 {
+        if err := ValidateVolumeBlockSize(params.VolumeBlockSize); err != nil {
+            return err
+        }
+    }
+    if params.SparseVolume && params.ReservationSize != 0 {
+        return fmt.Errorf(
+            "Parameters 'SparseVolume' and 'ReservationSize' cannot be used together, received %+v", params)
+    }
+    if err := validateVolumeModes(params.CompressionMode, params.SyncMode, params.LogBias); err != nil {
+        return err
+    }
 
     return p.sendRequest(http.MethodPost, "/storage/volumes", params)
 }
@@ -850,37 +1168,238 @@ func (p *Provider) CreateVolume(params CreateVolumeParams) error {
 type UpdateVolumeParams struct {
     // volume referenced quota size in bytes
     VolumeSize int64 `json:"volumeSize,omitempty"`
+    // see CreateVolumeParams for allowed values
+    CompressionMode string `json:"compressionMode,omitempty"`
+    SyncMode        string `json:"syncMode,omitempty"`
+    LogBias         string `json:"logBias,omitempty"`
+    // reservation size in bytes, not changed if not set, set to 0 to remove the reservation
+    ReservationSize *int64 `json:"reservationSize,omitempty"`
 }
 
 // UpdateVolume updates volume by path
-func (p *Provider) UpdateVolume(path string, params UpdateVolumeParams) error {
+func (p *Provider) UpdateVolume(path string, params UpdateVolumeParams) (err error) {
+    p, end := p.startSpan("UpdateVolume", attrPath.String(path))
+    defer end(&err)
+
     if path == "" {
         return fmt.Errorf("Parameter 'path' is required")
     }
+    if err := validateVolumeModes(params.CompressionMode, params.SyncMode, params.LogBias); err != nil {
+        return err
+    }
 
     uri :=  fmt.Sprintf("/storage/volumes/%s", url.PathEscape(path))
     return p.sendRequest(http.MethodPut, uri, params)
 }
 
+// ResizeVolumeParams - params to resize volume
+type ResizeVolumeParams struct {
+    // If set to `true`, allows to shrink the volume.
+    // Shrinking destroys data stored beyond the new size, it's never safe for a mapped LUN.
+    Force bool
+}
+
+// ResizeVolumeResult - outcome of volume resize
+type ResizeVolumeResult struct {
+    // volume size in bytes before the resize
+    PreviousSize int64 `json:"previousSize"`
+    // new volume size in bytes, rounded up to the volume block size
+    VolumeSize int64 `json:"volumeSize"`
+    // volume LUN mappings, if any the initiators should rescan the LUN to see the new size
+    LunMappings []LunMapping `json:"lunMappings"`
+}
+
+// IsLunMapped returns true if the resized volume is mapped as a LUN
+func (r ResizeVolumeResult) IsLunMapped() bool {
+    return len(r.LunMappings) > 0
+}
+
+// ResizeVolume safely changes volume size: the size is rounded up to the volume block size,
+// shrinking is rejected unless forced, growing is rejected if the pool has no space for the reservation
+func (p *Provider) ResizeVolume(path string, newSize int64, params ResizeVolumeParams) (
+    result ResizeVolumeResult,
+    err error,
+) {
+    p, end := p.startSpan("ResizeVolume", attrPath.String(path))
+    defer end(&err)
+
+    if path == "" {
+        return result, fmt.Errorf("Volume path is required")
+    } else if newSize <= 0 {
+        return result, fmt.Errorf("Volume size must be greater than 0, got: %d", newSize)
+    }
+
+    volume, err := p.GetVolume(path)
+    if err != nil {
+        return result, err
+    }
+
+    result.PreviousSize = volume.VolumeSize
+    result.VolumeSize = newSize
+    if volume.VolumeBlockSize > 0 && newSize%volume.VolumeBlockSize != 0 {
+        result.VolumeSize = (newSize/volume.VolumeBlockSize + 1) * volume.VolumeBlockSize
+    }
+
+    result.LunMappings, err = p.ListLunMappings(ListLunMappingsParams{Volume: path})
+    if err != nil {
+        return result, err
+    }
+
+    if result.VolumeSize == volume.VolumeSize {
+        return result, nil
+    } else if result.VolumeSize < volume.VolumeSize && !params.Force {
+        return result, &NefError{
+            Code: "EBADARG",
+            Err: fmt.Errorf(
+                "Volume '%s' cannot be shrunk from %d to %d bytes without 'Force' parameter (LUN mapped: %t)",
+                path,
+                volume.VolumeSize,
+                result.VolumeSize,
+                result.IsLunMapped(),
+            ),
+        }
+    }
+
+    // thick provisioned volume reserves its full size, extra reservation must fit into the pool
+    if volume.ReservationSize > 0 && result.VolumeSize > volume.ReservationSize {
+        pool := strings.Split(path, "/")[0]
+        poolAvailable, err := p.GetFilesystemAvailableCapacity(pool)
+        if err != nil {
+            return result, err
+        }
+
+        requiredSize := result.VolumeSize - volume.ReservationSize
+        if requiredSize > poolAvailable {
+            return result, &NefError{
+                Code: "ENOSPC",
+                Err: fmt.Errorf(
+                    "Cannot resize volume '%s' to %d bytes: %d bytes must be reserved, but pool '%s' has %d available",
+                    path,
+                    result.VolumeSize,
+                    requiredSize,
+                    pool,
+                    poolAvailable,
+                ),
+            }
+        }
+    }
+
+    err = p.UpdateVolume(path, UpdateVolumeParams{VolumeSize: result.VolumeSize})
+    return result, err
+}
+
+// ValidateVolumeBlockSize checks that volume block size is a power of two in the range allowed by ZFS
+func ValidateVolumeBlockSize(size int64) error {
+    if size < MinVolumeBlockSize || size > MaxVolumeBlockSize || size&(size-1) != 0 {
+        return fmt.Errorf(
+            "Volume block size must be a power of two between %d and %d, got: %d",
+            MinVolumeBlockSize,
+            MaxVolumeBlockSize,
+            size,
+        )
+    }
+    return nil
+}
+
+func validateVolumeModes(compressionMode, syncMode, logBias string) error {
+    if compressionMode != "" && !volumeCompressionModeRegexp.MatchString(compressionMode) {
+        return fmt.Errorf("Unknown volume compression mode: '%s'", compressionMode)
+    }
+
+    switch syncMode {
+    case "", "standard", "always", "disabled":
+    default:
+        return fmt.Errorf("Unknown volume sync mode: '%s'", syncMode)
+    }
+
+    switch logBias {
+    case "", "latency", "throughput":
+    default:
+        return fmt.Errorf("Unknown volume log bias: '%s'", logBias)
+    }
+
+    return nil
+}
+
 // GetLunMapping returns NexentaStor lunmapping for a volume
+// If the volume is mapped to several host groups, the first mapping is returned (see ListLunMappings)
 func (p *Provider) GetLunMapping(path string) (lunMapping LunMapping, err error) {
+    p, end := p.startSpan("GetLunMapping", attrPath.String(path))
+    defer end(&err)
+
     if path == "" {
-            return lunMapping, fmt.Errorf("Volume path is empty")
+        return lunMapping, fmt.Errorf("Volume path is empty")
+    }
+
+    lunMappings, err := p.ListLunMappings(ListLunMappingsParams{Volume: path})
+    if err != nil {
+        return lunMapping, err
+    }
+    if len(lunMappings) == 0 {
+        return lunMapping, &NefError{Code: "ENOENT", Err: fmt.Errorf("lunMapping '%s' not found", path)}
     }
+
+    return lunMappings[0], nil
+}
+
+// ListLunMappingsParams - filters for LUN mappings list, empty values are ignored
+type ListLunMappingsParams struct {
+    // volume path w/o leading slash
+    Volume      string
+    HostGroup   string
+    TargetGroup string
+}
+
+// ListLunMappings returns NexentaStor lunmappings matching all specified filters
+func (p *Provider) ListLunMappings(params ListLunMappingsParams) (lunMappings []LunMapping, err error) {
+    p, end := p.startSpan("ListLunMappings", attrPath.String(params.Volume))
+    defer end(&err)
+
     uri := p.RestClient.BuildURI("/san/lunMappings", map[string]string{
-        "volume": path,
-        "fields": "id,volume,targetGroup,hostGroup,lun",
+        "volume":      params.Volume,
+        "hostGroup":   params.HostGroup,
+        "targetGroup": params.TargetGroup,
+        "fields":      "id,volume,targetGroup,hostGroup,lun",
     })
+
     response := nefLunMappingsResponse{}
     err = p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
     if err != nil {
-        return lunMapping, err
+        return nil, err
     }
-   if len(response.Data) == 0 {
-        return lunMapping, &NefError{Code: "ENOENT", Err: fmt.Errorf("lunMapping '%s' not found", path)}
+
+    return response.Data, nil
+}
+
+// GetNextFreeLun returns the lowest LUN number which is not used by any mapping of the host group
+func (p *Provider) GetNextFreeLun(hostGroup string) (lun int, err error) {
+    p, end := p.startSpan("GetNextFreeLun")
+    defer end(&err)
+
+    if hostGroup == "" {
+        return 0, fmt.Errorf("Host group is required")
     }
 
-    return response.Data[0], nil
+    lunMappings, err := p.ListLunMappings(ListLunMappingsParams{HostGroup: hostGroup})
+    if err != nil {
+        return 0, err
+    }
+
+    usedLuns := make(map[int]bool, len(lunMappings))
+    for _, lunMapping := range lunMappings {
+        usedLuns[lunMapping.Lun] = true
+    }
+
+    for lun := 0; lun <= MaxLun; lun++ {
+        if !usedLuns[lun] {
+            return lun, nil
+        }
+    }
+
+    return 0, &NefError{
+        Code: "ENOSPC",
+        Err:  fmt.Errorf("No free LUN number left in host group '%s' (max: %d)", hostGroup, MaxLun),
+    }
 }
 
 // CreateISCSITargetParamas - params to create new iSCSI target
@@ -890,11 +1409,14 @@ type CreateISCSITargetParams struct {
 }
 
 // CreateISCSITarget - create new iSCSI target on NexentaStor
-func (p *Provider) CreateISCSITarget (params CreateISCSITargetParams) error {
+func (p *Provider) CreateISCSITarget(params CreateISCSITargetParams) (err error) {
+    p, end := p.startSpan("CreateISCSITarget")
+    defer end(&err)
+
     if params.Name == "" {
         return fmt.Errorf("Parameters 'Name' and 'Portal' are required, received: %+v", params)
     }
-    err := p.sendRequest(http.MethodPost, "/san/iscsi/targets", params)
+    err = p.sendRequest(http.MethodPost, "/san/iscsi/targets", params)
     if !IsAlreadyExistNefError(err) {
         return err
     }
@@ -913,12 +1435,15 @@ type UpdateTargetGroupParams struct {
 }
 
 // CreateUpdateTargetGroup - create new target group on NexentaStor
-func (p *Provider) CreateUpdateTargetGroup(params CreateTargetGroupParams) error {
+func (p *Provider) CreateUpdateTargetGroup(params CreateTargetGroupParams) (err error) {
+    p, end := p.startSpan("CreateUpdateTargetGroup")
+    defer end(&err)
+
     if params.Name == "" || len(params.Members) == 0 {
         return fmt.Errorf(
             "Parameters 'Name' and 'Members' are required, received: %+v", params)
     }
-    err := p.sendRequest(http.MethodPost, "/san/targetgroups", params)
+    err = p.sendRequest(http.MethodPost, "/san/targetgroups", params)
     if err != nil {
         if !IsAlreadyExistNefError(err) {
             return err
@@ -935,32 +1460,112 @@ func (p *Provider) CreateUpdateTargetGroup(params CreateTargetGroupParams) error
     return nil
 }
 
+// CreateHostGroupParams - params to create host group
+type CreateHostGroupParams struct {
+    Name       string    `json:"name"`
+    // initiator names: iSCSI IQNs or FC WWPNs (see NormalizeWWPN)
+    Members    []string  `json:"members"`
+}
+
+// UpdateHostGroupParams - params to update existing host group
+type UpdateHostGroupParams struct {
+    Members     []string    `json:"members"`
+}
+
+// CreateUpdateHostGroup - create new host group on NexentaStor or update members of existing one
+func (p *Provider) CreateUpdateHostGroup(params CreateHostGroupParams) (err error) {
+    p, end := p.startSpan("CreateUpdateHostGroup")
+    defer end(&err)
+
+    if params.Name == "" || len(params.Members) == 0 {
+        return fmt.Errorf(
+            "Parameters 'Name' and 'Members' are required, received: %+v", params)
+    }
+    err = p.sendRequest(http.MethodPost, "/san/hostgroups", params)
+    if err != nil {
+        if !IsAlreadyExistNefError(err) {
+            return err
+        }
+        uri :=  fmt.Sprintf("/san/hostgroups/%s", url.PathEscape(params.Name))
+        return p.sendRequest(http.MethodPut, uri, UpdateHostGroupParams{
+            Members: params.Members,
+        })
+    }
+    return nil
+}
+
+// GetHostGroup returns NexentaStor host group by its name
+func (p *Provider) GetHostGroup(name string) (hostGroup HostGroup, err error) {
+    p, end := p.startSpan("GetHostGroup")
+    defer end(&err)
+
+    if name == "" {
+        return hostGroup, fmt.Errorf("Host group name is empty")
+    }
+
+    uri := p.RestClient.BuildURI("/san/hostgroups", map[string]string{
+        "name":   name,
+        "fields": "name,members",
+    })
+
+    response := nefHostGroupsResponse{}
+    err = p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
+    if err != nil {
+        return hostGroup, err
+    }
+
+    if len(response.Data) == 0 {
+        return hostGroup, &NefError{Code: "ENOENT", Err: fmt.Errorf("Host group '%s' not found", name)}
+    }
+
+    return response.Data[0], nil
+}
+
 // CreateLunMappingParams - params to create new lun
 type CreateLunMappingParams struct {
     HostGroup   string `json:"hostGroup"`
     Volume      string `json:"volume"`
     TargetGroup string `json:"targetGroup"`
+    // LUN number to use, NexentaStor allocates one if not set (see GetNextFreeLun)
+    Lun         *int   `json:"lun,omitempty"`
 }
 
 // CreateLunMapping - creates lun for given volume
-func (p *Provider) CreateLunMapping(params CreateLunMappingParams) error {
+// Existing mapping is not an error if the LUN number is allocated by NexentaStor,
+// but it is if params.Lun is set: the LUN number might collide with another volume's one.
+func (p *Provider) CreateLunMapping(params CreateLunMappingParams) (err error) {
+    p, end := p.startSpan("CreateLunMapping", attrPath.String(params.Volume))
+    defer end(&err)
+
     if params.HostGroup == "" || params.Volume == "" || params.TargetGroup == "" {
         return fmt.Errorf(
             "Parameters 'HostGroup', 'Target' and 'TargetGroup' are required, received: %+v", params)
     }
-    err := p.sendRequest(http.MethodPost, "/san/lunMappings", params)
-    if !IsAlreadyExistNefError(err) {
+    if params.Lun != nil && (*params.Lun < 0 || *params.Lun > MaxLun) {
+        return fmt.Errorf("Parameter 'Lun' must be between 0 and %d, received: %d", MaxLun, *params.Lun)
+    }
+    err = p.sendRequest(http.MethodPost, "/san/lunMappings", params)
+    if params.Lun != nil || !IsAlreadyExistNefError(err) {
         return err
     }
     return nil
 }
 
+// DestroyVolumeParams - volume deletion parameters, same as DestroyFilesystemParams
 type DestroyVolumeParams struct {
+    // If set to `true`, then tries to destroy volume's snapshots as well.
     DestroySnapshots bool
+
+    // If set to `true`, then tries to find the most recent snapshot clone and if found one,
+    // that clone will be promoted to take over all the snapshots from the original volume,
+    // then the original volume will be destroyed (see DestroyFilesystemParams).
     PromoteMostRecentCloneIfExists bool
 }
 
-func (p *Provider) DestroyLunMapping(id string) error {
+func (p *Provider) DestroyLunMapping(id string) (err error) {
+    p, end := p.startSpan("DestroyLunMapping")
+    defer end(&err)
+
     if id == "" {
         return fmt.Errorf("LunMapping id is required")
     }
@@ -970,17 +1575,36 @@ func (p *Provider) DestroyLunMapping(id string) error {
     return p.sendRequest(http.MethodDelete, uri, nil)
 }
 
-func (p *Provider) DestroyVolume(path string, params DestroyVolumeParams) error {
-    err := p.destroyVolume(path, params.DestroySnapshots)
-    if err != nil {
+// DestroyVolume destroys volume on NS, may destroy snapshots and promote clones (see DestroyVolumeParams)
+// Path format: 'pool/volumeGroup/volume'
+func (p *Provider) DestroyVolume(path string, params DestroyVolumeParams) (err error) {
+    p, end := p.startSpan("DestroyVolume", attrPath.String(path))
+    defer end(&err)
+
+    err = p.destroyVolume(path, params.DestroySnapshots)
+    if err == nil {
+        return nil
+    } else if !params.PromoteMostRecentCloneIfExists || !IsAlreadyExistNefError(err) {
         return err
     }
-    return nil
+
+    err = p.promoteMostRecentCloneAndDestroy(
+        path,
+        p.PromoteVolume,
+        func() error { return p.destroyVolume(path, params.DestroySnapshots) },
+    )
+
+    // if not a NefError, wrap it into an explanation
+    if err != nil && !IsNefError(err) {
+        return fmt.Errorf("Failed to delete volume '%s': %s", path, err)
+    }
+
+    return err
 }
 
 func (p *Provider) destroyVolume(path string, destroySnapshots bool) error {
     if path == "" {
-        return fmt.Errorf("Filesystem path is required")
+        return fmt.Errorf("Volume path is required")
     }
 
     uri := p.RestClient.BuildURI(
@@ -992,3 +1616,154 @@ func (p *Provider) destroyVolume(path string, destroySnapshots bool) error {
 
     return p.sendRequest(http.MethodDelete, uri, nil)
 }
+
+// PromoteVolume promotes a cloned volume to be no longer dependent on its original snapshot
+func (p *Provider) PromoteVolume(path string) (err error) {
+    p, end := p.startSpan("PromoteVolume", attrPath.String(path))
+    defer end(&err)
+
+    if path == "" {
+        return fmt.Errorf("Volume path is required")
+    }
+
+    uri := fmt.Sprintf("/storage/volumes/%s/promote", url.PathEscape(path))
+
+    return p.sendRequest(http.MethodPost, uri, nil)
+}
+
+// GetVolumeSnapshots returns snapshots of the volume
+func (p *Provider) GetVolumeSnapshots(path string) (snapshots []Snapshot, err error) {
+    p, end := p.startSpan("GetVolumeSnapshots", attrPath.String(path))
+    defer end(&err)
+
+    if path == "" {
+        return []Snapshot{}, fmt.Errorf("Volume path is empty")
+    }
+
+    return p.GetSnapshots(path, false)
+}
+
+// CloneVolumeSnapshotParams - params to clone snapshot to volume
+type CloneVolumeSnapshotParams struct {
+    // volume path w/o leading slash
+    TargetPath string `json:"targetPath"`
+    // new volume size in bytes, clone keeps the size of the snapshot if not set,
+    // cannot be smaller than the snapshot size, the clone is destroyed if it cannot be resized
+    VolumeSize int64 `json:"-"`
+}
+
+// CloneVolumeSnapshot clones volume snapshot to a new volume and resizes it if a new size is set
+func (p *Provider) CloneVolumeSnapshot(path string, params CloneVolumeSnapshotParams) (err error) {
+    p, end := p.startSpan("CloneVolumeSnapshot", attrPath.String(path))
+    defer end(&err)
+
+    if path == "" {
+        return fmt.Errorf("Snapshot path is required")
+    }
+
+    if params.TargetPath == "" {
+        return fmt.Errorf("Parameter 'CloneVolumeSnapshotParams.TargetPath' is required")
+    }
+
+    uri := fmt.Sprintf("/storage/snapshots/%s/clone", url.PathEscape(path))
+
+    err = p.sendRequest(http.MethodPost, uri, params)
+    if err != nil || params.VolumeSize == 0 {
+        return err
+    }
+
+    // clone has the size of the volume at the moment the snapshot was taken
+    clone, err := p.GetVolume(params.TargetPath)
+    if err != nil {
+        return fmt.Errorf(
+            "Snapshot '%s' is cloned to '%s', but the clone is left with the snapshot size: %s",
+            path,
+            params.TargetPath,
+            err,
+        )
+    } else if params.VolumeSize == clone.VolumeSize {
+        return nil
+    } else if params.VolumeSize < clone.VolumeSize {
+        err = fmt.Errorf("requested size %d is smaller than snapshot size %d", params.VolumeSize, clone.VolumeSize)
+    } else {
+        err = p.UpdateVolume(params.TargetPath, UpdateVolumeParams{VolumeSize: params.VolumeSize})
+    }
+
+    if err != nil {
+        return p.destroyFailedClone(path, params.TargetPath, err)
+    }
+
+    return nil
+}
+
+// destroyFailedClone destroys volume clone which cannot be resized, returns the resize error
+func (p *Provider) destroyFailedClone(path, clonePath string, resizeErr error) error {
+    if err := p.destroyVolume(clonePath, false); err != nil {
+        return fmt.Errorf(
+            "Cannot resize clone '%s' of snapshot '%s': %s, the clone is left behind, failed to destroy it: %s",
+            clonePath,
+            path,
+            resizeErr,
+            err,
+        )
+    }
+
+    return fmt.Errorf(
+        "Cannot resize clone '%s' of snapshot '%s': %s, the clone is destroyed",
+        clonePath,
+        path,
+        resizeErr,
+    )
+}
+
+// CreateVolumeGroupParams - params to create volumeGroup
+type CreateVolumeGroupParams struct {
+    // volumeGroup path w/o leading slash
+    Path string `json:"path"`
+    // default block size in bytes for volumes created in the group (see ValidateVolumeBlockSize)
+    VolumeBlockSize int64 `json:"volumeBlockSize,omitempty"`
+}
+
+// CreateVolumeGroup creates volumeGroup by path
+func (p *Provider) CreateVolumeGroup(params CreateVolumeGroupParams) (err error) {
+    p, end := p.startSpan("CreateVolumeGroup", attrPath.String(params.Path))
+    defer end(&err)
+
+    if params.Path == "" {
+        return fmt.Errorf("Parameter 'CreateVolumeGroupParams.Path' is required")
+    }
+    if params.VolumeBlockSize != 0 {
+        if err := ValidateVolumeBlockSize(params.VolumeBlockSize); err != nil {
+            return err
+        }
+    }
+
+    return p.sendRequest(http.MethodPost, "/storage/volumeGroups", params)
+}
+
+// DestroyVolumeGroupParams - volumeGroup deletion parameters
+type DestroyVolumeGroupParams struct {
+    // If set to `true`, then volumeGroup's volumes and snapshots will be destroyed as well,
+    // otherwise deletion of non empty volumeGroup fails
+    Recursive bool
+}
+
+// DestroyVolumeGroup destroys volumeGroup by path
+func (p *Provider) DestroyVolumeGroup(path string, params DestroyVolumeGroupParams) (err error) {
+    p, end := p.startSpan("DestroyVolumeGroup", attrPath.String(path))
+    defer end(&err)
+
+    if path == "" {
+        return fmt.Errorf("VolumeGroup path is required")
+    }
+
+    uri := p.RestClient.BuildURI(
+        fmt.Sprintf("/storage/volumeGroups/%s", url.PathEscape(path)),
+        map[string]string{
+            "force":     strconv.FormatBool(params.Recursive),
+            "snapshots": strconv.FormatBool(params.Recursive),
+        },
+    )
+
+    return p.sendRequest(http.MethodDelete, uri, nil)
+}
@@ -0,0 +1,303 @@
+package ns
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Nexenta/go-nexentastor/pkg/metrics"
+)
+
+// default count of concurrent requests of bulk operations
+const defaultBatchConcurrency = 10
+
+// BatchResult - result of one item of a bulk operation
+type BatchResult struct {
+	// index of the item in the bulk operation params
+	Index int
+	// path of the dataset or share the item is about
+	Path string
+	Err  error
+}
+
+// Batch - bulk operations with bounded concurrency
+// If Provider is *Provider, async jobs started by the items are awaited by one shared poller,
+// so the concurrency limit applies to requests only, not to the jobs waiting time.
+type Batch struct {
+	Provider ProviderInterface
+
+	// maximum count of concurrent requests, defaultBatchConcurrency if not set
+	Concurrency int
+}
+
+// NewBatch creates bulk operations helper for the provider
+func NewBatch(provider ProviderInterface, concurrency int) *Batch {
+	return &Batch{
+		Provider:    provider,
+		Concurrency: concurrency,
+	}
+}
+
+// CreateFilesystems creates filesystems, see Provider.CreateFilesystem()
+func (b *Batch) CreateFilesystems(params []CreateFilesystemParams) []BatchResult {
+	return b.run(
+		len(params),
+		true,
+		func(i int) string { return params[i].Path },
+		func(p ProviderInterface, i int) error { return p.CreateFilesystem(params[i]) },
+	)
+}
+
+// DestroyFilesystems destroys filesystems, see Provider.DestroyFilesystem()
+func (b *Batch) DestroyFilesystems(paths []string, params DestroyFilesystemParams) []BatchResult {
+	// clone promotion depends on deletion errors, so each deletion waits for its own job
+	collectJobs := !params.PromoteMostRecentCloneIfExists
+	return b.run(
+		len(paths),
+		collectJobs,
+		func(i int) string { return paths[i] },
+		func(p ProviderInterface, i int) error { return p.DestroyFilesystem(paths[i], params) },
+	)
+}
+
+// CreateSnapshots creates snapshots, see Provider.CreateSnapshot()
+func (b *Batch) CreateSnapshots(params []CreateSnapshotParams) []BatchResult {
+	return b.run(
+		len(params),
+		true,
+		func(i int) string { return params[i].Path },
+		func(p ProviderInterface, i int) error { return p.CreateSnapshot(params[i]) },
+	)
+}
+
+// DestroySnapshots destroys snapshots, see Provider.DestroySnapshot()
+func (b *Batch) DestroySnapshots(paths []string) []BatchResult {
+	return b.run(
+		len(paths),
+		true,
+		func(i int) string { return paths[i] },
+		func(p ProviderInterface, i int) error { return p.DestroySnapshot(paths[i]) },
+	)
+}
+
+// CreateNfsShares creates NFS shares, see Provider.CreateNfsShare()
+func (b *Batch) CreateNfsShares(params []CreateNfsShareParams) []BatchResult {
+	return b.run(
+		len(params),
+		true,
+		func(i int) string { return params[i].Filesystem },
+		func(p ProviderInterface, i int) error { return p.CreateNfsShare(params[i]) },
+	)
+}
+
+// DeleteNfsShares destroys NFS shares by filesystem paths, see Provider.DeleteNfsShare()
+func (b *Batch) DeleteNfsShares(paths []string) []BatchResult {
+	return b.run(
+		len(paths),
+		true,
+		func(i int) string { return paths[i] },
+		func(p ProviderInterface, i int) error { return p.DeleteNfsShare(paths[i]) },
+	)
+}
+
+// CreateSmbShares creates SMB shares, see Provider.CreateSmbShare()
+func (b *Batch) CreateSmbShares(params []CreateSmbShareParams) []BatchResult {
+	return b.run(
+		len(params),
+		true,
+		func(i int) string { return params[i].Filesystem },
+		func(p ProviderInterface, i int) error { return p.CreateSmbShare(params[i]) },
+	)
+}
+
+// CreateVolumes creates volumes, see Provider.CreateVolume()
+func (b *Batch) CreateVolumes(params []CreateVolumeParams) []BatchResult {
+	return b.run(
+		len(params),
+		true,
+		func(i int) string { return params[i].Path },
+		func(p ProviderInterface, i int) error { return p.CreateVolume(params[i]) },
+	)
+}
+
+// DestroyVolumes destroys volumes, see Provider.DestroyVolume()
+func (b *Batch) DestroyVolumes(paths []string, params DestroyVolumeParams) []BatchResult {
+	collectJobs := !params.PromoteMostRecentCloneIfExists
+	return b.run(
+		len(paths),
+		collectJobs,
+		func(i int) string { return paths[i] },
+		func(p ProviderInterface, i int) error { return p.DestroyVolume(paths[i], params) },
+	)
+}
+
+// run calls operation for each item, at most Concurrency operations run at the same time
+func (b *Batch) run(
+	count int,
+	collectJobs bool,
+	getPath func(i int) string,
+	operation func(p ProviderInterface, i int) error,
+) []BatchResult {
+	results := make([]BatchResult, count)
+
+	concurrency := b.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+
+	provider, isProvider := b.Provider.(*Provider)
+	var waiter *batchJobWaiter
+	if collectJobs && isProvider {
+		waiter = newBatchJobWaiter(provider)
+		defer waiter.stop()
+	}
+
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		results[i] = BatchResult{Index: i, Path: getPath(i)}
+
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+
+			if waiter == nil {
+				results[i].Err = operation(b.Provider, i)
+				<-slots
+				return
+			}
+
+			// provider copy which collects async jobs instead of waiting for them
+			collector := &asyncJobCollector{}
+			itemProvider := *provider
+			itemProvider.jobCollector = collector
+
+			err := operation(&itemProvider, i)
+			<-slots
+			if err != nil {
+				results[i].Err = err
+				return
+			}
+
+			results[i].Err = waiter.wait(collector.jobIDs)
+		}(i)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// batchJobWaiter - polls status of async jobs of all batch items in one goroutine
+type batchJobWaiter struct {
+	provider *Provider
+
+	mux  sync.Mutex
+	jobs map[string]*batchJob
+
+	// signals new jobs, they are checked at once like waitForAsyncJob() does
+	added chan struct{}
+	done  chan struct{}
+}
+
+type batchJob struct {
+	startTime time.Time
+	result    chan error
+
+	// set after the first status check, accessed by the poll goroutine only
+	checked bool
+}
+
+func newBatchJobWaiter(provider *Provider) *batchJobWaiter {
+	w := &batchJobWaiter{
+		provider: provider,
+		jobs:     map[string]*batchJob{},
+		added:    make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	go w.poll()
+	return w
+}
+
+// wait blocks until all jobs are completed, returns the first error
+func (w *batchJobWaiter) wait(jobIDs []string) error {
+	jobs := make([]*batchJob, len(jobIDs))
+
+	w.mux.Lock()
+	for i, jobID := range jobIDs {
+		jobs[i] = &batchJob{
+			startTime: time.Now(),
+			result:    make(chan error, 1),
+		}
+		w.jobs[jobID] = jobs[i]
+	}
+	w.mux.Unlock()
+
+	select {
+	case w.added <- struct{}{}:
+	default: // poll goroutine is already notified
+	}
+
+	var firstErr error
+	for _, job := range jobs {
+		if err := <-job.result; err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (w *batchJobWaiter) stop() {
+	close(w.done)
+}
+
+func (w *batchJobWaiter) poll() {
+	l := w.provider.logger().WithField("func", "batchJobWaiter.poll()")
+
+	ticker := time.NewTicker(checkJobStatusInterval)
+	defer ticker.Stop()
+
+	for {
+		// new jobs are checked as soon as they are added, all jobs - each checkJobStatusInterval
+		newOnly := false
+		select {
+		case <-w.done:
+			return
+		case <-w.added:
+			newOnly = true
+		case <-ticker.C:
+		}
+
+		w.mux.Lock()
+		pending := make(map[string]*batchJob, len(w.jobs))
+		for jobID, job := range w.jobs {
+			if !newOnly || !job.checked {
+				pending[jobID] = job
+			}
+		}
+		w.mux.Unlock()
+
+		if len(pending) > 0 {
+			l.Debugf("checking %d jobs...", len(pending))
+		}
+
+		for jobID, job := range pending {
+			job.checked = true
+			jobDone, err := w.provider.IsJobDone(jobID)
+			outcome := metrics.JobSucceeded
+			if err != nil {
+				outcome = metrics.JobFailed
+			} else if !jobDone && time.Since(job.startTime) >= checkJobStatusTimeout {
+				err = fmt.Errorf("Checking job status timeout exceeded (%s)", checkJobStatusTimeout)
+				outcome = metrics.JobTimedOut
+			}
+			if err != nil || jobDone {
+				w.provider.observeJob(outcome, job.startTime)
+				w.mux.Lock()
+				delete(w.jobs, jobID)
+				w.mux.Unlock()
+				job.result <- err
+			}
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package ns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// environment variables used by EnvCredentialSource by default
+const (
+	defaultUsernameEnv = "NEXENTASTOR_USERNAME"
+	defaultPasswordEnv = "NEXENTASTOR_PASSWORD"
+)
+
+// default time limit for ExecCredentialSource command
+const defaultExecCredentialTimeout = 30 * time.Second
+
+// Credentials - NexentaStor API user credentials
+type Credentials struct {
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password" secret:"true"`
+}
+
+// CredentialSource - provides credentials to log in to NexentaStor
+// Credentials are requested on every login, so rotated credentials are used without provider re-creation.
+type CredentialSource interface {
+	GetCredentials() (Credentials, error)
+}
+
+// StaticCredentialSource - credentials which never change
+type StaticCredentialSource struct {
+	Credentials Credentials
+}
+
+// NewStaticCredentialSource creates credential source for fixed username and password
+func NewStaticCredentialSource(username, password string) *StaticCredentialSource {
+	return &StaticCredentialSource{
+		Credentials: Credentials{Username: username, Password: password},
+	}
+}
+
+// GetCredentials returns fixed credentials
+func (s *StaticCredentialSource) GetCredentials() (Credentials, error) {
+	return s.Credentials, nil
+}
+
+// FileCredentialSource - credentials from a YAML/JSON file with "username" and "password" fields,
+// or from a directory with "username" and "password" files (e.g. a mounted k8s secret).
+// The file is read again when its modification time changes.
+type FileCredentialSource struct {
+	path string
+
+	mux         sync.Mutex
+	modTime     time.Time
+	credentials Credentials
+}
+
+// NewFileCredentialSource creates credential source for a file or a directory
+func NewFileCredentialSource(path string) *FileCredentialSource {
+	return &FileCredentialSource{path: path}
+}
+
+// GetCredentials returns credentials from the file, the file is read only if it's been changed
+func (s *FileCredentialSource) GetCredentials() (Credentials, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("Cannot read credentials file '%s': %s", s.path, err)
+	}
+
+	files := []string{s.path}
+	if info.IsDir() {
+		files = []string{filepath.Join(s.path, "username"), filepath.Join(s.path, "password")}
+	}
+
+	// k8s secret files are symlinks replaced on update, so modification time of the targets is checked
+	var modTime time.Time
+	for _, file := range files {
+		fileInfo, err := os.Stat(file)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("Cannot read credentials file '%s': %s", file, err)
+		}
+		if fileInfo.ModTime().After(modTime) {
+			modTime = fileInfo.ModTime()
+		}
+	}
+
+	if !s.modTime.IsZero() && modTime.Equal(s.modTime) {
+		return s.credentials, nil
+	}
+
+	credentials := Credentials{}
+	if info.IsDir() {
+		values := make([]string, len(files))
+		for i, file := range files {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				return Credentials{}, fmt.Errorf("Cannot read credentials file '%s': %s", file, err)
+			}
+			values[i] = strings.TrimRight(string(data), "\r\n")
+		}
+		credentials = Credentials{Username: values[0], Password: values[1]}
+	} else {
+		data, err := ioutil.ReadFile(s.path)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("Cannot read credentials file '%s': %s", s.path, err)
+		}
+		if err := yaml.Unmarshal(data, &credentials); err != nil {
+			return Credentials{}, fmt.Errorf("Cannot parse credentials file '%s': %s", s.path, err)
+		}
+	}
+
+	if credentials.Username == "" || credentials.Password == "" {
+		return Credentials{}, fmt.Errorf("Credentials file '%s' must contain username and password", s.path)
+	}
+
+	s.modTime = modTime
+	s.credentials = credentials
+	return credentials, nil
+}
+
+// EnvCredentialSource - credentials from environment variables
+type EnvCredentialSource struct {
+	// environment variable names, $NEXENTASTOR_USERNAME and $NEXENTASTOR_PASSWORD if not set
+	UsernameEnv string
+	PasswordEnv string
+}
+
+// GetCredentials returns credentials from the environment variables
+func (s *EnvCredentialSource) GetCredentials() (Credentials, error) {
+	usernameEnv := s.UsernameEnv
+	if usernameEnv == "" {
+		usernameEnv = defaultUsernameEnv
+	}
+	passwordEnv := s.PasswordEnv
+	if passwordEnv == "" {
+		passwordEnv = defaultPasswordEnv
+	}
+
+	credentials := Credentials{
+		Username: os.Getenv(usernameEnv),
+		Password: os.Getenv(passwordEnv),
+	}
+	if credentials.Username == "" || credentials.Password == "" {
+		return Credentials{}, fmt.Errorf("Environment variables $%s and $%s must be set", usernameEnv, passwordEnv)
+	}
+
+	return credentials, nil
+}
+
+// ExecCredentialSource - credentials printed to stdout by an external command (e.g. a vault client) as JSON:
+//
+//	{"username": "admin", "password": "pass", "expiresAt": "2020-01-01T00:00:00Z"}
+//
+// The result is reused until "expiresAt", the command runs on every login if it's not set.
+type ExecCredentialSource struct {
+	Command string
+	Args    []string
+	// additional environment variables in "KEY=value" form, the current environment is inherited
+	Env []string
+	// command time limit, defaultExecCredentialTimeout if not set
+	Timeout time.Duration
+
+	mux         sync.Mutex
+	credentials Credentials
+	expiresAt   time.Time
+}
+
+// execCredentialOutput - expected output of ExecCredentialSource command
+type execCredentialOutput struct {
+	Credentials
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// NewExecCredentialSource creates credential source which runs the command
+func NewExecCredentialSource(command string, args ...string) *ExecCredentialSource {
+	return &ExecCredentialSource{
+		Command: command,
+		Args:    args,
+	}
+}
+
+// GetCredentials runs the command, unless previously returned credentials are not expired yet
+func (s *ExecCredentialSource) GetCredentials() (Credentials, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if time.Now().Before(s.expiresAt) {
+		return s.credentials, nil
+	}
+
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = defaultExecCredentialTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, s.Command, s.Args...)
+	cmd.Env = append(os.Environ(), s.Env...)
+	cmd.Stderr = os.Stderr
+
+	stdout, err := cmd.Output()
+	if err != nil {
+		return Credentials{}, fmt.Errorf("Credentials command '%s' failed: %s", s.Command, err)
+	}
+
+	output := execCredentialOutput{}
+	if err := json.Unmarshal(stdout, &output); err != nil {
+		return Credentials{}, fmt.Errorf("Cannot parse output of credentials command '%s': %s", s.Command, err)
+	} else if output.Username == "" || output.Password == "" {
+		return Credentials{}, fmt.Errorf("Credentials command '%s' must output username and password", s.Command)
+	}
+
+	s.credentials = output.Credentials
+	s.expiresAt = output.ExpiresAt
+	return s.credentials, nil
+}
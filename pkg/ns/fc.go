@@ -0,0 +1,133 @@
+package ns
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// wwpnPrefixes - known prefixes of WWPN notations, stripped before validation
+var wwpnPrefixes = []string{"wwn.", "naa.", "0x"}
+
+var wwpnHexRegexp = regexp.MustCompile("^[0-9A-F]{16}$")
+
+// NormalizeWWPN converts Fibre Channel WWPN to the format NexentaStor uses for SAN group members
+// Accepts "21:00:00:24:ff:4c:5a:10", "21-00-00-24-ff-4c-5a-10", "0x21000024ff4c5a10",
+// "naa.21000024ff4c5a10" and "wwn.21000024FF4C5A10", returns "wwn.21000024FF4C5A10"
+func NormalizeWWPN(wwpn string) (string, error) {
+	hex := strings.ToUpper(strings.TrimSpace(wwpn))
+	for _, prefix := range wwpnPrefixes {
+		if strings.HasPrefix(hex, strings.ToUpper(prefix)) {
+			hex = hex[len(prefix):]
+			break
+		}
+	}
+	hex = strings.NewReplacer(":", "", "-", "").Replace(hex)
+
+	if !wwpnHexRegexp.MatchString(hex) {
+		return "", fmt.Errorf("Invalid WWPN '%s': expected 16 hex digits", wwpn)
+	}
+
+	return fmt.Sprintf("wwn.%s", hex), nil
+}
+
+// FormatWWPN converts Fibre Channel WWPN to colon-separated lower case format: "21:00:00:24:ff:4c:5a:10"
+func FormatWWPN(wwpn string) (string, error) {
+	normalized, err := NormalizeWWPN(wwpn)
+	if err != nil {
+		return "", err
+	}
+
+	hex := strings.ToLower(strings.TrimPrefix(normalized, "wwn."))
+	octets := make([]string, 0, len(hex)/2)
+	for i := 0; i < len(hex); i += 2 {
+		octets = append(octets, hex[i:i+2])
+	}
+
+	return strings.Join(octets, ":"), nil
+}
+
+func normalizeWWPNs(wwpns []string) ([]string, error) {
+	normalized := make([]string, 0, len(wwpns))
+	for _, wwpn := range wwpns {
+		n, err := NormalizeWWPN(wwpn)
+		if err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, n)
+	}
+	return normalized, nil
+}
+
+// GetFCTargetPorts returns Fibre Channel ports of NexentaStor
+func (p *Provider) GetFCTargetPorts() (ports []FCPort, err error) {
+	p, end := p.startSpan("GetFCTargetPorts")
+	defer end(&err)
+
+	uri := p.RestClient.BuildURI("/san/fc/ports", map[string]string{
+		"fields": "wwpn,state,speed,mode",
+	})
+
+	response := nefFCPortsResponse{}
+	err = p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Data, nil
+}
+
+// SetFCPortMode switches Fibre Channel port to target or initiator mode
+// NexentaStor may require a reboot before the new mode takes effect
+func (p *Provider) SetFCPortMode(wwpn string, mode FCPortMode) (err error) {
+	p, end := p.startSpan("SetFCPortMode")
+	defer end(&err)
+
+	if mode != FCPortModeTarget && mode != FCPortModeInitiator {
+		return fmt.Errorf("Unknown FC port mode '%s', expected '%s' or '%s'", mode, FCPortModeTarget, FCPortModeInitiator)
+	}
+
+	normalized, err := NormalizeWWPN(wwpn)
+	if err != nil {
+		return err
+	}
+
+	uri := fmt.Sprintf("/san/fc/ports/%s", url.PathEscape(normalized))
+	data := struct {
+		Mode FCPortMode `json:"mode"`
+	}{mode}
+
+	return p.sendRequest(http.MethodPut, uri, data)
+}
+
+// CreateUpdateFCTargetGroup creates or updates target group of Fibre Channel target ports,
+// members are normalized WWPNs, so any format supported by NormalizeWWPN can be used
+func (p *Provider) CreateUpdateFCTargetGroup(params CreateTargetGroupParams) (err error) {
+	p, end := p.startSpan("CreateUpdateFCTargetGroup")
+	defer end(&err)
+
+	members, err := normalizeWWPNs(params.Members)
+	if err != nil {
+		return err
+	}
+	params.Members = members
+
+	return p.CreateUpdateTargetGroup(params)
+}
+
+// CreateUpdateFCHostGroup creates or updates host group of Fibre Channel initiator ports,
+// members are normalized WWPNs, so any format supported by NormalizeWWPN can be used
+func (p *Provider) CreateUpdateFCHostGroup(params CreateHostGroupParams) (err error) {
+	p, end := p.startSpan("CreateUpdateFCHostGroup")
+	defer end(&err)
+
+	members, err := normalizeWWPNs(params.Members)
+	if err != nil {
+		return err
+	}
+	params.Members = members
+
+	return p.CreateUpdateHostGroup(params)
+}
@@ -0,0 +1,343 @@
+package ns
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// IteratorParams - params to create list iterator
+type IteratorParams struct {
+	// count of items to request from NexentaStor at once, default and maximum is nsFilesystemListLimit
+	PageSize int
+
+	// token returned by Iterator.Token() to resume listing after the last returned item,
+	// a path of the last returned item is also accepted, but costs a full scan to find it
+	StartingToken string
+
+	// filtering, field selection and sorting, must be the same for all pages of one listing
+	ListOptions
+}
+
+// iteratorToken - continuation token content, encoded to opaque string
+type iteratorToken struct {
+	// NS offset of the next item to fetch
+	Offset int `json:"o"`
+	// path of the last returned item
+	LastPath string `json:"p"`
+}
+
+func (t iteratorToken) String() string {
+	data, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func parseIteratorToken(token string) (iteratorToken, bool) {
+	t := iteratorToken{}
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || json.Unmarshal(data, &t) != nil || t.Offset <= 0 || t.LastPath == "" {
+		return t, false
+	}
+	return t, true
+}
+
+// iteratorPageFunc loads up to limit items starting from NS offset, returns paths of all loaded items
+type iteratorPageFunc func(limit, offset int) ([]string, error)
+
+// Iterator - lazy paginated listing of NexentaStor collection, keeps the position between pages,
+// see FilesystemIterator, VolumeIterator and SnapshotIterator
+type Iterator struct {
+	pageSize int
+	token    iteratorToken
+	done     bool
+
+	// path to search for from the beginning of the list, set if listing position is unknown
+	seekPath string
+	// true if the next page must start with the last returned item to verify the position
+	verify bool
+}
+
+func newIterator(params IteratorParams) Iterator {
+	it := Iterator{pageSize: params.PageSize}
+	if it.pageSize <= 0 || it.pageSize > nsFilesystemListLimit {
+		it.pageSize = nsFilesystemListLimit
+	}
+
+	if params.StartingToken != "" {
+		if token, ok := parseIteratorToken(params.StartingToken); ok {
+			it.token = token
+			it.verify = true
+		} else {
+			// legacy token is a path of the last returned item
+			it.seekPath = params.StartingToken
+		}
+	}
+
+	return it
+}
+
+// Done returns true if all items have been returned
+func (it *Iterator) Done() bool {
+	return it.done
+}
+
+// Token returns opaque continuation token to resume listing after the last returned item,
+// returns empty string if all items have been returned
+func (it *Iterator) Token() string {
+	if it.done || it.token.LastPath == "" {
+		return ""
+	}
+	return it.token.String()
+}
+
+// next loads next page of up to limit items, returns index of the first item to return from the page
+func (it *Iterator) next(ctx context.Context, limit int, fetch iteratorPageFunc) (int, error) {
+	for !it.done {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		if it.verify {
+			// request the last returned item once again to make sure the list hasn't been shifted
+			verifyLimit := limit + 1
+			if verifyLimit > nsFilesystemListLimit {
+				verifyLimit = nsFilesystemListLimit
+			}
+			paths, err := fetch(verifyLimit, it.token.Offset-1)
+			if err != nil {
+				return 0, err
+			}
+			it.verify = false
+			if len(paths) > 0 && paths[0] == it.token.LastPath {
+				it.token.Offset--
+				it.advance(paths, verifyLimit)
+				return 1, nil
+			}
+			it.seekPath = it.token.LastPath
+			it.token = iteratorToken{}
+			continue
+		}
+
+		paths, err := fetch(limit, it.token.Offset)
+		if err != nil {
+			return 0, err
+		}
+		it.advance(paths, limit)
+
+		if it.seekPath == "" {
+			return 0, nil
+		}
+		for i, path := range paths {
+			if path == it.seekPath {
+				it.seekPath = ""
+				return i + 1, nil
+			}
+		}
+	}
+
+	// starting token not found, nothing to return
+	return 0, nil
+}
+
+func (it *Iterator) advance(paths []string, limit int) {
+	if len(paths) > 0 {
+		it.token.LastPath = paths[len(paths)-1]
+	}
+	it.token.Offset += len(paths)
+	it.done = len(paths) < limit
+}
+
+// FilesystemIterator - lazy paginated listing of filesystems
+type FilesystemIterator struct {
+	Iterator
+	parent string
+	fetch  func(limit, offset int) ([]Filesystem, error)
+}
+
+// NewFilesystemIterator creates filesystem iterator, fetch loads a page of filesystems by NS offset
+func NewFilesystemIterator(
+	parent string,
+	params IteratorParams,
+	fetch func(limit, offset int) ([]Filesystem, error),
+) *FilesystemIterator {
+	return &FilesystemIterator{
+		Iterator: newIterator(params),
+		parent:   parent,
+		fetch:    fetch,
+	}
+}
+
+// Next returns next page of filesystems, empty page is returned when iterator is done
+func (it *FilesystemIterator) Next(ctx context.Context) ([]Filesystem, error) {
+	return it.nextN(ctx, it.pageSize)
+}
+
+func (it *FilesystemIterator) nextN(ctx context.Context, limit int) ([]Filesystem, error) {
+	var page []Filesystem
+	from, err := it.next(ctx, limit, func(limit, offset int) ([]string, error) {
+		var err error
+		page, err = it.fetch(limit, offset)
+		paths := make([]string, len(page))
+		for i, fs := range page {
+			paths[i] = fs.Path
+		}
+		return paths, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	filesystems := []Filesystem{}
+	for _, fs := range page[from:] {
+		if fs.Path != it.parent { // exclude parent filesystem from the list
+			filesystems = append(filesystems, fs)
+		}
+	}
+
+	return filesystems, nil
+}
+
+// VolumeIterator - lazy paginated listing of volumes
+type VolumeIterator struct {
+	Iterator
+	fetch func(limit, offset int) ([]Volume, error)
+}
+
+// NewVolumeIterator creates volume iterator, fetch loads a page of volumes by NS offset
+func NewVolumeIterator(params IteratorParams, fetch func(limit, offset int) ([]Volume, error)) *VolumeIterator {
+	return &VolumeIterator{
+		Iterator: newIterator(params),
+		fetch:    fetch,
+	}
+}
+
+// Next returns next page of volumes, empty page is returned when iterator is done
+func (it *VolumeIterator) Next(ctx context.Context) ([]Volume, error) {
+	return it.nextN(ctx, it.pageSize)
+}
+
+func (it *VolumeIterator) nextN(ctx context.Context, limit int) ([]Volume, error) {
+	var page []Volume
+	from, err := it.next(ctx, limit, func(limit, offset int) ([]string, error) {
+		var err error
+		page, err = it.fetch(limit, offset)
+		paths := make([]string, len(page))
+		for i, volume := range page {
+			paths[i] = volume.Path
+		}
+		return paths, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]Volume{}, page[from:]...), nil
+}
+
+// SnapshotIterator - lazy paginated listing of snapshots
+type SnapshotIterator struct {
+	Iterator
+	fetch func(limit, offset int) ([]Snapshot, error)
+}
+
+// NewSnapshotIterator creates snapshot iterator, fetch loads a page of snapshots by NS offset
+func NewSnapshotIterator(params IteratorParams, fetch func(limit, offset int) ([]Snapshot, error)) *SnapshotIterator {
+	return &SnapshotIterator{
+		Iterator: newIterator(params),
+		fetch:    fetch,
+	}
+}
+
+// Next returns next page of snapshots, empty page is returned when iterator is done
+func (it *SnapshotIterator) Next(ctx context.Context) ([]Snapshot, error) {
+	var page []Snapshot
+	from, err := it.next(ctx, it.pageSize, func(limit, offset int) ([]string, error) {
+		var err error
+		page, err = it.fetch(limit, offset)
+		paths := make([]string, len(page))
+		for i, snapshot := range page {
+			paths[i] = snapshot.Path
+		}
+		return paths, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]Snapshot{}, page[from:]...), nil
+}
+
+// ListOptions - server-side filtering, field selection and sorting of list calls
+type ListOptions struct {
+	// fields to return, others stay empty in the result; default set is returned if not specified,
+	// "path" is always requested since pagination relies on it
+	Fields []string
+
+	// property filters, e.g. {"sharedOverNfs": "true"}
+	Filters map[string]string
+
+	// dataset name pattern, e.g. "pvc-*"
+	NamePattern string
+
+	// list all descendants, not only direct children
+	Recursive bool
+
+	// maximum depth of descendants to list, used with Recursive
+	Depth int
+
+	// field to sort the list by and the order: "asc" or "desc"
+	SortBy    string
+	SortOrder string
+}
+
+// listQueryParams returns NS query parameters for the options, limit and offset are set by the caller
+func (o ListOptions) listQueryParams(defaultFields string) (map[string]string, error) {
+	params := map[string]string{}
+	for key, value := range o.Filters {
+		params[key] = value
+	}
+
+	if len(o.Fields) == 0 {
+		params["fields"] = defaultFields
+	} else {
+		fields := o.Fields
+		if !containsString(fields, "path") {
+			fields = append([]string{"path"}, fields...)
+		}
+		params["fields"] = strings.Join(fields, ",")
+	}
+
+	if o.NamePattern != "" {
+		params["name"] = o.NamePattern
+	}
+	if o.Recursive {
+		params["recursive"] = "true"
+	}
+	if o.Depth > 0 {
+		params["depth"] = fmt.Sprint(o.Depth)
+	}
+
+	if o.SortBy != "" {
+		params["orderBy"] = o.SortBy
+	}
+	switch o.SortOrder {
+	case "":
+	case "asc", "desc":
+		params["orderDirection"] = o.SortOrder
+	default:
+		return nil, fmt.Errorf("ListOptions.SortOrder must be 'asc' or 'desc', got: '%s'", o.SortOrder)
+	}
+
+	return params, nil
+}
+
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package nsfake
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+)
+
+type alertKey struct {
+	source ns.AlertSource
+	id     string
+}
+
+// AddAlert adds fault or alert to the list of its source, existing one with the same ID is replaced
+func (p *Provider) AddAlert(alert ns.Alert) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if alert.Source != ns.AlertSourceFault && alert.Source != ns.AlertSourceAlert {
+		return fmt.Errorf(
+			"Unknown alert source '%s', expected '%s' or '%s'", alert.Source, ns.AlertSourceFault, ns.AlertSourceAlert)
+	}
+	if alert.Id == "" {
+		return fmt.Errorf("Alert id is required")
+	}
+	if alert.Time.IsZero() {
+		alert.Time = p.now()
+	}
+
+	p.alerts[alertKey{alert.Source, alert.Id}] = alert
+	return nil
+}
+
+// listAlerts returns alerts of the source matching the params ordered by time
+func (p *Provider) listAlerts(source ns.AlertSource, params ns.ListAlertsParams) []ns.Alert {
+	alerts := []ns.Alert{}
+	for key, alert := range p.alerts {
+		if key.source != source ||
+			(params.Severity != "" && alert.Severity != params.Severity) ||
+			(params.Component != "" && alert.Component != params.Component) ||
+			(params.Acknowledged != nil && alert.Acknowledged != *params.Acknowledged) {
+			continue
+		}
+		alerts = append(alerts, alert)
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		if !alerts[i].Time.Equal(alerts[j].Time) {
+			return alerts[i].Time.Before(alerts[j].Time)
+		}
+		return alerts[i].Id < alerts[j].Id
+	})
+	return alerts
+}
+
+// ListFaults returns faults added with AddAlert() matching the params
+func (p *Provider) ListFaults(params ns.ListAlertsParams) ([]ns.Alert, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("ListFaults", params); err != nil {
+		return nil, err
+	}
+	return p.listAlerts(ns.AlertSourceFault, params), nil
+}
+
+// ListAlerts returns alerts added with AddAlert() matching the params
+func (p *Provider) ListAlerts(params ns.ListAlertsParams) ([]ns.Alert, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("ListAlerts", params); err != nil {
+		return nil, err
+	}
+	return p.listAlerts(ns.AlertSourceAlert, params), nil
+}
+
+func (p *Provider) getAlert(source ns.AlertSource, id string) (alertKey, error) {
+	if id == "" {
+		return alertKey{}, fmt.Errorf("Alert id is required")
+	}
+	key := alertKey{source, id}
+	if _, ok := p.alerts[key]; !ok {
+		return alertKey{}, notExistError("%s '%s' not found", source, id)
+	}
+	return key, nil
+}
+
+// AcknowledgeAlert marks the fault or alert as acknowledged
+func (p *Provider) AcknowledgeAlert(source ns.AlertSource, id string) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("AcknowledgeAlert", source, id); err != nil {
+		return err
+	}
+
+	key, err := p.getAlert(source, id)
+	if err != nil {
+		return err
+	}
+	alert := p.alerts[key]
+	alert.Acknowledged = true
+	p.alerts[key] = alert
+	return nil
+}
+
+// ClearAlert removes the fault or alert from the list
+func (p *Provider) ClearAlert(source ns.AlertSource, id string) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("ClearAlert", source, id); err != nil {
+		return err
+	}
+
+	key, err := p.getAlert(source, id)
+	if err != nil {
+		return err
+	}
+	delete(p.alerts, key)
+	return nil
+}
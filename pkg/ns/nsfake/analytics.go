@@ -0,0 +1,122 @@
+package nsfake
+
+import (
+	"fmt"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+)
+
+// SetPoolStats sets I/O performance points of the pool, points must be ordered by time
+func (p *Provider) SetPoolStats(pool string, points []ns.IOStats) {
+	p.setIOStats(pool, points)
+}
+
+// SetVdevStats sets I/O performance points of the pool vdev, points must be ordered by time
+func (p *Provider) SetVdevStats(pool, vdev string, points []ns.IOStats) {
+	p.setIOStats(fmt.Sprintf("%s/%s", pool, vdev), points)
+}
+
+// SetDatasetStats sets I/O performance points of the filesystem or volume, points must be ordered by time
+func (p *Provider) SetDatasetStats(path string, points []ns.IOStats) {
+	p.setIOStats(path, points)
+}
+
+// SetNICStats sets performance points of the network interface, points must be ordered by time
+func (p *Provider) SetNICStats(name string, points []ns.NICStats) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.nicStats[name] = append([]ns.NICStats{}, points...)
+}
+
+func (p *Provider) setIOStats(resource string, points []ns.IOStats) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.ioStats[resource] = append([]ns.IOStats{}, points...)
+}
+
+// ioTimeSeries returns points of the resource in the query time range, points are not resampled
+func (p *Provider) ioTimeSeries(resource string, query ns.AnalyticsQuery) (ns.IOTimeSeries, error) {
+	query, err := query.Validate()
+	if err != nil {
+		return ns.IOTimeSeries{}, err
+	}
+
+	points := []ns.IOStats{}
+	for _, point := range p.ioStats[resource] {
+		if !point.Time.Before(query.Start) && point.Time.Before(query.End) {
+			points = append(points, point)
+		}
+	}
+
+	return ns.IOTimeSeries{Resource: resource, Resolution: query.Resolution, Points: points}, nil
+}
+
+// GetPoolStats returns points set with SetPoolStats() in the query time range
+func (p *Provider) GetPoolStats(pool string, query ns.AnalyticsQuery) (ns.IOTimeSeries, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("GetPoolStats", pool, query); err != nil {
+		return ns.IOTimeSeries{}, err
+	}
+
+	if _, ok := p.poolSizes[pool]; !ok {
+		return ns.IOTimeSeries{}, notExistError("Pool '%s' not found", pool)
+	}
+	return p.ioTimeSeries(pool, query)
+}
+
+// GetVdevStats returns points set with SetVdevStats() in the query time range
+func (p *Provider) GetVdevStats(pool, vdev string, query ns.AnalyticsQuery) (ns.IOTimeSeries, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("GetVdevStats", pool, vdev, query); err != nil {
+		return ns.IOTimeSeries{}, err
+	}
+
+	resource := fmt.Sprintf("%s/%s", pool, vdev)
+	if _, ok := p.ioStats[resource]; !ok {
+		return ns.IOTimeSeries{}, notExistError("Vdev '%s' not found", resource)
+	}
+	return p.ioTimeSeries(resource, query)
+}
+
+// GetDatasetStats returns points set with SetDatasetStats() in the query time range
+func (p *Provider) GetDatasetStats(path string, query ns.AnalyticsQuery) (ns.IOTimeSeries, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("GetDatasetStats", path, query); err != nil {
+		return ns.IOTimeSeries{}, err
+	}
+
+	if _, ok := p.datasets[path]; !ok {
+		return ns.IOTimeSeries{}, notExistError("Dataset '%s' not found", path)
+	}
+	return p.ioTimeSeries(path, query)
+}
+
+// GetNICStats returns points set with SetNICStats() in the query time range
+func (p *Provider) GetNICStats(name string, query ns.AnalyticsQuery) (ns.NICTimeSeries, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("GetNICStats", name, query); err != nil {
+		return ns.NICTimeSeries{}, err
+	}
+
+	stats, ok := p.nicStats[name]
+	if !ok {
+		return ns.NICTimeSeries{}, notExistError("Network interface '%s' not found", name)
+	}
+	query, err := query.Validate()
+	if err != nil {
+		return ns.NICTimeSeries{}, err
+	}
+
+	points := []ns.NICStats{}
+	for _, point := range stats {
+		if !point.Time.Before(query.Start) && point.Time.Before(query.End) {
+			points = append(points, point)
+		}
+	}
+
+	return ns.NICTimeSeries{Interface: name, Resolution: query.Resolution, Points: points}, nil
+}
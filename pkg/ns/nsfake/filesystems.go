@@ -0,0 +1,627 @@
+package nsfake
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+)
+
+// maximum page size of list calls, same as NexentaStor's one
+const listLimit = 100
+
+// filesystem returns filesystem as NexentaStor reports it
+func (p *Provider) filesystem(ds *dataset) ns.Filesystem {
+	fs := ds.filesystem
+	fs.MountPoint = "/" + ds.path
+	fs.BytesUsed = ds.bytesUsed
+	fs.BytesReferenced = ds.bytesUsed
+	fs.BytesUsedBySnapshots = ds.properties.BytesUsedBySnapshots
+	fs.CompressionRatio = ds.properties.CompressionRatio
+	fs.UserProperties = copyUserProperties(ds.properties.UserProperties)
+	fs.BytesAvailable = p.poolAvailable(poolName(ds.path))
+	if fs.ReferencedQuotaSize > 0 && fs.ReferencedQuotaSize-fs.BytesUsed < fs.BytesAvailable {
+		fs.BytesAvailable = fs.ReferencedQuotaSize - fs.BytesUsed
+		if fs.BytesAvailable < 0 {
+			fs.BytesAvailable = 0
+		}
+	}
+	_, fs.SharedOverNfs = p.nfsShares[ds.path]
+	_, fs.SharedOverSmb = p.smbShares[ds.path]
+	return fs
+}
+
+// listDatasets returns paths of parent dataset descendants of the kind matching the options,
+// the parent itself is included if it's of the kind
+func (p *Provider) listDatasets(parent string, kind datasetKind, options ns.ListOptions) ([]string, error) {
+	switch options.SortOrder {
+	case "", "asc", "desc":
+	default:
+		return nil, fmt.Errorf("ListOptions.SortOrder must be 'asc' or 'desc', got: '%s'", options.SortOrder)
+	}
+
+	paths := []string{}
+	for dsPath, ds := range p.datasets {
+		if ds.kind != kind {
+			continue
+		}
+		if dsPath != parent {
+			if !strings.HasPrefix(dsPath, parent+"/") {
+				continue
+			}
+			depth := strings.Count(strings.TrimPrefix(dsPath, parent+"/"), "/") + 1
+			if (!options.Recursive && depth > 1) || (options.Recursive && options.Depth > 0 && depth > options.Depth) {
+				continue
+			}
+			if options.NamePattern != "" {
+				if ok, _ := path.Match(options.NamePattern, path.Base(dsPath)); !ok {
+					continue
+				}
+			}
+		}
+		paths = append(paths, dsPath)
+	}
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+// filterAndSort applies ListOptions filters and sorting to items by their JSON fields
+func filterAndSort(items []interface{}, options ns.ListOptions) []interface{} {
+	fields := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		data, _ := json.Marshal(item)
+		json.Unmarshal(data, &fields[i])
+	}
+
+	indexes := []int{}
+	for i := range items {
+		match := true
+		for key, value := range options.Filters {
+			if fmt.Sprint(fields[i][key]) != value {
+				match = false
+				break
+			}
+		}
+		if match {
+			indexes = append(indexes, i)
+		}
+	}
+
+	if options.SortBy != "" {
+		sort.SliceStable(indexes, func(i, j int) bool {
+			a, b := fields[indexes[i]][options.SortBy], fields[indexes[j]][options.SortBy]
+			if options.SortOrder == "desc" {
+				return lessValues(b, a)
+			}
+			return lessValues(a, b)
+		})
+	}
+
+	result := make([]interface{}, len(indexes))
+	for i, index := range indexes {
+		result[i] = items[index]
+	}
+	return result
+}
+
+func lessValues(a, b interface{}) bool {
+	if aNumber, ok := a.(float64); ok {
+		if bNumber, ok := b.(float64); ok {
+			return aNumber < bNumber
+		}
+	}
+	return fmt.Sprint(a) < fmt.Sprint(b)
+}
+
+// page returns items[offset:offset+limit]
+func page(count, limit, offset int) (int, int) {
+	if offset > count {
+		offset = count
+	}
+	end := offset + limit
+	if limit <= 0 || end > count {
+		end = count
+	}
+	return offset, end
+}
+
+// listFilesystems returns filesystems as NexentaStor lists them: the parent and its children
+func (p *Provider) listFilesystems(parent string, options ns.ListOptions) ([]ns.Filesystem, error) {
+	paths, err := p.listDatasets(parent, kindFilesystem, options)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]interface{}, len(paths))
+	for i, dsPath := range paths {
+		items[i] = p.filesystem(p.datasets[dsPath])
+	}
+
+	filesystems := []ns.Filesystem{}
+	for _, item := range filterAndSort(items, options) {
+		filesystems = append(filesystems, item.(ns.Filesystem))
+	}
+	return filesystems, nil
+}
+
+func (p *Provider) getFilesystemsPage(parent string, limit, offset int, options ns.ListOptions) ([]ns.Filesystem, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	filesystems, err := p.listFilesystems(parent, options)
+	if err != nil {
+		return nil, err
+	}
+	from, to := page(len(filesystems), limit, offset)
+	return filesystems[from:to], nil
+}
+
+// withoutParent excludes parent filesystem from the list
+func withoutParent(parent string, filesystems []ns.Filesystem) []ns.Filesystem {
+	result := []ns.Filesystem{}
+	for _, fs := range filesystems {
+		if fs.Path != parent {
+			result = append(result, fs)
+		}
+	}
+	return result
+}
+
+// afterToken returns items after the one with starting token path and the next token,
+// the token is the path of the last returned item if limit is reached
+func afterToken(paths []string, startingToken string, limit int) (int, int, string) {
+	from := 0
+	if startingToken != "" {
+		from = len(paths)
+		for i, itemPath := range paths {
+			if itemPath == startingToken {
+				from = i + 1
+				break
+			}
+		}
+	}
+
+	to := len(paths)
+	if limit > 0 && from+limit < to {
+		to = from + limit
+	}
+
+	nextToken := ""
+	if limit > 0 && to-from == limit {
+		nextToken = paths[to-1]
+	}
+	return from, to, nextToken
+}
+
+// CreateFilesystem creates filesystem, parent filesystem must exist
+func (p *Provider) CreateFilesystem(params ns.CreateFilesystemParams) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("CreateFilesystem", params); err != nil {
+		return err
+	}
+
+	if params.Path == "" {
+		return fmt.Errorf("Parameter 'CreateFilesystemParams.Path' is required")
+	}
+	if err := p.checkNewDataset(params.Path, kindFilesystem); err != nil {
+		return err
+	}
+
+	p.datasets[params.Path] = &dataset{
+		kind: kindFilesystem,
+		path: params.Path,
+		filesystem: ns.Filesystem{
+			Path:                params.Path,
+			ReferencedQuotaSize: params.ReferencedQuotaSize,
+		},
+	}
+	return nil
+}
+
+// UpdateFilesystem updates filesystem quota
+func (p *Provider) UpdateFilesystem(path string, params ns.UpdateFilesystemParams) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("UpdateFilesystem", path, params); err != nil {
+		return err
+	}
+
+	if path == "" {
+		return fmt.Errorf("Parameter 'path' is required")
+	}
+	ds, err := p.getDataset(path, kindFilesystem)
+	if err != nil {
+		return err
+	}
+
+	if params.ReferencedQuotaSize != 0 {
+		ds.filesystem.ReferencedQuotaSize = params.ReferencedQuotaSize
+	}
+	return nil
+}
+
+// DestroyFilesystem destroys filesystem, see ns.DestroyFilesystemParams
+func (p *Provider) DestroyFilesystem(path string, params ns.DestroyFilesystemParams) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("DestroyFilesystem", path, params); err != nil {
+		return err
+	}
+
+	if path == "" {
+		return fmt.Errorf("Filesystem path is required")
+	}
+	ds, err := p.getDataset(path, kindFilesystem)
+	if err != nil {
+		return err
+	}
+	if parentPath(path) == "" {
+		return badArgError("Pool '%s' root filesystem cannot be destroyed", path)
+	}
+
+	return p.destroyWithPromotion(ds, params.DestroySnapshots, params.PromoteMostRecentCloneIfExists)
+}
+
+// SetFilesystemACL appends everyone@ entry of the rule set to filesystem ACL
+func (p *Provider) SetFilesystemACL(path string, aclRuleSet ns.ACLRuleSet) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("SetFilesystemACL", path, aclRuleSet); err != nil {
+		return err
+	}
+
+	if path == "" {
+		return fmt.Errorf("Filesystem path is required")
+	}
+	ds, err := p.getDataset(path, kindFilesystem)
+	if err != nil {
+		return err
+	}
+
+	permission := "full_set"
+	if aclRuleSet == ns.ACLReadOnly {
+		permission = "read_set"
+	}
+	ds.acl = append(ds.acl, ns.ACLRule{
+		Type:        "allow",
+		Principal:   "everyone@",
+		Flags:       []string{"file_inherit", "dir_inherit"},
+		Permissions: []string{permission},
+	})
+	return nil
+}
+
+// GetFilesystemACL returns ACL entries added by SetFilesystemACL
+func (p *Provider) GetFilesystemACL(path string) ([]ns.ACLRule, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("GetFilesystemACL", path); err != nil {
+		return nil, err
+	}
+
+	if path == "" {
+		return nil, fmt.Errorf("Filesystem path is required")
+	}
+	ds, err := p.getDataset(path, kindFilesystem)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]ns.ACLRule, len(ds.acl))
+	for i, rule := range ds.acl {
+		rule.Index = i
+		rules[i] = rule
+	}
+	return rules, nil
+}
+
+// DeleteFilesystemACLRule deletes ACL entry by index, following entries are shifted
+func (p *Provider) DeleteFilesystemACLRule(path string, index int) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("DeleteFilesystemACLRule", path, index); err != nil {
+		return err
+	}
+
+	if path == "" {
+		return fmt.Errorf("Filesystem path is required")
+	}
+	ds, err := p.getDataset(path, kindFilesystem)
+	if err != nil {
+		return err
+	}
+	if index < 0 || index >= len(ds.acl) {
+		return notExistError("ACL entry %d of '%s' not found", index, path)
+	}
+
+	ds.acl = append(ds.acl[:index], ds.acl[index+1:]...)
+	return nil
+}
+
+// GetFilesystem returns filesystem by path
+func (p *Provider) GetFilesystem(path string) (ns.Filesystem, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("GetFilesystem", path); err != nil {
+		return ns.Filesystem{}, err
+	}
+
+	if path == "" {
+		return ns.Filesystem{}, fmt.Errorf("Filesystem path is empty")
+	}
+	ds, err := p.getDataset(path, kindFilesystem)
+	if err != nil {
+		return ns.Filesystem{}, err
+	}
+	return p.filesystem(ds), nil
+}
+
+// GetFilesystemAvailableCapacity returns filesystem available size, 0 if there is no such filesystem
+func (p *Provider) GetFilesystemAvailableCapacity(path string) (int64, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("GetFilesystemAvailableCapacity", path); err != nil {
+		return 0, err
+	}
+
+	ds, err := p.getDataset(path, kindFilesystem)
+	if err != nil {
+		return 0, nil
+	}
+	return p.filesystem(ds).BytesAvailable, nil
+}
+
+// GetFilesystems returns child filesystems of the parent
+func (p *Provider) GetFilesystems(parent string) ([]ns.Filesystem, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("GetFilesystems", parent); err != nil {
+		return nil, err
+	}
+
+	filesystems, err := p.listFilesystems(parent, ns.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return withoutParent(parent, filesystems), nil
+}
+
+// GetFilesystemsWithStartingToken returns child filesystems after the one with starting token path,
+// next token is returned if limit is reached
+func (p *Provider) GetFilesystemsWithStartingToken(parent string, startingToken string, limit int) (
+	[]ns.Filesystem,
+	string,
+	error,
+) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("GetFilesystemsWithStartingToken", parent, startingToken, limit); err != nil {
+		return nil, "", err
+	}
+
+	filesystems, err := p.listFilesystems(parent, ns.ListOptions{})
+	if err != nil {
+		return nil, "", err
+	}
+	filesystems = withoutParent(parent, filesystems)
+
+	paths := make([]string, len(filesystems))
+	for i, fs := range filesystems {
+		paths[i] = fs.Path
+	}
+	from, to, nextToken := afterToken(paths, startingToken, limit)
+
+	return filesystems[from:to], nextToken, nil
+}
+
+// GetFilesystemsSlice returns a slice of child filesystems
+func (p *Provider) GetFilesystemsSlice(parent string, limit, offset int) ([]ns.Filesystem, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("GetFilesystemsSlice", parent, limit, offset); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 || limit >= listLimit {
+		return nil, fmt.Errorf(
+			"GetFilesystemsSlice(): parameter 'limit' must be greater that 0 and less than %d, got: %d",
+			listLimit,
+			limit,
+		)
+	} else if offset < 0 {
+		return nil, fmt.Errorf(
+			"GetFilesystemsSlice(): parameter 'offset' must be greater or equal to 0, got: %d",
+			offset,
+		)
+	}
+
+	filesystems, err := p.listFilesystems(parent, ns.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	from, to := page(len(filesystems), limit+1, offset)
+	return withoutParent(parent, filesystems[from:to]), nil
+}
+
+// GetFilesystemIterator returns iterator over child filesystems, pages are loaded from the current state
+func (p *Provider) GetFilesystemIterator(parent string, params ns.IteratorParams) *ns.FilesystemIterator {
+	p.mux.Lock()
+	p.record("GetFilesystemIterator", parent, params)
+	p.mux.Unlock()
+
+	return ns.NewFilesystemIterator(parent, params, func(limit, offset int) ([]ns.Filesystem, error) {
+		return p.getFilesystemsPage(parent, limit, offset, params.ListOptions)
+	})
+}
+
+// ListFilesystems returns child filesystems matching the options, Fields option is ignored
+func (p *Provider) ListFilesystems(parent string, options ns.ListOptions) ([]ns.Filesystem, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("ListFilesystems", parent, options); err != nil {
+		return nil, err
+	}
+
+	filesystems, err := p.listFilesystems(parent, options)
+	if err != nil {
+		return nil, err
+	}
+	return withoutParent(parent, filesystems), nil
+}
+
+// CreateNfsShare shares filesystem over NFS
+func (p *Provider) CreateNfsShare(params ns.CreateNfsShareParams) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("CreateNfsShare", params); err != nil {
+		return err
+	}
+
+	if params.Filesystem == "" {
+		return fmt.Errorf("CreateNfsShareParams.Filesystem is required")
+	}
+	if _, err := p.getDataset(params.Filesystem, kindFilesystem); err != nil {
+		return err
+	}
+	if _, ok := p.nfsShares[params.Filesystem]; ok {
+		return alreadyExistError("NFS share of '%s' already exists", params.Filesystem)
+	}
+
+	p.nfsShares[params.Filesystem] = params
+	return nil
+}
+
+// GetNfsShare returns NFS share of the filesystem with the rules it was created with
+func (p *Provider) GetNfsShare(path string) (ns.NfsShare, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("GetNfsShare", path); err != nil {
+		return ns.NfsShare{}, err
+	}
+
+	if path == "" {
+		return ns.NfsShare{}, fmt.Errorf("Filesystem path is required")
+	}
+	params, ok := p.nfsShares[path]
+	if !ok {
+		return ns.NfsShare{}, notExistError("NFS share of '%s' not found", path)
+	}
+
+	return ns.NfsShare{
+		Filesystem:       path,
+		Anon:             "root",
+		SecurityContexts: []ns.NfsSecurityContext{params.SecurityContext()},
+	}, nil
+}
+
+// DeleteNfsShare deletes NFS share of the filesystem
+func (p *Provider) DeleteNfsShare(path string) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("DeleteNfsShare", path); err != nil {
+		return err
+	}
+
+	if path == "" {
+		return fmt.Errorf("Filesystem path is empty")
+	}
+	if _, ok := p.nfsShares[path]; !ok {
+		return notExistError("NFS share of '%s' not found", path)
+	}
+
+	delete(p.nfsShares, path)
+	return nil
+}
+
+// CreateSmbShare shares filesystem over SMB, default share name is used if not set
+func (p *Provider) CreateSmbShare(params ns.CreateSmbShareParams) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("CreateSmbShare", params); err != nil {
+		return err
+	}
+
+	if params.Filesystem == "" {
+		return fmt.Errorf("CreateSmbShareParams.Filesystem is required")
+	}
+	ds, err := p.getDataset(params.Filesystem, kindFilesystem)
+	if err != nil {
+		return err
+	}
+	if _, ok := p.smbShares[params.Filesystem]; ok {
+		return alreadyExistError("SMB share of '%s' already exists", params.Filesystem)
+	}
+
+	shareName := params.ShareName
+	if shareName == "" {
+		shareName = ds.filesystem.GetDefaultSmbShareName()
+	}
+	for _, name := range p.smbShares {
+		if name == shareName {
+			return alreadyExistError("SMB share '%s' already exists", shareName)
+		}
+	}
+
+	p.smbShares[params.Filesystem] = shareName
+	return nil
+}
+
+// DeleteSmbShare deletes SMB share of the filesystem
+func (p *Provider) DeleteSmbShare(path string) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("DeleteSmbShare", path); err != nil {
+		return err
+	}
+
+	if path == "" {
+		return fmt.Errorf("Filesystem path is empty")
+	}
+	if _, ok := p.smbShares[path]; !ok {
+		return notExistError("SMB share of '%s' not found", path)
+	}
+
+	delete(p.smbShares, path)
+	return nil
+}
+
+// GetSmbShareName returns SMB share name of the filesystem
+func (p *Provider) GetSmbShareName(path string) (string, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("GetSmbShareName", path); err != nil {
+		return "", err
+	}
+
+	if path == "" {
+		return "", fmt.Errorf("Filesystem path is required")
+	}
+	shareName, ok := p.smbShares[path]
+	if !ok {
+		return "", notExistError("SMB share of '%s' not found", path)
+	}
+	return shareName, nil
+}
+
+// PromoteFilesystem makes cloned filesystem independent of its origin snapshot
+func (p *Provider) PromoteFilesystem(path string) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("PromoteFilesystem", path); err != nil {
+		return err
+	}
+
+	if path == "" {
+		return fmt.Errorf("Filesystem path is required")
+	}
+	ds, err := p.getDataset(path, kindFilesystem)
+	if err != nil {
+		return err
+	}
+	return p.promote(ds)
+}
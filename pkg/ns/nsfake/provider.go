@@ -0,0 +1,608 @@
+// Package nsfake - in-memory ns.ProviderInterface implementation for unit tests of code built on the library.
+// Provider models pools, the dataset tree, snapshots and clones, NFS/SMB shares, volumes and SAN objects,
+// returns NexentaStor error codes (EEXIST, ENOENT, EBUSY...) and records calls for assertions.
+package nsfake
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+)
+
+// default size of fake pools, 1TiB
+const defaultPoolSize = 1 << 40
+
+// system information of the fake appliance
+var defaultSystemInfo = ns.SystemInfo{
+	Version:  "5.3.0",
+	Hostname: "nsfake",
+	GUID:     "00000000-0000-0000-0000-000000000000",
+}
+
+// default block size of volumes created without one
+const defaultVolumeBlockSize = 8 * 1024
+
+// Call - recorded provider method call
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+func (c Call) String() string {
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = fmt.Sprintf("%+v", arg)
+	}
+	return fmt.Sprintf("%s(%s)", c.Method, strings.Join(args, ", "))
+}
+
+// TestingT - part of testing.TB used by assertions
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+type datasetKind int
+
+const (
+	kindFilesystem datasetKind = iota
+	kindVolumeGroup
+	kindVolume
+)
+
+func (k datasetKind) String() string {
+	switch k {
+	case kindVolumeGroup:
+		return "VolumeGroup"
+	case kindVolume:
+		return "Volume"
+	}
+	return "Filesystem"
+}
+
+// dataset - filesystem, volumeGroup or volume
+type dataset struct {
+	kind       datasetKind
+	path       string
+	bytesUsed  int64
+	filesystem ns.Filesystem
+	volume     ns.Volume
+
+	// default block size of volumeGroup volumes
+	volumeBlockSize int64
+
+	// properties set with SetProperties()
+	properties Properties
+
+	// filesystem ACL entries added by SetFilesystemACL(), indexes are positions in the list
+	acl []ns.ACLRule
+
+	// snapshot the dataset is cloned from, empty for original datasets
+	origin string
+}
+
+// snapshot - snapshot with creation transaction number to order snapshots
+type snapshot struct {
+	ns.Snapshot
+	txg int
+
+	// size of the volume at the moment the snapshot was taken, volume clones get this size
+	volumeSize int64
+}
+
+// Provider - in-memory NexentaStor provider, safe for concurrent use
+type Provider struct {
+	mux sync.Mutex
+
+	poolSizes    map[string]int64
+	datasets     map[string]*dataset
+	snapshots    map[string]*snapshot
+	nfsShares    map[string]ns.CreateNfsShareParams
+	smbShares    map[string]string
+	lunMappings  map[string]ns.LunMapping
+	hostGroups   map[string]ns.HostGroup
+	targetGroups map[string][]string
+	iscsiTargets map[string]ns.CreateISCSITargetParams
+	fcPorts      map[string]ns.FCPort
+	alerts       map[alertKey]ns.Alert
+	ioStats      map[string][]ns.IOStats
+	nicStats     map[string][]ns.NICStats
+	systemInfo   ns.SystemInfo
+	license      ns.License
+	clusters     []ns.RSFCluster
+
+	// last snapshot transaction number and LUN mapping ID
+	txg         int
+	lunMappingN int
+
+	calls  []Call
+	errors map[string]error
+	now    func() time.Time
+}
+
+var _ ns.ProviderInterface = &Provider{}
+
+// ProviderArgs - params to create fake Provider instance
+type ProviderArgs struct {
+	// Pools - names of pools to create, each pool has a root filesystem
+	Pools []string
+
+	// PoolSize - size of each pool in bytes, defaultPoolSize if not set
+	PoolSize int64
+
+	// License - returned by GetLicense(), valid license if not set
+	License *ns.License
+
+	// SystemInfo - returned by GetSystemInfo(), defaultSystemInfo if not set
+	SystemInfo *ns.SystemInfo
+
+	// RSFClusters - returned by GetRSFClusters()
+	RSFClusters []ns.RSFCluster
+
+	// FCPorts - Fibre Channel ports of the appliance
+	FCPorts []ns.FCPort
+
+	// Now - clock for snapshot creation time, time.Now if not set
+	Now func() time.Time
+}
+
+// NewProvider creates fake NexentaStor provider
+func NewProvider(args ProviderArgs) *Provider {
+	poolSize := args.PoolSize
+	if poolSize <= 0 {
+		poolSize = defaultPoolSize
+	}
+
+	now := args.Now
+	if now == nil {
+		now = time.Now
+	}
+
+	p := &Provider{
+		poolSizes:    map[string]int64{},
+		datasets:     map[string]*dataset{},
+		snapshots:    map[string]*snapshot{},
+		nfsShares:    map[string]ns.CreateNfsShareParams{},
+		smbShares:    map[string]string{},
+		lunMappings:  map[string]ns.LunMapping{},
+		hostGroups:   map[string]ns.HostGroup{},
+		targetGroups: map[string][]string{},
+		iscsiTargets: map[string]ns.CreateISCSITargetParams{},
+		fcPorts:      map[string]ns.FCPort{},
+		alerts:       map[alertKey]ns.Alert{},
+		ioStats:      map[string][]ns.IOStats{},
+		nicStats:     map[string][]ns.NICStats{},
+		systemInfo:   defaultSystemInfo,
+		license:      ns.License{Valid: true},
+		clusters:     append([]ns.RSFCluster{}, args.RSFClusters...),
+		errors:       map[string]error{},
+		now:          now,
+	}
+
+	for _, pool := range args.Pools {
+		p.poolSizes[pool] = poolSize
+		p.datasets[pool] = &dataset{kind: kindFilesystem, path: pool, filesystem: ns.Filesystem{Path: pool}}
+	}
+
+	if args.License != nil {
+		p.license = *args.License
+	}
+	if args.SystemInfo != nil {
+		p.systemInfo = *args.SystemInfo
+	}
+
+	for _, port := range args.FCPorts {
+		if wwpn, err := ns.NormalizeWWPN(port.WWPN); err == nil {
+			port.WWPN = wwpn
+		}
+		p.fcPorts[port.WWPN] = port
+	}
+
+	return p
+}
+
+// SetError makes all following calls of the method fail with the error, nil error removes the failure
+func (p *Provider) SetError(method string, err error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if err == nil {
+		delete(p.errors, method)
+	} else {
+		p.errors[method] = err
+	}
+}
+
+// SetBytesUsed sets space used by the dataset, it's 0 for all datasets by default
+func (p *Provider) SetBytesUsed(path string, bytesUsed int64) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	ds, ok := p.datasets[path]
+	if !ok {
+		return notExistError("Dataset '%s' not found", path)
+	}
+	ds.bytesUsed = bytesUsed
+	return nil
+}
+
+// Properties - dataset properties which are not changed by the fake itself
+type Properties struct {
+	BytesUsedBySnapshots int64
+	CompressionRatio     float64
+	UserProperties       map[string]string
+}
+
+// SetProperties sets properties of the filesystem or volume returned by following calls
+func (p *Provider) SetProperties(path string, properties Properties) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	ds, ok := p.datasets[path]
+	if !ok {
+		return notExistError("Dataset '%s' not found", path)
+	}
+	ds.properties = properties
+	ds.properties.UserProperties = copyUserProperties(properties.UserProperties)
+	return nil
+}
+
+func copyUserProperties(userProperties map[string]string) map[string]string {
+	if userProperties == nil {
+		return nil
+	}
+	result := make(map[string]string, len(userProperties))
+	for name, value := range userProperties {
+		result[name] = value
+	}
+	return result
+}
+
+// record adds the call to the list and returns error set by SetError(), must be called with the lock held
+func (p *Provider) record(method string, args ...interface{}) error {
+	p.calls = append(p.calls, Call{Method: method, Args: args})
+	return p.errors[method]
+}
+
+// Calls returns all recorded calls in order
+func (p *Provider) Calls() []Call {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return append([]Call{}, p.calls...)
+}
+
+// CallCount returns count of the method calls
+func (p *Provider) CallCount(method string) int {
+	count := 0
+	for _, call := range p.Calls() {
+		if call.Method == method {
+			count++
+		}
+	}
+	return count
+}
+
+// ResetCalls clears recorded calls
+func (p *Provider) ResetCalls() {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.calls = nil
+}
+
+// AssertCalled reports an error if the method hasn't been called, or hasn't been called with the args if set
+func (p *Provider) AssertCalled(t TestingT, method string, args ...interface{}) bool {
+	if p.findCall(method, args) {
+		return true
+	}
+	if len(args) == 0 {
+		t.Errorf("expected %s() to be called, got calls: %v", method, p.Calls())
+	} else {
+		t.Errorf("expected %s to be called, got calls: %v", Call{Method: method, Args: args}, p.Calls())
+	}
+	return false
+}
+
+// AssertNotCalled reports an error if the method has been called, or has been called with the args if set
+func (p *Provider) AssertNotCalled(t TestingT, method string, args ...interface{}) bool {
+	if !p.findCall(method, args) {
+		return true
+	}
+	t.Errorf("expected %s() not to be called, got calls: %v", method, p.Calls())
+	return false
+}
+
+func (p *Provider) findCall(method string, args []interface{}) bool {
+	for _, call := range p.Calls() {
+		if call.Method == method && (len(args) == 0 || reflect.DeepEqual(call.Args, args)) {
+			return true
+		}
+	}
+	return false
+}
+
+// LogIn does nothing, fake provider doesn't require authentication
+func (p *Provider) LogIn() error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return p.record("LogIn")
+}
+
+// IsJobDone returns true, all fake operations are synchronous
+func (p *Provider) IsJobDone(jobID string) (bool, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("IsJobDone", jobID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetLicense returns license set in ProviderArgs
+func (p *Provider) GetLicense() (ns.License, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("GetLicense"); err != nil {
+		return ns.License{}, err
+	}
+	return p.license, nil
+}
+
+// GetSystemInfo returns system information set in ProviderArgs
+func (p *Provider) GetSystemInfo() (ns.SystemInfo, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("GetSystemInfo"); err != nil {
+		return ns.SystemInfo{}, err
+	}
+	return p.systemInfo, nil
+}
+
+// GetRSFClusters returns clusters set in ProviderArgs
+func (p *Provider) GetRSFClusters() ([]ns.RSFCluster, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("GetRSFClusters"); err != nil {
+		return nil, err
+	}
+	return append([]ns.RSFCluster{}, p.clusters...), nil
+}
+
+// GetPools returns pools sorted by name
+func (p *Provider) GetPools() ([]ns.Pool, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("GetPools"); err != nil {
+		return nil, err
+	}
+
+	pools := []ns.Pool{}
+	for name := range p.poolSizes {
+		pools = append(pools, ns.Pool{Name: name})
+	}
+	sort.Slice(pools, func(i, j int) bool { return pools[i].Name < pools[j].Name })
+	return pools, nil
+}
+
+func nefError(code, format string, args ...interface{}) error {
+	return &ns.NefError{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+func notExistError(format string, args ...interface{}) error {
+	return nefError("ENOENT", format, args...)
+}
+
+func alreadyExistError(format string, args ...interface{}) error {
+	return nefError("EEXIST", format, args...)
+}
+
+func busyError(format string, args ...interface{}) error {
+	return nefError("EBUSY", format, args...)
+}
+
+func badArgError(format string, args ...interface{}) error {
+	return nefError("EBADARG", format, args...)
+}
+
+// parentPath returns path of the parent dataset, empty string for pools
+func parentPath(path string) string {
+	if i := strings.LastIndexByte(path, '/'); i != -1 {
+		return path[:i]
+	}
+	return ""
+}
+
+func poolName(path string) string {
+	return strings.SplitN(path, "/", 2)[0]
+}
+
+// poolAvailable returns pool size minus space used and reserved by its datasets
+func (p *Provider) poolAvailable(pool string) int64 {
+	available := p.poolSizes[pool]
+	for _, ds := range p.datasets {
+		if poolName(ds.path) != pool {
+			continue
+		}
+		available -= ds.bytesUsed
+		if ds.kind == kindVolume && ds.volume.ReservationSize > ds.bytesUsed {
+			available -= ds.volume.ReservationSize - ds.bytesUsed
+		}
+	}
+	if available < 0 {
+		return 0
+	}
+	return available
+}
+
+// getDataset returns dataset of the kind, ENOENT error if there is no such dataset
+func (p *Provider) getDataset(path string, kind datasetKind) (*dataset, error) {
+	ds, ok := p.datasets[path]
+	if !ok || ds.kind != kind {
+		return nil, notExistError("%s '%s' not found", kind, path)
+	}
+	return ds, nil
+}
+
+// checkNewDataset checks that the dataset doesn't exist and its parent of one of the kinds exists
+func (p *Provider) checkNewDataset(path string, parentKinds ...datasetKind) error {
+	if _, ok := p.datasets[path]; ok {
+		return alreadyExistError("Dataset '%s' already exists", path)
+	}
+
+	parent, ok := p.datasets[parentPath(path)]
+	if !ok {
+		return notExistError("Parent dataset of '%s' not found", path)
+	}
+	for _, kind := range parentKinds {
+		if parent.kind == kind {
+			return nil
+		}
+	}
+	return badArgError("%s '%s' cannot contain dataset '%s'", parent.kind, parent.path, path)
+}
+
+// children returns paths of direct child datasets
+func (p *Provider) children(path string) []string {
+	children := []string{}
+	for childPath := range p.datasets {
+		if parentPath(childPath) == path {
+			children = append(children, childPath)
+		}
+	}
+	sort.Strings(children)
+	return children
+}
+
+// datasetSnapshots returns snapshots of the dataset ordered by creation
+func (p *Provider) datasetSnapshots(path string) []*snapshot {
+	snapshots := []*snapshot{}
+	for _, s := range p.snapshots {
+		if s.Parent == path {
+			snapshots = append(snapshots, s)
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].txg < snapshots[j].txg })
+	return snapshots
+}
+
+// destroyDataset removes dataset and its snapshots, see DestroyFilesystemParams
+func (p *Provider) destroyDataset(ds *dataset, destroySnapshots bool) error {
+	if children := p.children(ds.path); len(children) > 0 {
+		return busyError("%s '%s' has children: %s", ds.kind, ds.path, strings.Join(children, ", "))
+	}
+
+	snapshots := p.datasetSnapshots(ds.path)
+	if len(snapshots) > 0 && !destroySnapshots {
+		return busyError("%s '%s' has snapshots", ds.kind, ds.path)
+	}
+	for _, s := range snapshots {
+		if len(s.Clones) > 0 {
+			return alreadyExistError(
+				"%s '%s' has dependent clones: %s", ds.kind, ds.path, strings.Join(s.Clones, ", "))
+		}
+	}
+
+	for _, s := range snapshots {
+		delete(p.snapshots, s.Path)
+	}
+	p.unlinkClone(ds)
+	delete(p.datasets, ds.path)
+	delete(p.nfsShares, ds.path)
+	delete(p.smbShares, ds.path)
+
+	return nil
+}
+
+// unlinkClone removes the dataset from clones of its origin snapshot
+func (p *Provider) unlinkClone(ds *dataset) {
+	origin, ok := p.snapshots[ds.origin]
+	if !ok {
+		return
+	}
+	clones := []string{}
+	for _, clone := range origin.Clones {
+		if clone != ds.path {
+			clones = append(clones, clone)
+		}
+	}
+	origin.Clones = clones
+}
+
+// destroyWithPromotion destroys dataset, promotes the most recent clone first if requested and dataset has clones
+func (p *Provider) destroyWithPromotion(ds *dataset, destroySnapshots, promote bool) error {
+	err := p.destroyDataset(ds, destroySnapshots)
+	if err == nil || !promote || !ns.IsAlreadyExistNefError(err) {
+		return err
+	}
+
+	var mostRecent *snapshot
+	for _, s := range p.datasetSnapshots(ds.path) {
+		if len(s.Clones) > 0 {
+			mostRecent = s
+		}
+	}
+	if err := p.promote(p.datasets[mostRecent.Clones[0]]); err != nil {
+		return err
+	}
+
+	return p.destroyDataset(ds, destroySnapshots)
+}
+
+// promote makes the clone independent: snapshots of the origin dataset up to the clone origin snapshot
+// are moved to the clone, the origin dataset becomes a clone of the moved snapshot
+func (p *Provider) promote(clone *dataset) error {
+	origin, ok := p.snapshots[clone.origin]
+	if !ok {
+		return badArgError("%s '%s' is not a clone", clone.kind, clone.path)
+	}
+	source := p.datasets[origin.Parent]
+
+	for _, s := range p.datasetSnapshots(source.path) {
+		if s.txg > origin.txg {
+			continue
+		}
+		if _, ok := p.snapshots[clone.path+"@"+s.Name]; ok {
+			return alreadyExistError("Snapshot '%s@%s' already exists", clone.path, s.Name)
+		}
+	}
+
+	renamed := map[string]string{}
+	for _, s := range p.datasetSnapshots(source.path) {
+		if s.txg > origin.txg {
+			continue
+		}
+		delete(p.snapshots, s.Path)
+		renamed[s.Path] = clone.path + "@" + s.Name
+		s.Path = renamed[s.Path]
+		s.Parent = clone.path
+		p.snapshots[s.Path] = s
+	}
+
+	// source is a clone of the moved snapshot now, other clones keep using it
+	clones := []string{source.path}
+	for _, c := range origin.Clones {
+		if c != clone.path {
+			clones = append(clones, c)
+		}
+	}
+	origin.Clones = clones
+	source.origin, clone.origin = origin.Path, source.origin
+	if previousOrigin, ok := p.snapshots[clone.origin]; ok {
+		for i, c := range previousOrigin.Clones {
+			if c == source.path {
+				previousOrigin.Clones[i] = clone.path
+			}
+		}
+	}
+
+	for _, ds := range p.datasets {
+		if newPath, ok := renamed[ds.origin]; ok {
+			ds.origin = newPath
+		}
+	}
+
+	return nil
+}
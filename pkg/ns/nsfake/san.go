@@ -0,0 +1,320 @@
+package nsfake
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+)
+
+// listLunMappings returns LUN mappings matching the params in creation order
+func (p *Provider) listLunMappings(params ns.ListLunMappingsParams) []ns.LunMapping {
+	lunMappings := []ns.LunMapping{}
+	for _, lunMapping := range p.lunMappings {
+		if (params.Volume == "" || lunMapping.Volume == params.Volume) &&
+			(params.HostGroup == "" || lunMapping.HostGroup == params.HostGroup) &&
+			(params.TargetGroup == "" || lunMapping.TargetGroup == params.TargetGroup) {
+			lunMappings = append(lunMappings, lunMapping)
+		}
+	}
+	sort.Slice(lunMappings, func(i, j int) bool { return lunMappings[i].Id < lunMappings[j].Id })
+	return lunMappings
+}
+
+// nextFreeLun returns the lowest LUN number not used in the host group
+func (p *Provider) nextFreeLun(hostGroup string) (int, error) {
+	usedLuns := map[int]bool{}
+	for _, lunMapping := range p.listLunMappings(ns.ListLunMappingsParams{HostGroup: hostGroup}) {
+		usedLuns[lunMapping.Lun] = true
+	}
+
+	for lun := 0; lun <= ns.MaxLun; lun++ {
+		if !usedLuns[lun] {
+			return lun, nil
+		}
+	}
+
+	return 0, nefError(
+		"ENOSPC", "No free LUN number left in host group '%s' (max: %d)", hostGroup, ns.MaxLun)
+}
+
+// CreateLunMapping maps volume to the host group, existing mapping of the volume to the group is kept,
+// it's an EEXIST error if the LUN number is set explicitly like ns.Provider does
+func (p *Provider) CreateLunMapping(params ns.CreateLunMappingParams) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("CreateLunMapping", params); err != nil {
+		return err
+	}
+
+	if params.HostGroup == "" || params.Volume == "" || params.TargetGroup == "" {
+		return fmt.Errorf(
+			"Parameters 'HostGroup', 'Target' and 'TargetGroup' are required, received: %+v", params)
+	}
+	if params.Lun != nil && (*params.Lun < 0 || *params.Lun > ns.MaxLun) {
+		return fmt.Errorf("Parameter 'Lun' must be between 0 and %d, received: %d", ns.MaxLun, *params.Lun)
+	}
+	if _, err := p.getDataset(params.Volume, kindVolume); err != nil {
+		return err
+	}
+	if _, ok := p.hostGroups[params.HostGroup]; !ok {
+		return notExistError("Host group '%s' not found", params.HostGroup)
+	}
+	if _, ok := p.targetGroups[params.TargetGroup]; !ok {
+		return notExistError("Target group '%s' not found", params.TargetGroup)
+	}
+
+	existing := p.listLunMappings(ns.ListLunMappingsParams{Volume: params.Volume, HostGroup: params.HostGroup})
+	if len(existing) > 0 {
+		if params.Lun != nil {
+			return alreadyExistError(
+				"Volume '%s' is already mapped to host group '%s'", params.Volume, params.HostGroup)
+		}
+		return nil
+	}
+
+	var lun int
+	if params.Lun != nil {
+		lun = *params.Lun
+		for _, lunMapping := range p.listLunMappings(ns.ListLunMappingsParams{HostGroup: params.HostGroup}) {
+			if lunMapping.Lun == lun {
+				return alreadyExistError("LUN %d is already used in host group '%s'", lun, params.HostGroup)
+			}
+		}
+	} else {
+		var err error
+		if lun, err = p.nextFreeLun(params.HostGroup); err != nil {
+			return err
+		}
+	}
+
+	p.lunMappingN++
+	id := fmt.Sprintf("%032x", p.lunMappingN)
+	p.lunMappings[id] = ns.LunMapping{
+		Id:          id,
+		Volume:      params.Volume,
+		TargetGroup: params.TargetGroup,
+		HostGroup:   params.HostGroup,
+		Lun:         lun,
+	}
+	return nil
+}
+
+// GetLunMapping returns the first LUN mapping of the volume
+func (p *Provider) GetLunMapping(path string) (ns.LunMapping, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("GetLunMapping", path); err != nil {
+		return ns.LunMapping{}, err
+	}
+
+	if path == "" {
+		return ns.LunMapping{}, fmt.Errorf("Volume path is empty")
+	}
+	lunMappings := p.listLunMappings(ns.ListLunMappingsParams{Volume: path})
+	if len(lunMappings) == 0 {
+		return ns.LunMapping{}, notExistError("lunMapping '%s' not found", path)
+	}
+	return lunMappings[0], nil
+}
+
+// ListLunMappings returns LUN mappings matching the params
+func (p *Provider) ListLunMappings(params ns.ListLunMappingsParams) ([]ns.LunMapping, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("ListLunMappings", params); err != nil {
+		return nil, err
+	}
+	return p.listLunMappings(params), nil
+}
+
+// GetNextFreeLun returns the lowest LUN number not used in the host group
+func (p *Provider) GetNextFreeLun(hostGroup string) (int, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("GetNextFreeLun", hostGroup); err != nil {
+		return 0, err
+	}
+
+	if hostGroup == "" {
+		return 0, fmt.Errorf("Host group is required")
+	}
+	return p.nextFreeLun(hostGroup)
+}
+
+// DestroyLunMapping destroys LUN mapping by ID
+func (p *Provider) DestroyLunMapping(id string) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("DestroyLunMapping", id); err != nil {
+		return err
+	}
+
+	if id == "" {
+		return fmt.Errorf("LunMapping id is required")
+	}
+	if _, ok := p.lunMappings[id]; !ok {
+		return notExistError("lunMapping '%s' not found", id)
+	}
+
+	delete(p.lunMappings, id)
+	return nil
+}
+
+// CreateISCSITarget creates iSCSI target, existing target is kept
+func (p *Provider) CreateISCSITarget(params ns.CreateISCSITargetParams) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("CreateISCSITarget", params); err != nil {
+		return err
+	}
+
+	if params.Name == "" {
+		return fmt.Errorf("Parameters 'Name' and 'Portal' are required, received: %+v", params)
+	}
+	if _, ok := p.iscsiTargets[params.Name]; !ok {
+		p.iscsiTargets[params.Name] = params
+	}
+	return nil
+}
+
+func (p *Provider) createUpdateTargetGroup(params ns.CreateTargetGroupParams) error {
+	if params.Name == "" || len(params.Members) == 0 {
+		return fmt.Errorf("Parameters 'Name' and 'Members' are required, received: %+v", params)
+	}
+	p.targetGroups[params.Name] = append([]string{}, params.Members...)
+	return nil
+}
+
+func (p *Provider) createUpdateHostGroup(params ns.CreateHostGroupParams) error {
+	if params.Name == "" || len(params.Members) == 0 {
+		return fmt.Errorf("Parameters 'Name' and 'Members' are required, received: %+v", params)
+	}
+	p.hostGroups[params.Name] = ns.HostGroup{Name: params.Name, Members: append([]string{}, params.Members...)}
+	return nil
+}
+
+// CreateUpdateTargetGroup creates target group or updates members of existing one
+func (p *Provider) CreateUpdateTargetGroup(params ns.CreateTargetGroupParams) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("CreateUpdateTargetGroup", params); err != nil {
+		return err
+	}
+	return p.createUpdateTargetGroup(params)
+}
+
+// CreateUpdateHostGroup creates host group or updates members of existing one
+func (p *Provider) CreateUpdateHostGroup(params ns.CreateHostGroupParams) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("CreateUpdateHostGroup", params); err != nil {
+		return err
+	}
+	return p.createUpdateHostGroup(params)
+}
+
+// GetHostGroup returns host group by name
+func (p *Provider) GetHostGroup(name string) (ns.HostGroup, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("GetHostGroup", name); err != nil {
+		return ns.HostGroup{}, err
+	}
+
+	if name == "" {
+		return ns.HostGroup{}, fmt.Errorf("Host group name is empty")
+	}
+	hostGroup, ok := p.hostGroups[name]
+	if !ok {
+		return ns.HostGroup{}, notExistError("Host group '%s' not found", name)
+	}
+	hostGroup.Members = append([]string{}, hostGroup.Members...)
+	return hostGroup, nil
+}
+
+// GetFCTargetPorts returns Fibre Channel ports set in ProviderArgs sorted by WWPN
+func (p *Provider) GetFCTargetPorts() ([]ns.FCPort, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("GetFCTargetPorts"); err != nil {
+		return nil, err
+	}
+
+	ports := []ns.FCPort{}
+	for _, port := range p.fcPorts {
+		ports = append(ports, port)
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i].WWPN < ports[j].WWPN })
+	return ports, nil
+}
+
+// SetFCPortMode sets Fibre Channel port mode
+func (p *Provider) SetFCPortMode(wwpn string, mode ns.FCPortMode) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("SetFCPortMode", wwpn, mode); err != nil {
+		return err
+	}
+
+	if mode != ns.FCPortModeTarget && mode != ns.FCPortModeInitiator {
+		return fmt.Errorf(
+			"Unknown FC port mode '%s', expected '%s' or '%s'", mode, ns.FCPortModeTarget, ns.FCPortModeInitiator)
+	}
+	normalized, err := ns.NormalizeWWPN(wwpn)
+	if err != nil {
+		return err
+	}
+	port, ok := p.fcPorts[normalized]
+	if !ok {
+		return notExistError("FC port '%s' not found", normalized)
+	}
+
+	port.Mode = mode
+	p.fcPorts[normalized] = port
+	return nil
+}
+
+func normalizeWWPNs(wwpns []string) ([]string, error) {
+	normalized := make([]string, 0, len(wwpns))
+	for _, wwpn := range wwpns {
+		n, err := ns.NormalizeWWPN(wwpn)
+		if err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, n)
+	}
+	return normalized, nil
+}
+
+// CreateUpdateFCTargetGroup creates or updates target group of Fibre Channel ports, WWPNs are normalized
+func (p *Provider) CreateUpdateFCTargetGroup(params ns.CreateTargetGroupParams) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("CreateUpdateFCTargetGroup", params); err != nil {
+		return err
+	}
+
+	members, err := normalizeWWPNs(params.Members)
+	if err != nil {
+		return err
+	}
+	params.Members = members
+	return p.createUpdateTargetGroup(params)
+}
+
+// CreateUpdateFCHostGroup creates or updates host group of Fibre Channel initiators, WWPNs are normalized
+func (p *Provider) CreateUpdateFCHostGroup(params ns.CreateHostGroupParams) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("CreateUpdateFCHostGroup", params); err != nil {
+		return err
+	}
+
+	members, err := normalizeWWPNs(params.Members)
+	if err != nil {
+		return err
+	}
+	params.Members = members
+	return p.createUpdateHostGroup(params)
+}
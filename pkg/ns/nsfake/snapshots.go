@@ -0,0 +1,250 @@
+package nsfake
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+)
+
+// splitSnapshotPath splits 'pool/fs@snapshot' path to dataset path and snapshot name
+func splitSnapshotPath(snapshotPath string) (string, string, error) {
+	parts := strings.Split(snapshotPath, "@")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", badArgError("Snapshot path must be 'dataset@name', got: '%s'", snapshotPath)
+	}
+	return parts[0], parts[1], nil
+}
+
+func (p *Provider) getSnapshot(snapshotPath string) (*snapshot, error) {
+	s, ok := p.snapshots[snapshotPath]
+	if !ok {
+		return nil, notExistError("Snapshot '%s' not found", snapshotPath)
+	}
+	return s, nil
+}
+
+// snapshotCopy returns snapshot without shared clones slice
+func snapshotCopy(s *snapshot) ns.Snapshot {
+	snapshot := s.Snapshot
+	snapshot.Clones = append([]string{}, s.Clones...)
+	return snapshot
+}
+
+// listSnapshots returns snapshots of the dataset, and of its descendants if recursive, matching the options
+func (p *Provider) listSnapshots(volumePath string, recursive bool, options ns.ListOptions) ([]ns.Snapshot, error) {
+	switch options.SortOrder {
+	case "", "asc", "desc":
+	default:
+		return nil, fmt.Errorf("ListOptions.SortOrder must be 'asc' or 'desc', got: '%s'", options.SortOrder)
+	}
+
+	snapshots := []*snapshot{}
+	for _, s := range p.snapshots {
+		if s.Parent != volumePath && !(recursive && strings.HasPrefix(s.Parent, volumePath+"/")) {
+			continue
+		}
+		if options.NamePattern != "" {
+			if ok, _ := path.Match(options.NamePattern, s.Name); !ok {
+				continue
+			}
+		}
+		snapshots = append(snapshots, s)
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].txg < snapshots[j].txg })
+
+	items := make([]interface{}, len(snapshots))
+	for i, s := range snapshots {
+		items[i] = snapshotCopy(s)
+	}
+
+	result := []ns.Snapshot{}
+	for _, item := range filterAndSort(items, options) {
+		result = append(result, item.(ns.Snapshot))
+	}
+	return result, nil
+}
+
+// CreateSnapshot creates snapshot, path format: 'pool/fs@snapshot'
+func (p *Provider) CreateSnapshot(params ns.CreateSnapshotParams) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("CreateSnapshot", params); err != nil {
+		return err
+	}
+
+	if params.Path == "" {
+		return fmt.Errorf("Parameter 'CreateSnapshotParams.Path' is required")
+	}
+	datasetPath, name, err := splitSnapshotPath(params.Path)
+	if err != nil {
+		return err
+	}
+	if _, ok := p.datasets[datasetPath]; !ok {
+		return notExistError("Dataset '%s' not found", datasetPath)
+	}
+	if _, ok := p.snapshots[params.Path]; ok {
+		return alreadyExistError("Snapshot '%s' already exists", params.Path)
+	}
+
+	p.txg++
+	p.snapshots[params.Path] = &snapshot{
+		Snapshot: ns.Snapshot{
+			Path:         params.Path,
+			Name:         name,
+			Parent:       datasetPath,
+			Clones:       []string{},
+			CreationTxg:  strconv.Itoa(p.txg),
+			CreationTime: p.now(),
+		},
+		txg:        p.txg,
+		volumeSize: p.datasets[datasetPath].volume.VolumeSize,
+	}
+	return nil
+}
+
+// DestroySnapshot destroys snapshot, snapshot with clones cannot be destroyed
+func (p *Provider) DestroySnapshot(path string) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("DestroySnapshot", path); err != nil {
+		return err
+	}
+
+	if path == "" {
+		return fmt.Errorf("Snapshot path is required")
+	}
+	s, err := p.getSnapshot(path)
+	if err != nil {
+		return err
+	}
+	if len(s.Clones) > 0 {
+		return busyError("Snapshot '%s' has dependent clones: %s", path, strings.Join(s.Clones, ", "))
+	}
+
+	delete(p.snapshots, path)
+	return nil
+}
+
+// GetSnapshot returns snapshot by path
+func (p *Provider) GetSnapshot(path string) (ns.Snapshot, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("GetSnapshot", path); err != nil {
+		return ns.Snapshot{}, err
+	}
+
+	if path == "" {
+		return ns.Snapshot{}, fmt.Errorf("Snapshot path is empty")
+	}
+	s, err := p.getSnapshot(path)
+	if err != nil {
+		return ns.Snapshot{}, err
+	}
+	return snapshotCopy(s), nil
+}
+
+// GetSnapshots returns snapshots of the dataset ordered by creation
+func (p *Provider) GetSnapshots(volumePath string, recursive bool) ([]ns.Snapshot, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("GetSnapshots", volumePath, recursive); err != nil {
+		return nil, err
+	}
+
+	if volumePath == "" {
+		return []ns.Snapshot{}, fmt.Errorf("Snapshots volume path is empty")
+	}
+	return p.listSnapshots(volumePath, recursive, ns.ListOptions{})
+}
+
+// GetSnapshotIterator returns iterator over snapshots of the dataset, pages are loaded from the current state
+func (p *Provider) GetSnapshotIterator(volumePath string, recursive bool, params ns.IteratorParams) *ns.SnapshotIterator {
+	p.mux.Lock()
+	p.record("GetSnapshotIterator", volumePath, recursive, params)
+	p.mux.Unlock()
+
+	return ns.NewSnapshotIterator(params, func(limit, offset int) ([]ns.Snapshot, error) {
+		p.mux.Lock()
+		defer p.mux.Unlock()
+
+		snapshots, err := p.listSnapshots(volumePath, recursive || params.Recursive, params.ListOptions)
+		if err != nil {
+			return nil, err
+		}
+		from, to := page(len(snapshots), limit, offset)
+		return snapshots[from:to], nil
+	})
+}
+
+// ListSnapshots returns snapshots of the dataset matching the options, Fields option is ignored
+func (p *Provider) ListSnapshots(volumePath string, options ns.ListOptions) ([]ns.Snapshot, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("ListSnapshots", volumePath, options); err != nil {
+		return nil, err
+	}
+
+	if volumePath == "" {
+		return []ns.Snapshot{}, fmt.Errorf("Snapshots volume path is empty")
+	}
+	return p.listSnapshots(volumePath, options.Recursive, options)
+}
+
+// clone creates dataset of the snapshot dataset kind linked to the snapshot
+func (p *Provider) clone(snapshotPath, targetPath string) (*dataset, error) {
+	s, err := p.getSnapshot(snapshotPath)
+	if err != nil {
+		return nil, err
+	}
+	source := p.datasets[s.Parent]
+
+	parentKinds := []datasetKind{kindFilesystem}
+	if source.kind == kindVolume {
+		parentKinds = []datasetKind{kindVolumeGroup}
+	}
+	if err := p.checkNewDataset(targetPath, parentKinds...); err != nil {
+		return nil, err
+	}
+
+	ds := &dataset{
+		kind:       source.kind,
+		path:       targetPath,
+		filesystem: ns.Filesystem{Path: targetPath},
+		volume:     source.volume,
+		origin:     snapshotPath,
+	}
+	ds.volume.Path = targetPath
+	ds.volume.VolumeSize = s.volumeSize
+
+	p.datasets[targetPath] = ds
+	s.Clones = append(s.Clones, targetPath)
+
+	return ds, nil
+}
+
+// CloneSnapshot clones snapshot to a new filesystem
+func (p *Provider) CloneSnapshot(path string, params ns.CloneSnapshotParams) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("CloneSnapshot", path, params); err != nil {
+		return err
+	}
+
+	if path == "" {
+		return fmt.Errorf("Snapshot path is required")
+	}
+	if params.TargetPath == "" {
+		return fmt.Errorf("Parameter 'CloneSnapshotParams.TargetPath' is required")
+	}
+
+	ds, err := p.clone(path, params.TargetPath)
+	if err != nil {
+		return err
+	}
+	ds.filesystem.ReferencedQuotaSize = params.ReferencedQuotaSize
+	return nil
+}
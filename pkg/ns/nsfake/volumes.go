@@ -0,0 +1,498 @@
+package nsfake
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+)
+
+// volumeState returns volume as NexentaStor reports it
+func (p *Provider) volumeState(ds *dataset) ns.Volume {
+	volume := ds.volume
+	volume.BytesUsed = ds.bytesUsed
+	volume.BytesReferenced = ds.bytesUsed
+	volume.BytesUsedBySnapshots = ds.properties.BytesUsedBySnapshots
+	volume.CompressionRatio = ds.properties.CompressionRatio
+	volume.UserProperties = copyUserProperties(ds.properties.UserProperties)
+	volume.BytesAvailable = p.poolAvailable(poolName(ds.path))
+	return volume
+}
+
+// listVolumes returns volumes of the volumeGroup matching the options
+func (p *Provider) listVolumes(parent string, options ns.ListOptions) ([]ns.Volume, error) {
+	paths, err := p.listDatasets(parent, kindVolume, options)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]interface{}, len(paths))
+	for i, dsPath := range paths {
+		items[i] = p.volumeState(p.datasets[dsPath])
+	}
+
+	volumes := []ns.Volume{}
+	for _, item := range filterAndSort(items, options) {
+		volumes = append(volumes, item.(ns.Volume))
+	}
+	return volumes, nil
+}
+
+// mappedVolumeError returns EBUSY error if the volume or volumes under the path have LUN mappings
+func (p *Provider) mappedVolumeError(path string) error {
+	for _, lunMapping := range p.lunMappings {
+		if lunMapping.Volume == path || strings.HasPrefix(lunMapping.Volume, path+"/") {
+			return busyError("Volume '%s' is mapped to host group '%s'", lunMapping.Volume, lunMapping.HostGroup)
+		}
+	}
+	return nil
+}
+
+// setVolumeSize changes size of the volume, reservation of thick provisioned volume follows the size
+func (p *Provider) setVolumeSize(ds *dataset, size int64) error {
+	if !ds.volume.SparseVolume {
+		required := size - ds.volume.ReservationSize
+		if available := p.poolAvailable(poolName(ds.path)); required > available {
+			return nefError(
+				"ENOSPC", "Cannot reserve %d bytes for volume '%s', pool has %d available", required, ds.path, available)
+		}
+		ds.volume.ReservationSize = size
+	}
+	ds.volume.VolumeSize = size
+	return nil
+}
+
+// CreateVolume creates volume in volumeGroup, thick provisioned volume reserves its size in the pool
+func (p *Provider) CreateVolume(params ns.CreateVolumeParams) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("CreateVolume", params); err != nil {
+		return err
+	}
+
+	if params.Path == "" {
+		return fmt.Errorf("Parameters 'Volume.Path' is required, received %+v", params)
+	}
+	if params.VolumeBlockSize != 0 {
+		if err := ns.ValidateVolumeBlockSize(params.VolumeBlockSize); err != nil {
+			return err
+		}
+	}
+	if err := p.checkNewDataset(params.Path, kindVolumeGroup); err != nil {
+		return err
+	}
+
+	blockSize := params.VolumeBlockSize
+	if blockSize == 0 {
+		blockSize = p.datasets[parentPath(params.Path)].volumeBlockSize
+	}
+	if blockSize == 0 {
+		blockSize = defaultVolumeBlockSize
+	}
+
+	ds := &dataset{
+		kind: kindVolume,
+		path: params.Path,
+		volume: ns.Volume{
+			Path:            params.Path,
+			VolumeBlockSize: blockSize,
+			SparseVolume:    params.SparseVolume,
+			CompressionMode: params.CompressionMode,
+			SyncMode:        params.SyncMode,
+			LogBias:         params.LogBias,
+		},
+	}
+	if err := p.setVolumeSize(ds, params.VolumeSize); err != nil {
+		return err
+	}
+	if params.ReservationSize != 0 {
+		ds.volume.ReservationSize = params.ReservationSize
+	}
+
+	p.datasets[params.Path] = ds
+	return nil
+}
+
+// GetVolume returns volume by path
+func (p *Provider) GetVolume(path string) (ns.Volume, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("GetVolume", path); err != nil {
+		return ns.Volume{}, err
+	}
+
+	if path == "" {
+		return ns.Volume{}, fmt.Errorf("Volume path is empty")
+	}
+	ds, err := p.getDataset(path, kindVolume)
+	if err != nil {
+		return ns.Volume{}, err
+	}
+	return p.volumeState(ds), nil
+}
+
+// GetVolumes returns volumes of the volumeGroup
+func (p *Provider) GetVolumes(parent string) ([]ns.Volume, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("GetVolumes", parent); err != nil {
+		return nil, err
+	}
+	return p.listVolumes(parent, ns.ListOptions{})
+}
+
+// UpdateVolume updates volume size and modes
+func (p *Provider) UpdateVolume(path string, params ns.UpdateVolumeParams) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("UpdateVolume", path, params); err != nil {
+		return err
+	}
+
+	if path == "" {
+		return fmt.Errorf("Parameter 'path' is required")
+	}
+	ds, err := p.getDataset(path, kindVolume)
+	if err != nil {
+		return err
+	}
+
+	if params.VolumeSize != 0 {
+		if err := p.setVolumeSize(ds, params.VolumeSize); err != nil {
+			return err
+		}
+	}
+	if params.CompressionMode != "" {
+		ds.volume.CompressionMode = params.CompressionMode
+	}
+	if params.SyncMode != "" {
+		ds.volume.SyncMode = params.SyncMode
+	}
+	if params.LogBias != "" {
+		ds.volume.LogBias = params.LogBias
+	}
+	if params.ReservationSize != nil {
+		ds.volume.ReservationSize = *params.ReservationSize
+	}
+	return nil
+}
+
+// ResizeVolume resizes volume the same way ns.Provider does: the size is rounded up to the block size,
+// shrinking requires Force parameter
+func (p *Provider) ResizeVolume(path string, newSize int64, params ns.ResizeVolumeParams) (ns.ResizeVolumeResult, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	result := ns.ResizeVolumeResult{}
+	if err := p.record("ResizeVolume", path, newSize, params); err != nil {
+		return result, err
+	}
+
+	if path == "" {
+		return result, fmt.Errorf("Volume path is required")
+	} else if newSize <= 0 {
+		return result, fmt.Errorf("Volume size must be greater than 0, got: %d", newSize)
+	}
+	ds, err := p.getDataset(path, kindVolume)
+	if err != nil {
+		return result, err
+	}
+
+	result.PreviousSize = ds.volume.VolumeSize
+	result.VolumeSize = newSize
+	if blockSize := ds.volume.VolumeBlockSize; blockSize > 0 && newSize%blockSize != 0 {
+		result.VolumeSize = (newSize/blockSize + 1) * blockSize
+	}
+	result.LunMappings = p.listLunMappings(ns.ListLunMappingsParams{Volume: path})
+
+	if result.VolumeSize == ds.volume.VolumeSize {
+		return result, nil
+	} else if result.VolumeSize < ds.volume.VolumeSize && !params.Force {
+		return result, badArgError(
+			"Volume '%s' cannot be shrunk from %d to %d bytes without 'Force' parameter (LUN mapped: %t)",
+			path,
+			ds.volume.VolumeSize,
+			result.VolumeSize,
+			result.IsLunMapped(),
+		)
+	}
+
+	return result, p.setVolumeSize(ds, result.VolumeSize)
+}
+
+// DestroyVolume destroys volume, see ns.DestroyVolumeParams, mapped volume cannot be destroyed
+func (p *Provider) DestroyVolume(path string, params ns.DestroyVolumeParams) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("DestroyVolume", path, params); err != nil {
+		return err
+	}
+
+	if path == "" {
+		return fmt.Errorf("Volume path is required")
+	}
+	ds, err := p.getDataset(path, kindVolume)
+	if err != nil {
+		return err
+	}
+	if err := p.mappedVolumeError(path); err != nil {
+		return err
+	}
+
+	return p.destroyWithPromotion(ds, params.DestroySnapshots, params.PromoteMostRecentCloneIfExists)
+}
+
+// GetVolumeGroup returns volumeGroup by path
+func (p *Provider) GetVolumeGroup(path string) (ns.VolumeGroup, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("GetVolumeGroup", path); err != nil {
+		return ns.VolumeGroup{}, err
+	}
+
+	if path == "" {
+		return ns.VolumeGroup{}, fmt.Errorf("VolumeGroup path is empty")
+	}
+	ds, err := p.getDataset(path, kindVolumeGroup)
+	if err != nil {
+		return ns.VolumeGroup{}, err
+	}
+
+	return p.volumeGroupState(ds), nil
+}
+
+// volumeGroupState returns volumeGroup as NexentaStor reports it, used space is a sum of its volumes
+func (p *Provider) volumeGroupState(ds *dataset) ns.VolumeGroup {
+	volumeGroup := ns.VolumeGroup{Path: ds.path, BytesAvailable: p.poolAvailable(poolName(ds.path))}
+	for _, volumeDataset := range p.datasets {
+		if parentPath(volumeDataset.path) == ds.path {
+			volumeGroup.BytesUsed += volumeDataset.bytesUsed
+		}
+	}
+	return volumeGroup
+}
+
+// GetVolumeGroups returns volumeGroups of the pool sorted by path
+func (p *Provider) GetVolumeGroups(pool string) ([]ns.VolumeGroup, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("GetVolumeGroups", pool); err != nil {
+		return nil, err
+	}
+
+	if pool == "" {
+		return nil, fmt.Errorf("Pool name is required")
+	}
+
+	volumeGroups := []ns.VolumeGroup{}
+	for _, ds := range p.datasets {
+		if ds.kind != kindVolumeGroup || poolName(ds.path) != pool {
+			continue
+		}
+		volumeGroups = append(volumeGroups, p.volumeGroupState(ds))
+	}
+	sort.Slice(volumeGroups, func(i, j int) bool { return volumeGroups[i].Path < volumeGroups[j].Path })
+
+	return volumeGroups, nil
+}
+
+// CreateVolumeGroup creates volumeGroup in a pool or filesystem
+func (p *Provider) CreateVolumeGroup(params ns.CreateVolumeGroupParams) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("CreateVolumeGroup", params); err != nil {
+		return err
+	}
+
+	if params.Path == "" {
+		return fmt.Errorf("Parameter 'CreateVolumeGroupParams.Path' is required")
+	}
+	if params.VolumeBlockSize != 0 {
+		if err := ns.ValidateVolumeBlockSize(params.VolumeBlockSize); err != nil {
+			return err
+		}
+	}
+	if err := p.checkNewDataset(params.Path, kindFilesystem); err != nil {
+		return err
+	}
+
+	p.datasets[params.Path] = &dataset{
+		kind:            kindVolumeGroup,
+		path:            params.Path,
+		volumeBlockSize: params.VolumeBlockSize,
+	}
+	return nil
+}
+
+// DestroyVolumeGroup destroys volumeGroup, non empty one is destroyed with Recursive parameter only
+func (p *Provider) DestroyVolumeGroup(path string, params ns.DestroyVolumeGroupParams) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("DestroyVolumeGroup", path, params); err != nil {
+		return err
+	}
+
+	if path == "" {
+		return fmt.Errorf("VolumeGroup path is required")
+	}
+	ds, err := p.getDataset(path, kindVolumeGroup)
+	if err != nil {
+		return err
+	}
+
+	if params.Recursive {
+		if err := p.mappedVolumeError(path); err != nil {
+			return err
+		}
+		// check all volumes first, so nothing is destroyed if one of them cannot be
+		for _, volumePath := range p.children(path) {
+			for _, s := range p.datasetSnapshots(volumePath) {
+				if len(s.Clones) > 0 {
+					return alreadyExistError(
+						"Volume '%s' has dependent clones: %s", volumePath, strings.Join(s.Clones, ", "))
+				}
+			}
+		}
+		for _, volumePath := range p.children(path) {
+			if err := p.destroyDataset(p.datasets[volumePath], true); err != nil {
+				return err
+			}
+		}
+	}
+
+	return p.destroyDataset(ds, params.Recursive)
+}
+
+// GetVolumeSnapshots returns snapshots of the volume
+func (p *Provider) GetVolumeSnapshots(path string) ([]ns.Snapshot, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("GetVolumeSnapshots", path); err != nil {
+		return nil, err
+	}
+
+	if path == "" {
+		return []ns.Snapshot{}, fmt.Errorf("Volume path is empty")
+	}
+	return p.listSnapshots(path, false, ns.ListOptions{})
+}
+
+// CloneVolumeSnapshot clones volume snapshot to a new volume and resizes it if a new size is set
+func (p *Provider) CloneVolumeSnapshot(path string, params ns.CloneVolumeSnapshotParams) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("CloneVolumeSnapshot", path, params); err != nil {
+		return err
+	}
+
+	if path == "" {
+		return fmt.Errorf("Snapshot path is required")
+	}
+	if params.TargetPath == "" {
+		return fmt.Errorf("Parameter 'CloneVolumeSnapshotParams.TargetPath' is required")
+	}
+
+	ds, err := p.clone(path, params.TargetPath)
+	if err != nil {
+		return err
+	}
+	if ds.kind != kindVolume {
+		delete(p.datasets, ds.path)
+		p.unlinkClone(ds)
+		return badArgError("Snapshot '%s' is not a volume snapshot", path)
+	}
+
+	// clone shares snapshot data, nothing is reserved until it's resized
+	ds.volume.ReservationSize = 0
+	if params.VolumeSize == 0 || params.VolumeSize == ds.volume.VolumeSize {
+		return nil
+	}
+
+	if params.VolumeSize < ds.volume.VolumeSize {
+		err = fmt.Errorf("requested size %d is smaller than snapshot size %d", params.VolumeSize, ds.volume.VolumeSize)
+	} else {
+		err = p.setVolumeSize(ds, params.VolumeSize)
+	}
+	if err != nil {
+		delete(p.datasets, ds.path)
+		p.unlinkClone(ds)
+		return fmt.Errorf(
+			"Cannot resize clone '%s' of snapshot '%s': %s, the clone is destroyed", ds.path, path, err)
+	}
+	return nil
+}
+
+// PromoteVolume makes cloned volume independent of its origin snapshot
+func (p *Provider) PromoteVolume(path string) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("PromoteVolume", path); err != nil {
+		return err
+	}
+
+	if path == "" {
+		return fmt.Errorf("Volume path is required")
+	}
+	ds, err := p.getDataset(path, kindVolume)
+	if err != nil {
+		return err
+	}
+	return p.promote(ds)
+}
+
+// GetVolumesWithStartingToken returns volumes after the one with starting token path,
+// next token is returned if limit is reached
+func (p *Provider) GetVolumesWithStartingToken(parent string, startingToken string, limit int) (
+	[]ns.Volume,
+	string,
+	error,
+) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("GetVolumesWithStartingToken", parent, startingToken, limit); err != nil {
+		return nil, "", err
+	}
+
+	volumes, err := p.listVolumes(parent, ns.ListOptions{})
+	if err != nil {
+		return nil, "", err
+	}
+
+	paths := make([]string, len(volumes))
+	for i, volume := range volumes {
+		paths[i] = volume.Path
+	}
+	from, to, nextToken := afterToken(paths, startingToken, limit)
+
+	return volumes[from:to], nextToken, nil
+}
+
+// GetVolumeIterator returns iterator over volumes of the volumeGroup, pages are loaded from the current state
+func (p *Provider) GetVolumeIterator(parent string, params ns.IteratorParams) *ns.VolumeIterator {
+	p.mux.Lock()
+	p.record("GetVolumeIterator", parent, params)
+	p.mux.Unlock()
+
+	return ns.NewVolumeIterator(params, func(limit, offset int) ([]ns.Volume, error) {
+		p.mux.Lock()
+		defer p.mux.Unlock()
+
+		volumes, err := p.listVolumes(parent, params.ListOptions)
+		if err != nil {
+			return nil, err
+		}
+		from, to := page(len(volumes), limit, offset)
+		return volumes[from:to], nil
+	})
+}
+
+// ListVolumes returns volumes of the volumeGroup matching the options, Fields option is ignored
+func (p *Provider) ListVolumes(parent string, options ns.ListOptions) ([]ns.Volume, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if err := p.record("ListVolumes", parent, options); err != nil {
+		return nil, err
+	}
+	return p.listVolumes(parent, options)
+}
@@ -0,0 +1,1059 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/Nexenta/go-nexentastor/pkg/ns (interfaces: ProviderInterface)
+//
+// Generated by this command:
+//
+//	mockgen -destination=nsmock/provider.go -package=nsmock . ProviderInterface
+//
+
+// Package nsmock is a generated GoMock package.
+package nsmock
+
+import (
+	reflect "reflect"
+
+	ns "github.com/Nexenta/go-nexentastor/pkg/ns"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockProviderInterface is a mock of ProviderInterface interface.
+type MockProviderInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockProviderInterfaceMockRecorder
+	isgomock struct{}
+}
+
+// MockProviderInterfaceMockRecorder is the mock recorder for MockProviderInterface.
+type MockProviderInterfaceMockRecorder struct {
+	mock *MockProviderInterface
+}
+
+// NewMockProviderInterface creates a new mock instance.
+func NewMockProviderInterface(ctrl *gomock.Controller) *MockProviderInterface {
+	mock := &MockProviderInterface{ctrl: ctrl}
+	mock.recorder = &MockProviderInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockProviderInterface) EXPECT() *MockProviderInterfaceMockRecorder {
+	return m.recorder
+}
+
+// AcknowledgeAlert mocks base method.
+func (m *MockProviderInterface) AcknowledgeAlert(source ns.AlertSource, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AcknowledgeAlert", source, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AcknowledgeAlert indicates an expected call of AcknowledgeAlert.
+func (mr *MockProviderInterfaceMockRecorder) AcknowledgeAlert(source, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcknowledgeAlert", reflect.TypeOf((*MockProviderInterface)(nil).AcknowledgeAlert), source, id)
+}
+
+// ClearAlert mocks base method.
+func (m *MockProviderInterface) ClearAlert(source ns.AlertSource, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClearAlert", source, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ClearAlert indicates an expected call of ClearAlert.
+func (mr *MockProviderInterfaceMockRecorder) ClearAlert(source, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearAlert", reflect.TypeOf((*MockProviderInterface)(nil).ClearAlert), source, id)
+}
+
+// CloneSnapshot mocks base method.
+func (m *MockProviderInterface) CloneSnapshot(path string, params ns.CloneSnapshotParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloneSnapshot", path, params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloneSnapshot indicates an expected call of CloneSnapshot.
+func (mr *MockProviderInterfaceMockRecorder) CloneSnapshot(path, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloneSnapshot", reflect.TypeOf((*MockProviderInterface)(nil).CloneSnapshot), path, params)
+}
+
+// CloneVolumeSnapshot mocks base method.
+func (m *MockProviderInterface) CloneVolumeSnapshot(path string, params ns.CloneVolumeSnapshotParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloneVolumeSnapshot", path, params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloneVolumeSnapshot indicates an expected call of CloneVolumeSnapshot.
+func (mr *MockProviderInterfaceMockRecorder) CloneVolumeSnapshot(path, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloneVolumeSnapshot", reflect.TypeOf((*MockProviderInterface)(nil).CloneVolumeSnapshot), path, params)
+}
+
+// CreateFilesystem mocks base method.
+func (m *MockProviderInterface) CreateFilesystem(params ns.CreateFilesystemParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateFilesystem", params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateFilesystem indicates an expected call of CreateFilesystem.
+func (mr *MockProviderInterfaceMockRecorder) CreateFilesystem(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateFilesystem", reflect.TypeOf((*MockProviderInterface)(nil).CreateFilesystem), params)
+}
+
+// CreateISCSITarget mocks base method.
+func (m *MockProviderInterface) CreateISCSITarget(params ns.CreateISCSITargetParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateISCSITarget", params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateISCSITarget indicates an expected call of CreateISCSITarget.
+func (mr *MockProviderInterfaceMockRecorder) CreateISCSITarget(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateISCSITarget", reflect.TypeOf((*MockProviderInterface)(nil).CreateISCSITarget), params)
+}
+
+// CreateLunMapping mocks base method.
+func (m *MockProviderInterface) CreateLunMapping(params ns.CreateLunMappingParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateLunMapping", params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateLunMapping indicates an expected call of CreateLunMapping.
+func (mr *MockProviderInterfaceMockRecorder) CreateLunMapping(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLunMapping", reflect.TypeOf((*MockProviderInterface)(nil).CreateLunMapping), params)
+}
+
+// CreateNfsShare mocks base method.
+func (m *MockProviderInterface) CreateNfsShare(params ns.CreateNfsShareParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateNfsShare", params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateNfsShare indicates an expected call of CreateNfsShare.
+func (mr *MockProviderInterfaceMockRecorder) CreateNfsShare(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateNfsShare", reflect.TypeOf((*MockProviderInterface)(nil).CreateNfsShare), params)
+}
+
+// CreateSmbShare mocks base method.
+func (m *MockProviderInterface) CreateSmbShare(params ns.CreateSmbShareParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSmbShare", params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateSmbShare indicates an expected call of CreateSmbShare.
+func (mr *MockProviderInterfaceMockRecorder) CreateSmbShare(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSmbShare", reflect.TypeOf((*MockProviderInterface)(nil).CreateSmbShare), params)
+}
+
+// CreateSnapshot mocks base method.
+func (m *MockProviderInterface) CreateSnapshot(params ns.CreateSnapshotParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSnapshot", params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateSnapshot indicates an expected call of CreateSnapshot.
+func (mr *MockProviderInterfaceMockRecorder) CreateSnapshot(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSnapshot", reflect.TypeOf((*MockProviderInterface)(nil).CreateSnapshot), params)
+}
+
+// CreateUpdateFCHostGroup mocks base method.
+func (m *MockProviderInterface) CreateUpdateFCHostGroup(params ns.CreateHostGroupParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUpdateFCHostGroup", params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateUpdateFCHostGroup indicates an expected call of CreateUpdateFCHostGroup.
+func (mr *MockProviderInterfaceMockRecorder) CreateUpdateFCHostGroup(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUpdateFCHostGroup", reflect.TypeOf((*MockProviderInterface)(nil).CreateUpdateFCHostGroup), params)
+}
+
+// CreateUpdateFCTargetGroup mocks base method.
+func (m *MockProviderInterface) CreateUpdateFCTargetGroup(params ns.CreateTargetGroupParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUpdateFCTargetGroup", params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateUpdateFCTargetGroup indicates an expected call of CreateUpdateFCTargetGroup.
+func (mr *MockProviderInterfaceMockRecorder) CreateUpdateFCTargetGroup(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUpdateFCTargetGroup", reflect.TypeOf((*MockProviderInterface)(nil).CreateUpdateFCTargetGroup), params)
+}
+
+// CreateUpdateHostGroup mocks base method.
+func (m *MockProviderInterface) CreateUpdateHostGroup(params ns.CreateHostGroupParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUpdateHostGroup", params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateUpdateHostGroup indicates an expected call of CreateUpdateHostGroup.
+func (mr *MockProviderInterfaceMockRecorder) CreateUpdateHostGroup(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUpdateHostGroup", reflect.TypeOf((*MockProviderInterface)(nil).CreateUpdateHostGroup), params)
+}
+
+// CreateUpdateTargetGroup mocks base method.
+func (m *MockProviderInterface) CreateUpdateTargetGroup(params ns.CreateTargetGroupParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUpdateTargetGroup", params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateUpdateTargetGroup indicates an expected call of CreateUpdateTargetGroup.
+func (mr *MockProviderInterfaceMockRecorder) CreateUpdateTargetGroup(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUpdateTargetGroup", reflect.TypeOf((*MockProviderInterface)(nil).CreateUpdateTargetGroup), params)
+}
+
+// CreateVolume mocks base method.
+func (m *MockProviderInterface) CreateVolume(params ns.CreateVolumeParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateVolume", params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateVolume indicates an expected call of CreateVolume.
+func (mr *MockProviderInterfaceMockRecorder) CreateVolume(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateVolume", reflect.TypeOf((*MockProviderInterface)(nil).CreateVolume), params)
+}
+
+// CreateVolumeGroup mocks base method.
+func (m *MockProviderInterface) CreateVolumeGroup(params ns.CreateVolumeGroupParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateVolumeGroup", params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateVolumeGroup indicates an expected call of CreateVolumeGroup.
+func (mr *MockProviderInterfaceMockRecorder) CreateVolumeGroup(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateVolumeGroup", reflect.TypeOf((*MockProviderInterface)(nil).CreateVolumeGroup), params)
+}
+
+// DeleteFilesystemACLRule mocks base method.
+func (m *MockProviderInterface) DeleteFilesystemACLRule(path string, index int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteFilesystemACLRule", path, index)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteFilesystemACLRule indicates an expected call of DeleteFilesystemACLRule.
+func (mr *MockProviderInterfaceMockRecorder) DeleteFilesystemACLRule(path, index any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFilesystemACLRule", reflect.TypeOf((*MockProviderInterface)(nil).DeleteFilesystemACLRule), path, index)
+}
+
+// DeleteNfsShare mocks base method.
+func (m *MockProviderInterface) DeleteNfsShare(path string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteNfsShare", path)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteNfsShare indicates an expected call of DeleteNfsShare.
+func (mr *MockProviderInterfaceMockRecorder) DeleteNfsShare(path any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteNfsShare", reflect.TypeOf((*MockProviderInterface)(nil).DeleteNfsShare), path)
+}
+
+// DeleteSmbShare mocks base method.
+func (m *MockProviderInterface) DeleteSmbShare(path string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSmbShare", path)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSmbShare indicates an expected call of DeleteSmbShare.
+func (mr *MockProviderInterfaceMockRecorder) DeleteSmbShare(path any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSmbShare", reflect.TypeOf((*MockProviderInterface)(nil).DeleteSmbShare), path)
+}
+
+// DestroyFilesystem mocks base method.
+func (m *MockProviderInterface) DestroyFilesystem(path string, params ns.DestroyFilesystemParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DestroyFilesystem", path, params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DestroyFilesystem indicates an expected call of DestroyFilesystem.
+func (mr *MockProviderInterfaceMockRecorder) DestroyFilesystem(path, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DestroyFilesystem", reflect.TypeOf((*MockProviderInterface)(nil).DestroyFilesystem), path, params)
+}
+
+// DestroyLunMapping mocks base method.
+func (m *MockProviderInterface) DestroyLunMapping(id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DestroyLunMapping", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DestroyLunMapping indicates an expected call of DestroyLunMapping.
+func (mr *MockProviderInterfaceMockRecorder) DestroyLunMapping(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DestroyLunMapping", reflect.TypeOf((*MockProviderInterface)(nil).DestroyLunMapping), id)
+}
+
+// DestroySnapshot mocks base method.
+func (m *MockProviderInterface) DestroySnapshot(path string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DestroySnapshot", path)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DestroySnapshot indicates an expected call of DestroySnapshot.
+func (mr *MockProviderInterfaceMockRecorder) DestroySnapshot(path any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DestroySnapshot", reflect.TypeOf((*MockProviderInterface)(nil).DestroySnapshot), path)
+}
+
+// DestroyVolume mocks base method.
+func (m *MockProviderInterface) DestroyVolume(path string, params ns.DestroyVolumeParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DestroyVolume", path, params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DestroyVolume indicates an expected call of DestroyVolume.
+func (mr *MockProviderInterfaceMockRecorder) DestroyVolume(path, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DestroyVolume", reflect.TypeOf((*MockProviderInterface)(nil).DestroyVolume), path, params)
+}
+
+// DestroyVolumeGroup mocks base method.
+func (m *MockProviderInterface) DestroyVolumeGroup(path string, params ns.DestroyVolumeGroupParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DestroyVolumeGroup", path, params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DestroyVolumeGroup indicates an expected call of DestroyVolumeGroup.
+func (mr *MockProviderInterfaceMockRecorder) DestroyVolumeGroup(path, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DestroyVolumeGroup", reflect.TypeOf((*MockProviderInterface)(nil).DestroyVolumeGroup), path, params)
+}
+
+// GetDatasetStats mocks base method.
+func (m *MockProviderInterface) GetDatasetStats(path string, query ns.AnalyticsQuery) (ns.IOTimeSeries, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDatasetStats", path, query)
+	ret0, _ := ret[0].(ns.IOTimeSeries)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDatasetStats indicates an expected call of GetDatasetStats.
+func (mr *MockProviderInterfaceMockRecorder) GetDatasetStats(path, query any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDatasetStats", reflect.TypeOf((*MockProviderInterface)(nil).GetDatasetStats), path, query)
+}
+
+// GetFCTargetPorts mocks base method.
+func (m *MockProviderInterface) GetFCTargetPorts() ([]ns.FCPort, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFCTargetPorts")
+	ret0, _ := ret[0].([]ns.FCPort)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFCTargetPorts indicates an expected call of GetFCTargetPorts.
+func (mr *MockProviderInterfaceMockRecorder) GetFCTargetPorts() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFCTargetPorts", reflect.TypeOf((*MockProviderInterface)(nil).GetFCTargetPorts))
+}
+
+// GetFilesystem mocks base method.
+func (m *MockProviderInterface) GetFilesystem(path string) (ns.Filesystem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFilesystem", path)
+	ret0, _ := ret[0].(ns.Filesystem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFilesystem indicates an expected call of GetFilesystem.
+func (mr *MockProviderInterfaceMockRecorder) GetFilesystem(path any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFilesystem", reflect.TypeOf((*MockProviderInterface)(nil).GetFilesystem), path)
+}
+
+// GetFilesystemACL mocks base method.
+func (m *MockProviderInterface) GetFilesystemACL(path string) ([]ns.ACLRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFilesystemACL", path)
+	ret0, _ := ret[0].([]ns.ACLRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFilesystemACL indicates an expected call of GetFilesystemACL.
+func (mr *MockProviderInterfaceMockRecorder) GetFilesystemACL(path any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFilesystemACL", reflect.TypeOf((*MockProviderInterface)(nil).GetFilesystemACL), path)
+}
+
+// GetFilesystemAvailableCapacity mocks base method.
+func (m *MockProviderInterface) GetFilesystemAvailableCapacity(path string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFilesystemAvailableCapacity", path)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFilesystemAvailableCapacity indicates an expected call of GetFilesystemAvailableCapacity.
+func (mr *MockProviderInterfaceMockRecorder) GetFilesystemAvailableCapacity(path any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFilesystemAvailableCapacity", reflect.TypeOf((*MockProviderInterface)(nil).GetFilesystemAvailableCapacity), path)
+}
+
+// GetFilesystemIterator mocks base method.
+func (m *MockProviderInterface) GetFilesystemIterator(parent string, params ns.IteratorParams) *ns.FilesystemIterator {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFilesystemIterator", parent, params)
+	ret0, _ := ret[0].(*ns.FilesystemIterator)
+	return ret0
+}
+
+// GetFilesystemIterator indicates an expected call of GetFilesystemIterator.
+func (mr *MockProviderInterfaceMockRecorder) GetFilesystemIterator(parent, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFilesystemIterator", reflect.TypeOf((*MockProviderInterface)(nil).GetFilesystemIterator), parent, params)
+}
+
+// GetFilesystems mocks base method.
+func (m *MockProviderInterface) GetFilesystems(parent string) ([]ns.Filesystem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFilesystems", parent)
+	ret0, _ := ret[0].([]ns.Filesystem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFilesystems indicates an expected call of GetFilesystems.
+func (mr *MockProviderInterfaceMockRecorder) GetFilesystems(parent any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFilesystems", reflect.TypeOf((*MockProviderInterface)(nil).GetFilesystems), parent)
+}
+
+// GetFilesystemsSlice mocks base method.
+func (m *MockProviderInterface) GetFilesystemsSlice(parent string, limit, offset int) ([]ns.Filesystem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFilesystemsSlice", parent, limit, offset)
+	ret0, _ := ret[0].([]ns.Filesystem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFilesystemsSlice indicates an expected call of GetFilesystemsSlice.
+func (mr *MockProviderInterfaceMockRecorder) GetFilesystemsSlice(parent, limit, offset any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFilesystemsSlice", reflect.TypeOf((*MockProviderInterface)(nil).GetFilesystemsSlice), parent, limit, offset)
+}
+
+// GetFilesystemsWithStartingToken mocks base method.
+func (m *MockProviderInterface) GetFilesystemsWithStartingToken(parent, startingToken string, limit int) ([]ns.Filesystem, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFilesystemsWithStartingToken", parent, startingToken, limit)
+	ret0, _ := ret[0].([]ns.Filesystem)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetFilesystemsWithStartingToken indicates an expected call of GetFilesystemsWithStartingToken.
+func (mr *MockProviderInterfaceMockRecorder) GetFilesystemsWithStartingToken(parent, startingToken, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFilesystemsWithStartingToken", reflect.TypeOf((*MockProviderInterface)(nil).GetFilesystemsWithStartingToken), parent, startingToken, limit)
+}
+
+// GetHostGroup mocks base method.
+func (m *MockProviderInterface) GetHostGroup(name string) (ns.HostGroup, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHostGroup", name)
+	ret0, _ := ret[0].(ns.HostGroup)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHostGroup indicates an expected call of GetHostGroup.
+func (mr *MockProviderInterfaceMockRecorder) GetHostGroup(name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHostGroup", reflect.TypeOf((*MockProviderInterface)(nil).GetHostGroup), name)
+}
+
+// GetLicense mocks base method.
+func (m *MockProviderInterface) GetLicense() (ns.License, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLicense")
+	ret0, _ := ret[0].(ns.License)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLicense indicates an expected call of GetLicense.
+func (mr *MockProviderInterfaceMockRecorder) GetLicense() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLicense", reflect.TypeOf((*MockProviderInterface)(nil).GetLicense))
+}
+
+// GetLunMapping mocks base method.
+func (m *MockProviderInterface) GetLunMapping(path string) (ns.LunMapping, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLunMapping", path)
+	ret0, _ := ret[0].(ns.LunMapping)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLunMapping indicates an expected call of GetLunMapping.
+func (mr *MockProviderInterfaceMockRecorder) GetLunMapping(path any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLunMapping", reflect.TypeOf((*MockProviderInterface)(nil).GetLunMapping), path)
+}
+
+// GetNICStats mocks base method.
+func (m *MockProviderInterface) GetNICStats(name string, query ns.AnalyticsQuery) (ns.NICTimeSeries, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNICStats", name, query)
+	ret0, _ := ret[0].(ns.NICTimeSeries)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNICStats indicates an expected call of GetNICStats.
+func (mr *MockProviderInterfaceMockRecorder) GetNICStats(name, query any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNICStats", reflect.TypeOf((*MockProviderInterface)(nil).GetNICStats), name, query)
+}
+
+// GetNextFreeLun mocks base method.
+func (m *MockProviderInterface) GetNextFreeLun(hostGroup string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNextFreeLun", hostGroup)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNextFreeLun indicates an expected call of GetNextFreeLun.
+func (mr *MockProviderInterfaceMockRecorder) GetNextFreeLun(hostGroup any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNextFreeLun", reflect.TypeOf((*MockProviderInterface)(nil).GetNextFreeLun), hostGroup)
+}
+
+// GetNfsShare mocks base method.
+func (m *MockProviderInterface) GetNfsShare(path string) (ns.NfsShare, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNfsShare", path)
+	ret0, _ := ret[0].(ns.NfsShare)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNfsShare indicates an expected call of GetNfsShare.
+func (mr *MockProviderInterfaceMockRecorder) GetNfsShare(path any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNfsShare", reflect.TypeOf((*MockProviderInterface)(nil).GetNfsShare), path)
+}
+
+// GetPoolStats mocks base method.
+func (m *MockProviderInterface) GetPoolStats(pool string, query ns.AnalyticsQuery) (ns.IOTimeSeries, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPoolStats", pool, query)
+	ret0, _ := ret[0].(ns.IOTimeSeries)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPoolStats indicates an expected call of GetPoolStats.
+func (mr *MockProviderInterfaceMockRecorder) GetPoolStats(pool, query any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPoolStats", reflect.TypeOf((*MockProviderInterface)(nil).GetPoolStats), pool, query)
+}
+
+// GetPools mocks base method.
+func (m *MockProviderInterface) GetPools() ([]ns.Pool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPools")
+	ret0, _ := ret[0].([]ns.Pool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPools indicates an expected call of GetPools.
+func (mr *MockProviderInterfaceMockRecorder) GetPools() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPools", reflect.TypeOf((*MockProviderInterface)(nil).GetPools))
+}
+
+// GetRSFClusters mocks base method.
+func (m *MockProviderInterface) GetRSFClusters() ([]ns.RSFCluster, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRSFClusters")
+	ret0, _ := ret[0].([]ns.RSFCluster)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRSFClusters indicates an expected call of GetRSFClusters.
+func (mr *MockProviderInterfaceMockRecorder) GetRSFClusters() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRSFClusters", reflect.TypeOf((*MockProviderInterface)(nil).GetRSFClusters))
+}
+
+// GetSmbShareName mocks base method.
+func (m *MockProviderInterface) GetSmbShareName(path string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSmbShareName", path)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSmbShareName indicates an expected call of GetSmbShareName.
+func (mr *MockProviderInterfaceMockRecorder) GetSmbShareName(path any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSmbShareName", reflect.TypeOf((*MockProviderInterface)(nil).GetSmbShareName), path)
+}
+
+// GetSnapshot mocks base method.
+func (m *MockProviderInterface) GetSnapshot(path string) (ns.Snapshot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSnapshot", path)
+	ret0, _ := ret[0].(ns.Snapshot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSnapshot indicates an expected call of GetSnapshot.
+func (mr *MockProviderInterfaceMockRecorder) GetSnapshot(path any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSnapshot", reflect.TypeOf((*MockProviderInterface)(nil).GetSnapshot), path)
+}
+
+// GetSnapshotIterator mocks base method.
+func (m *MockProviderInterface) GetSnapshotIterator(volumePath string, recursive bool, params ns.IteratorParams) *ns.SnapshotIterator {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSnapshotIterator", volumePath, recursive, params)
+	ret0, _ := ret[0].(*ns.SnapshotIterator)
+	return ret0
+}
+
+// GetSnapshotIterator indicates an expected call of GetSnapshotIterator.
+func (mr *MockProviderInterfaceMockRecorder) GetSnapshotIterator(volumePath, recursive, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSnapshotIterator", reflect.TypeOf((*MockProviderInterface)(nil).GetSnapshotIterator), volumePath, recursive, params)
+}
+
+// GetSnapshots mocks base method.
+func (m *MockProviderInterface) GetSnapshots(volumePath string, recursive bool) ([]ns.Snapshot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSnapshots", volumePath, recursive)
+	ret0, _ := ret[0].([]ns.Snapshot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSnapshots indicates an expected call of GetSnapshots.
+func (mr *MockProviderInterfaceMockRecorder) GetSnapshots(volumePath, recursive any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSnapshots", reflect.TypeOf((*MockProviderInterface)(nil).GetSnapshots), volumePath, recursive)
+}
+
+// GetSystemInfo mocks base method.
+func (m *MockProviderInterface) GetSystemInfo() (ns.SystemInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSystemInfo")
+	ret0, _ := ret[0].(ns.SystemInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSystemInfo indicates an expected call of GetSystemInfo.
+func (mr *MockProviderInterfaceMockRecorder) GetSystemInfo() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSystemInfo", reflect.TypeOf((*MockProviderInterface)(nil).GetSystemInfo))
+}
+
+// GetVdevStats mocks base method.
+func (m *MockProviderInterface) GetVdevStats(pool, vdev string, query ns.AnalyticsQuery) (ns.IOTimeSeries, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVdevStats", pool, vdev, query)
+	ret0, _ := ret[0].(ns.IOTimeSeries)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVdevStats indicates an expected call of GetVdevStats.
+func (mr *MockProviderInterfaceMockRecorder) GetVdevStats(pool, vdev, query any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVdevStats", reflect.TypeOf((*MockProviderInterface)(nil).GetVdevStats), pool, vdev, query)
+}
+
+// GetVolume mocks base method.
+func (m *MockProviderInterface) GetVolume(path string) (ns.Volume, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVolume", path)
+	ret0, _ := ret[0].(ns.Volume)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVolume indicates an expected call of GetVolume.
+func (mr *MockProviderInterfaceMockRecorder) GetVolume(path any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVolume", reflect.TypeOf((*MockProviderInterface)(nil).GetVolume), path)
+}
+
+// GetVolumeGroup mocks base method.
+func (m *MockProviderInterface) GetVolumeGroup(path string) (ns.VolumeGroup, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVolumeGroup", path)
+	ret0, _ := ret[0].(ns.VolumeGroup)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVolumeGroup indicates an expected call of GetVolumeGroup.
+func (mr *MockProviderInterfaceMockRecorder) GetVolumeGroup(path any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVolumeGroup", reflect.TypeOf((*MockProviderInterface)(nil).GetVolumeGroup), path)
+}
+
+// GetVolumeGroups mocks base method.
+func (m *MockProviderInterface) GetVolumeGroups(pool string) ([]ns.VolumeGroup, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVolumeGroups", pool)
+	ret0, _ := ret[0].([]ns.VolumeGroup)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVolumeGroups indicates an expected call of GetVolumeGroups.
+func (mr *MockProviderInterfaceMockRecorder) GetVolumeGroups(pool any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVolumeGroups", reflect.TypeOf((*MockProviderInterface)(nil).GetVolumeGroups), pool)
+}
+
+// GetVolumeIterator mocks base method.
+func (m *MockProviderInterface) GetVolumeIterator(parent string, params ns.IteratorParams) *ns.VolumeIterator {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVolumeIterator", parent, params)
+	ret0, _ := ret[0].(*ns.VolumeIterator)
+	return ret0
+}
+
+// GetVolumeIterator indicates an expected call of GetVolumeIterator.
+func (mr *MockProviderInterfaceMockRecorder) GetVolumeIterator(parent, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVolumeIterator", reflect.TypeOf((*MockProviderInterface)(nil).GetVolumeIterator), parent, params)
+}
+
+// GetVolumeSnapshots mocks base method.
+func (m *MockProviderInterface) GetVolumeSnapshots(path string) ([]ns.Snapshot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVolumeSnapshots", path)
+	ret0, _ := ret[0].([]ns.Snapshot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVolumeSnapshots indicates an expected call of GetVolumeSnapshots.
+func (mr *MockProviderInterfaceMockRecorder) GetVolumeSnapshots(path any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVolumeSnapshots", reflect.TypeOf((*MockProviderInterface)(nil).GetVolumeSnapshots), path)
+}
+
+// GetVolumes mocks base method.
+func (m *MockProviderInterface) GetVolumes(parent string) ([]ns.Volume, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVolumes", parent)
+	ret0, _ := ret[0].([]ns.Volume)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVolumes indicates an expected call of GetVolumes.
+func (mr *MockProviderInterfaceMockRecorder) GetVolumes(parent any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVolumes", reflect.TypeOf((*MockProviderInterface)(nil).GetVolumes), parent)
+}
+
+// GetVolumesWithStartingToken mocks base method.
+func (m *MockProviderInterface) GetVolumesWithStartingToken(parent, startingToken string, limit int) ([]ns.Volume, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVolumesWithStartingToken", parent, startingToken, limit)
+	ret0, _ := ret[0].([]ns.Volume)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetVolumesWithStartingToken indicates an expected call of GetVolumesWithStartingToken.
+func (mr *MockProviderInterfaceMockRecorder) GetVolumesWithStartingToken(parent, startingToken, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVolumesWithStartingToken", reflect.TypeOf((*MockProviderInterface)(nil).GetVolumesWithStartingToken), parent, startingToken, limit)
+}
+
+// IsJobDone mocks base method.
+func (m *MockProviderInterface) IsJobDone(jobID string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsJobDone", jobID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsJobDone indicates an expected call of IsJobDone.
+func (mr *MockProviderInterfaceMockRecorder) IsJobDone(jobID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsJobDone", reflect.TypeOf((*MockProviderInterface)(nil).IsJobDone), jobID)
+}
+
+// ListAlerts mocks base method.
+func (m *MockProviderInterface) ListAlerts(params ns.ListAlertsParams) ([]ns.Alert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAlerts", params)
+	ret0, _ := ret[0].([]ns.Alert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAlerts indicates an expected call of ListAlerts.
+func (mr *MockProviderInterfaceMockRecorder) ListAlerts(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAlerts", reflect.TypeOf((*MockProviderInterface)(nil).ListAlerts), params)
+}
+
+// ListFaults mocks base method.
+func (m *MockProviderInterface) ListFaults(params ns.ListAlertsParams) ([]ns.Alert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListFaults", params)
+	ret0, _ := ret[0].([]ns.Alert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListFaults indicates an expected call of ListFaults.
+func (mr *MockProviderInterfaceMockRecorder) ListFaults(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFaults", reflect.TypeOf((*MockProviderInterface)(nil).ListFaults), params)
+}
+
+// ListFilesystems mocks base method.
+func (m *MockProviderInterface) ListFilesystems(parent string, options ns.ListOptions) ([]ns.Filesystem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListFilesystems", parent, options)
+	ret0, _ := ret[0].([]ns.Filesystem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListFilesystems indicates an expected call of ListFilesystems.
+func (mr *MockProviderInterfaceMockRecorder) ListFilesystems(parent, options any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFilesystems", reflect.TypeOf((*MockProviderInterface)(nil).ListFilesystems), parent, options)
+}
+
+// ListLunMappings mocks base method.
+func (m *MockProviderInterface) ListLunMappings(params ns.ListLunMappingsParams) ([]ns.LunMapping, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListLunMappings", params)
+	ret0, _ := ret[0].([]ns.LunMapping)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListLunMappings indicates an expected call of ListLunMappings.
+func (mr *MockProviderInterfaceMockRecorder) ListLunMappings(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLunMappings", reflect.TypeOf((*MockProviderInterface)(nil).ListLunMappings), params)
+}
+
+// ListSnapshots mocks base method.
+func (m *MockProviderInterface) ListSnapshots(volumePath string, options ns.ListOptions) ([]ns.Snapshot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSnapshots", volumePath, options)
+	ret0, _ := ret[0].([]ns.Snapshot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSnapshots indicates an expected call of ListSnapshots.
+func (mr *MockProviderInterfaceMockRecorder) ListSnapshots(volumePath, options any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSnapshots", reflect.TypeOf((*MockProviderInterface)(nil).ListSnapshots), volumePath, options)
+}
+
+// ListVolumes mocks base method.
+func (m *MockProviderInterface) ListVolumes(parent string, options ns.ListOptions) ([]ns.Volume, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListVolumes", parent, options)
+	ret0, _ := ret[0].([]ns.Volume)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListVolumes indicates an expected call of ListVolumes.
+func (mr *MockProviderInterfaceMockRecorder) ListVolumes(parent, options any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListVolumes", reflect.TypeOf((*MockProviderInterface)(nil).ListVolumes), parent, options)
+}
+
+// LogIn mocks base method.
+func (m *MockProviderInterface) LogIn() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LogIn")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LogIn indicates an expected call of LogIn.
+func (mr *MockProviderInterfaceMockRecorder) LogIn() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LogIn", reflect.TypeOf((*MockProviderInterface)(nil).LogIn))
+}
+
+// PromoteFilesystem mocks base method.
+func (m *MockProviderInterface) PromoteFilesystem(path string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PromoteFilesystem", path)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PromoteFilesystem indicates an expected call of PromoteFilesystem.
+func (mr *MockProviderInterfaceMockRecorder) PromoteFilesystem(path any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PromoteFilesystem", reflect.TypeOf((*MockProviderInterface)(nil).PromoteFilesystem), path)
+}
+
+// PromoteVolume mocks base method.
+func (m *MockProviderInterface) PromoteVolume(path string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PromoteVolume", path)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PromoteVolume indicates an expected call of PromoteVolume.
+func (mr *MockProviderInterfaceMockRecorder) PromoteVolume(path any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PromoteVolume", reflect.TypeOf((*MockProviderInterface)(nil).PromoteVolume), path)
+}
+
+// ResizeVolume mocks base method.
+func (m *MockProviderInterface) ResizeVolume(path string, newSize int64, params ns.ResizeVolumeParams) (ns.ResizeVolumeResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResizeVolume", path, newSize, params)
+	ret0, _ := ret[0].(ns.ResizeVolumeResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResizeVolume indicates an expected call of ResizeVolume.
+func (mr *MockProviderInterfaceMockRecorder) ResizeVolume(path, newSize, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResizeVolume", reflect.TypeOf((*MockProviderInterface)(nil).ResizeVolume), path, newSize, params)
+}
+
+// SetFCPortMode mocks base method.
+func (m *MockProviderInterface) SetFCPortMode(wwpn string, mode ns.FCPortMode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetFCPortMode", wwpn, mode)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetFCPortMode indicates an expected call of SetFCPortMode.
+func (mr *MockProviderInterfaceMockRecorder) SetFCPortMode(wwpn, mode any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetFCPortMode", reflect.TypeOf((*MockProviderInterface)(nil).SetFCPortMode), wwpn, mode)
+}
+
+// SetFilesystemACL mocks base method.
+func (m *MockProviderInterface) SetFilesystemACL(path string, aclRuleSet ns.ACLRuleSet) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetFilesystemACL", path, aclRuleSet)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetFilesystemACL indicates an expected call of SetFilesystemACL.
+func (mr *MockProviderInterfaceMockRecorder) SetFilesystemACL(path, aclRuleSet any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetFilesystemACL", reflect.TypeOf((*MockProviderInterface)(nil).SetFilesystemACL), path, aclRuleSet)
+}
+
+// UpdateFilesystem mocks base method.
+func (m *MockProviderInterface) UpdateFilesystem(path string, params ns.UpdateFilesystemParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateFilesystem", path, params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateFilesystem indicates an expected call of UpdateFilesystem.
+func (mr *MockProviderInterfaceMockRecorder) UpdateFilesystem(path, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateFilesystem", reflect.TypeOf((*MockProviderInterface)(nil).UpdateFilesystem), path, params)
+}
+
+// UpdateVolume mocks base method.
+func (m *MockProviderInterface) UpdateVolume(path string, params ns.UpdateVolumeParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateVolume", path, params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateVolume indicates an expected call of UpdateVolume.
+func (mr *MockProviderInterfaceMockRecorder) UpdateVolume(path, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateVolume", reflect.TypeOf((*MockProviderInterface)(nil).UpdateVolume), path, params)
+}
@@ -1,6 +1,7 @@
 package ns
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -8,7 +9,11 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 
+	"github.com/Nexenta/go-nexentastor/pkg/logger"
+	"github.com/Nexenta/go-nexentastor/pkg/metrics"
 	"github.com/Nexenta/go-nexentastor/pkg/rest"
 )
 
@@ -17,10 +22,13 @@ const (
 	checkJobStatusTimeout  = 60 * time.Second
 )
 
+//go:generate mockgen -destination=nsmock/provider.go -package=nsmock . ProviderInterface
+
 // ProviderInterface - NexentaStor provider interface
 type ProviderInterface interface {
 	// system
 	LogIn() error
+	GetSystemInfo() (SystemInfo, error)
 	IsJobDone(jobID string) (bool, error)
 	GetLicense() (License, error)
 	GetRSFClusters() ([]RSFCluster, error)
@@ -33,14 +41,19 @@ type ProviderInterface interface {
 	UpdateFilesystem(path string, params UpdateFilesystemParams) error
 	DestroyFilesystem(path string, params DestroyFilesystemParams) error
 	SetFilesystemACL(path string, aclRuleSet ACLRuleSet) error
+	GetFilesystemACL(path string) ([]ACLRule, error)
+	DeleteFilesystemACLRule(path string, index int) error
 	GetFilesystem(path string) (Filesystem, error)
 	GetFilesystemAvailableCapacity(path string) (int64, error)
 	GetFilesystems(parent string) ([]Filesystem, error)
 	GetFilesystemsWithStartingToken(parent string, startingToken string, limit int) ([]Filesystem, string, error)
 	GetFilesystemsSlice(parent string, limit, offset int) ([]Filesystem, error)
+	GetFilesystemIterator(parent string, params IteratorParams) *FilesystemIterator
+	ListFilesystems(parent string, options ListOptions) ([]Filesystem, error)
 
 	// filesystems - nfs share
 	CreateNfsShare(params CreateNfsShareParams) error
+	GetNfsShare(path string) (NfsShare, error)
 	DeleteNfsShare(path string) error
 
 	// filesystems - smb share
@@ -53,6 +66,8 @@ type ProviderInterface interface {
 	DestroySnapshot(path string) error
 	GetSnapshot(path string) (Snapshot, error)
 	GetSnapshots(volumePath string, recursive bool) ([]Snapshot, error)
+	GetSnapshotIterator(volumePath string, recursive bool, params IteratorParams) *SnapshotIterator
+	ListSnapshots(volumePath string, options ListOptions) ([]Snapshot, error)
 	CloneSnapshot(path string, params CloneSnapshotParams) error
 	PromoteFilesystem(path string) error
 
@@ -61,31 +76,123 @@ type ProviderInterface interface {
 	GetVolume(path string) (Volume, error)
 	GetVolumes(parent string) ([]Volume, error)
 	UpdateVolume(path string, params UpdateVolumeParams) error
+	ResizeVolume(path string, newSize int64, params ResizeVolumeParams) (ResizeVolumeResult, error)
 	DestroyVolume(path string, params DestroyVolumeParams) error
 	GetVolumeGroup(path string) (VolumeGroup, error)
+	GetVolumeGroups(pool string) ([]VolumeGroup, error)
+	CreateVolumeGroup(params CreateVolumeGroupParams) error
+	DestroyVolumeGroup(path string, params DestroyVolumeGroupParams) error
+	GetVolumeSnapshots(path string) ([]Snapshot, error)
+	CloneVolumeSnapshot(path string, params CloneVolumeSnapshotParams) error
+	PromoteVolume(path string) error
 	GetVolumesWithStartingToken(parent string, startingToken string, limit int) ([]Volume, string, error)
+	GetVolumeIterator(parent string, params IteratorParams) *VolumeIterator
+	ListVolumes(parent string, options ListOptions) ([]Volume, error)
 
-	// iSCSI
+	// SAN
 	CreateLunMapping(params CreateLunMappingParams) error
 	GetLunMapping(path string) (LunMapping, error)
+	ListLunMappings(params ListLunMappingsParams) ([]LunMapping, error)
+	GetNextFreeLun(hostGroup string) (int, error)
 	DestroyLunMapping(id string) error
 	CreateISCSITarget(params CreateISCSITargetParams) error
 	CreateUpdateTargetGroup(params CreateTargetGroupParams) error
+	CreateUpdateHostGroup(params CreateHostGroupParams) error
+	GetHostGroup(name string) (HostGroup, error)
+
+	// Fibre Channel
+	GetFCTargetPorts() ([]FCPort, error)
+	SetFCPortMode(wwpn string, mode FCPortMode) error
+	CreateUpdateFCTargetGroup(params CreateTargetGroupParams) error
+	CreateUpdateFCHostGroup(params CreateHostGroupParams) error
+
+	// alerts
+	ListFaults(params ListAlertsParams) ([]Alert, error)
+	ListAlerts(params ListAlertsParams) ([]Alert, error)
+	AcknowledgeAlert(source AlertSource, id string) error
+	ClearAlert(source AlertSource, id string) error
+
+	// analytics
+	GetPoolStats(pool string, query AnalyticsQuery) (IOTimeSeries, error)
+	GetVdevStats(pool, vdev string, query AnalyticsQuery) (IOTimeSeries, error)
+	GetDatasetStats(path string, query AnalyticsQuery) (IOTimeSeries, error)
+	GetNICStats(name string, query AnalyticsQuery) (NICTimeSeries, error)
 }
 
 // Provider - NexentaStor API provider
 type Provider struct {
 	Address    string
 	Username   string
-	Password   string
+	Password   string `secret:"true"`
 	RestClient rest.ClientInterface
-	Log        *logrus.Entry
+	// Log - logrus entry with provider fields, nil if the provider is created with ProviderArgs.Logger only
+	Log *logrus.Entry
+
+	// writes provider logs, see logger()
+	log logger.Logger
+
+	// if set, async jobs started by requests are not awaited, but collected
+	jobCollector *asyncJobCollector
+
+	// login state, Username and Password are used if not set
+	auth *providerAuth
+
+	// overrides rest client timeout if set (see WithTimeout)
+	requestTimeout time.Duration
+
+	// receives async job, login and retry measurements
+	metrics metrics.Metrics
+
+	// creates spans of provider methods, no spans are created if not set
+	tracer trace.Tracer
+
+	// context to send requests with, set by WithContext() and method spans
+	ctx context.Context
+}
+
+// asyncJobCollector - IDs of async jobs started by provider requests
+type asyncJobCollector struct {
+	jobIDs []string
 }
 
 func (p *Provider) String() string {
 	return p.Address
 }
 
+// logger returns logger to write provider logs to, Log is used for providers created without NewProvider()
+func (p *Provider) logger() logger.Logger {
+	if p.log != nil {
+		return p.log
+	}
+	return logger.Pick(nil, p.Log)
+}
+
+// WithTimeout returns provider copy which sends requests with another timeout, e.g. for long running requests.
+// The copy shares rest client and login state with the original provider.
+func (p *Provider) WithTimeout(timeout time.Duration) *Provider {
+	provider := *p
+	provider.requestTimeout = timeout
+	return &provider
+}
+
+// send sends request with provider's context and request timeout,
+// the context is not applied if RestClient doesn't implement rest.ContextClient
+func (p *Provider) send(method, path string, data interface{}) (int, []byte, error) {
+	client, ok := p.RestClient.(rest.ContextClient)
+	if !ok {
+		return p.RestClient.Send(method, path, data)
+	}
+
+	ctx := p.context()
+	if p.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.requestTimeout)
+		defer cancel()
+	}
+
+	return client.SendWithContext(ctx, method, path, data)
+}
+
 func (p *Provider) parseNefError(bodyBytes []byte, prefix string) error {
 	var restErrorMessage string
 	var restErrorCode string
@@ -159,9 +266,14 @@ func (p *Provider) sendRequest(method, path string, data interface{}) error {
 }
 
 func (p *Provider) doAuthRequest(method, path string, data interface{}) ([]byte, error) {
-	l := p.Log.WithField("func", "doAuthRequest()")
+	l := p.logger().WithField("func", "doAuthRequest()")
 
-	statusCode, bodyBytes, err := p.RestClient.Send(method, path, data)
+	sentToken, err := p.prepareAuthToken()
+	if err != nil {
+		return nil, err
+	}
+
+	statusCode, bodyBytes, err := p.send(method, path, data)
 	if err != nil {
 		return bodyBytes, err
 	}
@@ -171,15 +283,16 @@ func (p *Provider) doAuthRequest(method, path string, data interface{}) ([]byte,
 	// log in again if user is not logged in
 	if statusCode == http.StatusUnauthorized && IsAuthNefError(nefError) {
 		// do login call if used is not authorized in api
-		l.Debugf("log in as '%s'...", p.Username)
+		l.Debugf("auth token is rejected, log in...")
 
-		err = p.LogIn()
+		_, err = p.renewAuthToken(sentToken)
 		if err != nil {
 			return nil, err
 		}
 
 		// send original request again
-		statusCode, bodyBytes, err = p.RestClient.Send(method, path, data)
+		p.observeRetry(metrics.RetryAuth)
+		statusCode, bodyBytes, err = p.send(method, path, data)
 		if err != nil {
 			return bodyBytes, err
 		}
@@ -193,7 +306,14 @@ func (p *Provider) doAuthRequest(method, path string, data interface{}) ([]byte,
 			return bodyBytes, err
 		}
 
-		err = p.waitForAsyncJob(strings.TrimPrefix(href, "/jobStatus/"))
+		jobID := strings.TrimPrefix(href, "/jobStatus/")
+		if p.jobCollector != nil {
+			// job will be awaited by the collector owner (see Batch)
+			p.jobCollector.jobIDs = append(p.jobCollector.jobIDs, jobID)
+			return bodyBytes, nil
+		}
+
+		err = p.waitForAsyncJob(jobID)
 		if err != nil {
 			l.Debugf("waitForAsyncJob() error: %s", err)
 		}
@@ -230,7 +350,10 @@ func (p *Provider) parseAsyncJobHref(bodyBytes []byte) (string, error) {
 
 // waitForAsyncJob - keep asking for job status while it's not completed, return an error if timeout exceeded
 func (p *Provider) waitForAsyncJob(jobID string) (err error) {
-	l := p.Log.WithField("job", jobID)
+	p, end := p.startSpan("waitForAsyncJob", attrJobID.String(jobID))
+	defer end(&err)
+
+	l := p.logger().WithField("job", jobID)
 
 	timer := time.NewTimer(0)
 	timeout := time.After(checkJobStatusTimeout)
@@ -241,8 +364,10 @@ func (p *Provider) waitForAsyncJob(jobID string) (err error) {
 		case <-timer.C:
 			jobDone, err := p.IsJobDone(jobID)
 			if err != nil { // request failed
+				p.observeJob(metrics.JobFailed, startTime)
 				return err
 			} else if jobDone { // job is completed
+				p.observeJob(metrics.JobSucceeded, startTime)
 				return nil
 			} else {
 				waitingTimeSeconds := time.Since(startTime).Seconds()
@@ -253,38 +378,128 @@ func (p *Provider) waitForAsyncJob(jobID string) (err error) {
 			}
 		case <-timeout:
 			timer.Stop()
+			p.observeJob(metrics.JobTimedOut, startTime)
 			return fmt.Errorf("Checking job status timeout exceeded (%ds)", checkJobStatusTimeout)
 		}
 	}
 }
 
+// observeJob reports async job wait duration, provider created without NewProvider() reports nothing
+func (p *Provider) observeJob(outcome string, startTime time.Time) {
+	if p.metrics != nil {
+		p.metrics.ObserveJob(p.Address, outcome, time.Since(startTime))
+	}
+}
+
+// observeRetry reports repeated request
+func (p *Provider) observeRetry(reason string) {
+	if p.metrics != nil {
+		p.metrics.ObserveRetry(p.Address, reason)
+	}
+}
+
+// observeLogin reports login request result
+func (p *Provider) observeLogin(err error) {
+	if p.metrics != nil {
+		p.metrics.ObserveLogin(p.Address, err)
+	}
+}
+
 // ProviderArgs - params to create Provider instance
 type ProviderArgs struct {
 	Address  string
 	Username string
-	Password string
-	Log      *logrus.Entry
+	Password string `secret:"true"`
+
+	// Log - logrus entry to write to, not used if Logger is set
+	Log *logrus.Entry
+
+	// Logger - logger to write to (see logger package for logrus, slog and zap adapters),
+	// secrets are always redacted, nothing is logged if neither Log nor Logger is set
+	Logger logger.Logger
+
+	// Credentials - source of login credentials, overrides Username and Password
+	Credentials CredentialSource
+
+	// TokenTTL - auth token lifetime, the token is refreshed before it expires, defaultTokenTTL if not set
+	TokenTTL time.Duration
 
 	// InsecureSkipVerify controls whether a client verifies the server's certificate chain and host name.
 	InsecureSkipVerify bool
+
+	// TLS - CA bundle, client certificate, certificate pinning and TLS version options
+	TLS rest.TLSOptions
+
+	// Timeout - overall NexentaStor API request timeout, see rest.ClientArgs
+	Timeout time.Duration
+
+	// Transport - proxy and connection pool settings, see rest.ClientArgs
+	Transport rest.TransportOptions
+
+	// RoundTripper - custom HTTP transport, see rest.ClientArgs
+	RoundTripper http.RoundTripper
+
+	// WrapTransport - wraps HTTP transport, e.g. to trace or record requests, see rest.ClientArgs
+	WrapTransport func(rt http.RoundTripper) http.RoundTripper
+
+	// Metrics - receives request, async job and login measurements, see metrics.Metrics
+	Metrics metrics.Metrics
+
+	// TracerProvider - creates spans of provider methods and HTTP requests, e.g. otel.GetTracerProvider(),
+	// no spans are created if not set
+	TracerProvider trace.TracerProvider
+
+	// Propagator - injects trace context into request headers, e.g. propagation.TraceContext{}
+	Propagator propagation.TextMapPropagator
 }
 
 // NewProvider creates NexentaStor provider instance
 func NewProvider(args ProviderArgs) (ProviderInterface, error) {
-	l := args.Log.WithFields(logrus.Fields{
+	fields := logger.Fields{
 		"cmp": "NSProvider",
 		"ns":  args.Address,
-	})
+	}
+	l := logger.Pick(args.Logger, args.Log).WithFields(fields)
+	var log *logrus.Entry
+	if args.Log != nil {
+		log = args.Log.WithFields(logrus.Fields(fields))
+	}
 
 	if args.Address == "" {
 		return nil, fmt.Errorf("NexentaStor address not specified: %s", args.Address)
 	}
 
-	restClient := rest.NewClient(rest.ClientArgs{
+	restClient, err := rest.NewClientE(rest.ClientArgs{
 		Address:            args.Address,
-		Log:                l,
+		Logger:             l,
 		InsecureSkipVerify: args.InsecureSkipVerify,
+		TLS:                args.TLS,
+		Timeout:            args.Timeout,
+		Transport:          args.Transport,
+		RoundTripper:       args.RoundTripper,
+		WrapTransport:      args.WrapTransport,
+		Metrics:            args.Metrics,
+		TracerProvider:     args.TracerProvider,
+		Propagator:         args.Propagator,
 	})
+	if err != nil {
+		return nil, fmt.Errorf("Cannot create REST client for '%s': %s", args.Address, err)
+	}
+
+	credentials := args.Credentials
+	if credentials == nil {
+		credentials = NewStaticCredentialSource(args.Username, args.Password)
+	}
+
+	providerMetrics := args.Metrics
+	if providerMetrics == nil {
+		providerMetrics = metrics.Nop{}
+	}
+
+	var tracer trace.Tracer
+	if args.TracerProvider != nil {
+		tracer = args.TracerProvider.Tracer(tracerName)
+	}
 
 	l.Debugf("created for '%s'", args.Address)
 	return &Provider{
@@ -292,6 +507,10 @@ func NewProvider(args ProviderArgs) (ProviderInterface, error) {
 		Username:   args.Username,
 		Password:   args.Password,
 		RestClient: restClient,
-		Log:        l,
+		Log:        log,
+		log:        l,
+		auth:       newProviderAuth(credentials, args.TokenTTL),
+		metrics:    providerMetrics,
+		tracer:     tracer,
 	}, nil
 }
@@ -2,20 +2,40 @@ package ns
 
 import (
 	"fmt"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Nexenta/go-nexentastor/pkg/logger"
+	"github.com/Nexenta/go-nexentastor/pkg/metrics"
+	"github.com/Nexenta/go-nexentastor/pkg/rest"
 )
 
 // Resolver - NexentaStor cluster API provider
 type Resolver struct {
 	Nodes []ProviderInterface
-	Log   *logrus.Entry
+	// Log - logrus entry with resolver fields, nil if the resolver is created with ResolverArgs.Logger only
+	Log *logrus.Entry
+
+	// writes resolver logs, see logger()
+	log logger.Logger
+}
+
+// logger returns logger to write resolver logs to, Log is used for resolvers created without NewResolver()
+func (r *Resolver) logger() logger.Logger {
+	if r.log != nil {
+		return r.log
+	}
+	return logger.Pick(nil, r.Log)
 }
 
 // Resolve returns one NS from the list of NSs by provided pool/dataset/fs path
 func (r *Resolver) Resolve(path string) (ProviderInterface, error) {
-	l := r.Log.WithField("func", "Resolve()")
+	l := r.logger().WithField("func", "Resolve()")
 
 	if path == "" {
 		return nil, fmt.Errorf("Resolved was called with empty pool/dataset path")
@@ -50,7 +70,7 @@ func (r *Resolver) Resolve(path string) (ProviderInterface, error) {
 
 // Resolve returns one NS from the list of NSs by provided pool/volumeGroup path
 func (r *Resolver) ResolveFromVg(path string) (ProviderInterface, error) {
-	l := r.Log.WithField("func", "Resolve()")
+	l := r.logger().WithField("func", "Resolve()")
 
 	if path == "" {
 		return nil, fmt.Errorf("Resolved was called with empty pool/volumeGroup path")
@@ -85,7 +105,7 @@ func (r *Resolver) ResolveFromVg(path string) (ProviderInterface, error) {
 // IsCluster checks if nodes is a NS cluster
 // For now it simple checks if all nodes return at least one similar cluster name
 func (r *Resolver) IsCluster() (bool, error) {
-	l := r.Log.WithField("func", "IsCluster()")
+	l := r.logger().WithField("func", "IsCluster()")
 
 	if len(r.Nodes) < 2 {
 		return false, nil
@@ -124,19 +144,59 @@ func (r *Resolver) IsCluster() (bool, error) {
 type ResolverArgs struct {
 	Address  string
 	Username string
-	Password string
-	Log      *logrus.Entry
+	Password string `secret:"true"`
+
+	// Log - logrus entry to write to, not used if Logger is set
+	Log *logrus.Entry
+
+	// Logger - logger to write to, see ProviderArgs
+	Logger logger.Logger
+
+	// Credentials - source of login credentials, overrides Username and Password
+	Credentials CredentialSource
+
+	// TokenTTL - auth token lifetime, see ProviderArgs
+	TokenTTL time.Duration
 
 	// InsecureSkipVerify controls whether a client verifies the server's certificate chain and host name.
 	InsecureSkipVerify bool
+
+	// TLS - CA bundle, client certificate, certificate pinning and TLS version options
+	TLS rest.TLSOptions
+
+	// Timeout - overall NexentaStor API request timeout, see rest.ClientArgs
+	Timeout time.Duration
+
+	// Transport - proxy and connection pool settings, see rest.ClientArgs
+	Transport rest.TransportOptions
+
+	// RoundTripper - custom HTTP transport, see rest.ClientArgs
+	RoundTripper http.RoundTripper
+
+	// WrapTransport - wraps HTTP transport, e.g. to trace or record requests, see rest.ClientArgs
+	WrapTransport func(rt http.RoundTripper) http.RoundTripper
+
+	// Metrics - receives request, async job and login measurements, see metrics.Metrics
+	Metrics metrics.Metrics
+
+	// TracerProvider - creates spans of provider methods and HTTP requests, see ProviderArgs
+	TracerProvider trace.TracerProvider
+
+	// Propagator - injects trace context into request headers, see ProviderArgs
+	Propagator propagation.TextMapPropagator
 }
 
 // NewResolver creates NexentaStor resolver instance based on configuration
 func NewResolver(args ResolverArgs) (*Resolver, error) {
-	l := args.Log.WithFields(logrus.Fields{
+	fields := logger.Fields{
 		"cmp": "NSResolver",
 		"ns":  args.Address,
-	})
+	}
+	l := logger.Pick(args.Logger, args.Log).WithFields(fields)
+	var log *logrus.Entry
+	if args.Log != nil {
+		log = args.Log.WithFields(logrus.Fields(fields))
+	}
 
 	if args.Address == "" {
 		return nil, fmt.Errorf("NexentaStor address not specified: %s", args.Address)
@@ -149,8 +209,19 @@ func NewResolver(args ResolverArgs) (*Resolver, error) {
 			Address:            address,
 			Username:           args.Username,
 			Password:           args.Password,
-			Log:                l,
+			Log:                log,
+			Logger:             args.Logger,
+			Credentials:        args.Credentials,
+			TokenTTL:           args.TokenTTL,
 			InsecureSkipVerify: args.InsecureSkipVerify,
+			TLS:                args.TLS,
+			Timeout:            args.Timeout,
+			Transport:          args.Transport,
+			RoundTripper:       args.RoundTripper,
+			WrapTransport:      args.WrapTransport,
+			Metrics:            args.Metrics,
+			TracerProvider:     args.TracerProvider,
+			Propagator:         args.Propagator,
 		})
 		if err != nil {
 			return nil, fmt.Errorf("Cannot create provider for %s NexentaStor: %s", address, err)
@@ -161,6 +232,7 @@ func NewResolver(args ResolverArgs) (*Resolver, error) {
 	l.Debugf("created for '%s'", args.Address)
 	return &Resolver{
 		Nodes: nodes,
-		Log:   l,
+		Log:   log,
+		log:   l,
 	}, nil
 }
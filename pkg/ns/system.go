@@ -0,0 +1,75 @@
+package ns
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+)
+
+var versionRegexp = regexp.MustCompile(`^(\d+)(?:\.(\d+))?(?:\.(\d+))?`)
+
+// Version - NexentaStor version, suffixes after major, minor and patch numbers are ignored in comparison
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+
+	// version as NexentaStor returns it, e.g. "5.3.1.0-b12"
+	raw string
+}
+
+// ParseVersion parses NexentaStor version: "5", "5.3", "5.3.1" and "5.3.1.0-b12" are accepted
+func ParseVersion(version string) (Version, error) {
+	match := versionRegexp.FindStringSubmatch(version)
+	if match == nil {
+		return Version{}, fmt.Errorf("Invalid NexentaStor version '%s': expected 'major.minor.patch'", version)
+	}
+
+	numbers := make([]int, 3)
+	for i, number := range match[1:] {
+		if number != "" {
+			numbers[i], _ = strconv.Atoi(number)
+		}
+	}
+
+	return Version{Major: numbers[0], Minor: numbers[1], Patch: numbers[2], raw: version}, nil
+}
+
+// Less returns true if the version is older than another one
+func (v Version) Less(other Version) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor < other.Minor
+	}
+	return v.Patch < other.Patch
+}
+
+func (v Version) String() string {
+	if v.raw != "" {
+		return v.raw
+	}
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// SystemInfo - NexentaStor appliance information
+type SystemInfo struct {
+	Version  string `json:"version"`
+	Hostname string `json:"hostName"`
+	GUID     string `json:"guid"`
+}
+
+// GetSystemInfo returns NexentaStor version, hostname and GUID
+func (p *Provider) GetSystemInfo() (info SystemInfo, err error) {
+	p, end := p.startSpan("GetSystemInfo")
+	defer end(&err)
+
+	uri := p.RestClient.BuildURI("/system/info", map[string]string{
+		"fields": "version,hostName,guid",
+	})
+
+	err = p.sendRequestWithStruct(http.MethodGet, uri, nil, &info)
+	return info, err
+}
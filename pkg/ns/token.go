@@ -0,0 +1,280 @@
+package ns
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// NexentaStor auth token lifetime, used if the token doesn't tell its expiration time
+	defaultTokenTTL = 10 * time.Minute
+
+	// token is refreshed in advance, so requests don't fail with expired token
+	tokenRefreshMargin = 30 * time.Second
+)
+
+// authToken - NexentaStor auth token with its expiration time
+type authToken struct {
+	value     string
+	expiresAt time.Time
+}
+
+func newAuthToken(value string, ttl time.Duration) authToken {
+	expiresAt, ok := parseJWTExpiration(value)
+	if !ok {
+		expiresAt = time.Now().Add(ttl)
+	}
+	return authToken{value: value, expiresAt: expiresAt}
+}
+
+// needsRefresh returns true if the token is about to expire
+func (t authToken) needsRefresh() bool {
+	return time.Until(t.expiresAt) < tokenRefreshMargin
+}
+
+// parseJWTExpiration returns "exp" claim if the token is a JWT
+func parseJWTExpiration(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	claims := struct {
+		Exp int64 `json:"exp"`
+	}{}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}, false
+	}
+
+	return time.Unix(claims.Exp, 0), true
+}
+
+// tokenCache - auth tokens shared by all providers of the same NexentaStor endpoint and credentials
+type tokenCache struct {
+	mux    sync.Mutex
+	tokens map[string]authToken
+	// logins in progress
+	logins map[string]*loginCall
+}
+
+// loginCall - login shared by concurrent token renewals
+type loginCall struct {
+	done  chan struct{}
+	token authToken
+	err   error
+}
+
+// loginFunc logs in, returns the token and the cache key of the used credentials
+type loginFunc func() (authToken, string, error)
+
+var sharedTokenCache = &tokenCache{
+	tokens: map[string]authToken{},
+	logins: map[string]*loginCall{},
+}
+
+// tokenCacheKey returns cache key of the endpoint and credentials, the password is a part of the key,
+// so a provider with wrong or outdated password never uses a token obtained with the right one
+func tokenCacheKey(address string, credentials Credentials) string {
+	hash := sha256.Sum256([]byte(address + "\n" + credentials.Username + "\n" + credentials.Password))
+	return address + "\n" + credentials.Username + "\n" + hex.EncodeToString(hash[:])
+}
+
+// get returns cached token, expired tokens are not returned
+func (c *tokenCache) get(key string) (authToken, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	token, ok := c.tokens[key]
+	if ok && !time.Now().Before(token.expiresAt) {
+		delete(c.tokens, key)
+		return authToken{}, false
+	}
+	return token, ok
+}
+
+// renew returns a token to replace the rejected one: the cached token if someone has already renewed it,
+// otherwise a token returned by login. Concurrent renewals of the same key share one login call.
+// Empty rejected token forces a login. The token is cached by the key login returns,
+// it differs from the passed one if credentials have been rotated.
+func (c *tokenCache) renew(key, rejected string, login loginFunc) (authToken, error) {
+	c.mux.Lock()
+
+	if token, ok := c.tokens[key]; ok && rejected != "" && token.value != rejected && !token.needsRefresh() {
+		c.mux.Unlock()
+		return token, nil
+	}
+
+	if call, ok := c.logins[key]; ok {
+		c.mux.Unlock()
+		<-call.done
+		return call.token, call.err
+	}
+
+	call := &loginCall{done: make(chan struct{})}
+	c.logins[key] = call
+	c.mux.Unlock()
+
+	var loginKey string
+	call.token, loginKey, call.err = login()
+
+	c.mux.Lock()
+	delete(c.logins, key)
+	if call.err == nil {
+		c.tokens[loginKey] = call.token
+	}
+	if (call.err != nil || loginKey != key) && c.tokens[key].value == rejected {
+		delete(c.tokens, key)
+	}
+	c.mux.Unlock()
+
+	close(call.done)
+	return call.token, call.err
+}
+
+// providerAuth - provider login state, shared by provider copies (see Batch)
+type providerAuth struct {
+	credentials CredentialSource
+	tokenTTL    time.Duration
+
+	mux sync.Mutex
+	// token cache key of the last used credentials
+	key string
+	// token set to provider's rest client
+	token authToken
+}
+
+func newProviderAuth(credentials CredentialSource, tokenTTL time.Duration) *providerAuth {
+	if tokenTTL <= 0 {
+		tokenTTL = defaultTokenTTL
+	}
+	return &providerAuth{
+		credentials: credentials,
+		tokenTTL:    tokenTTL,
+	}
+}
+
+// getCredentials returns credentials to log in with
+func (p *Provider) getCredentials() (Credentials, error) {
+	if p.auth == nil {
+		return Credentials{Username: p.Username, Password: p.Password}, nil
+	}
+
+	credentials, err := p.auth.credentials.GetCredentials()
+	if err != nil {
+		return credentials, fmt.Errorf("Cannot get NexentaStor credentials: %s", err)
+	}
+
+	return credentials, nil
+}
+
+// authCacheKey returns token cache key of the provider, credentials are requested only once,
+// then the key is updated on each login
+func (p *Provider) authCacheKey() (string, error) {
+	p.auth.mux.Lock()
+	key := p.auth.key
+	p.auth.mux.Unlock()
+
+	if key == "" {
+		credentials, err := p.getCredentials()
+		if err != nil {
+			return "", err
+		}
+		key = tokenCacheKey(p.Address, credentials)
+
+		p.auth.mux.Lock()
+		p.auth.key = key
+		p.auth.mux.Unlock()
+	}
+
+	return key, nil
+}
+
+// useAuthToken sets the token to provider's rest client
+func (p *Provider) useAuthToken(token authToken) {
+	p.auth.mux.Lock()
+	defer p.auth.mux.Unlock()
+
+	if p.auth.token.value != token.value {
+		p.RestClient.SetAuthToken(token.value)
+		p.auth.token = token
+	}
+}
+
+// prepareAuthToken sets token shared by another provider or refreshes the token which is about to expire,
+// returns the token to send requests with. Nothing is done if provider has never logged in
+// and there is no shared token, so login happens on EAUTH.
+func (p *Provider) prepareAuthToken() (string, error) {
+	if p.auth == nil {
+		return "", nil
+	}
+
+	key, err := p.authCacheKey()
+	if err != nil {
+		return "", err
+	}
+
+	p.auth.mux.Lock()
+	current := p.auth.token
+	p.auth.mux.Unlock()
+
+	token, ok := sharedTokenCache.get(key)
+	if !ok {
+		if current.value == "" {
+			return "", nil
+		}
+		p.logger().WithField("func", "prepareAuthToken()").Debugf("auth token has expired, log in...")
+		return p.renewAuthToken(current.value)
+	} else if token.needsRefresh() {
+		p.logger().WithField("func", "prepareAuthToken()").Debugf("auth token is about to expire, log in...")
+		return p.renewAuthToken(token.value)
+	}
+
+	p.useAuthToken(token)
+	return token.value, nil
+}
+
+// renewAuthToken replaces the rejected token, empty rejected token forces a login
+// Only one login request is sent at a time for the same NexentaStor and credentials,
+// other callers wait for its result.
+func (p *Provider) renewAuthToken(rejected string) (string, error) {
+	if p.auth == nil {
+		return "", p.LogIn()
+	}
+
+	key, err := p.authCacheKey()
+	if err != nil {
+		return "", err
+	}
+
+	token, err := sharedTokenCache.renew(key, rejected, func() (authToken, string, error) {
+		credentials, value, err := p.sendLogInRequest()
+		if err != nil {
+			return authToken{}, "", err
+		}
+
+		// credentials might be rotated since the key was calculated
+		loginKey := tokenCacheKey(p.Address, credentials)
+		p.auth.mux.Lock()
+		p.auth.key = loginKey
+		p.auth.mux.Unlock()
+
+		return newAuthToken(value, p.auth.tokenTTL), loginKey, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	p.useAuthToken(token)
+	return token.value, nil
+}
@@ -0,0 +1,69 @@
+package ns
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// name of the tracer spans of provider methods are created by
+const tracerName = "github.com/Nexenta/go-nexentastor/pkg/ns"
+
+// span attributes
+const (
+	attrAddress  = attribute.Key("nexentastor.address")
+	attrPath     = attribute.Key("nexentastor.path")
+	attrJobID    = attribute.Key("nexentastor.job_id")
+	attrNefError = attribute.Key("nexentastor.error_code")
+)
+
+// WithContext returns provider copy which sends requests with the context: requests are canceled with it,
+// spans of the copy methods are children of the context span.
+// The copy shares rest client and login state with the original provider.
+func (p *Provider) WithContext(ctx context.Context) *Provider {
+	provider := *p
+	provider.ctx = ctx
+	return &provider
+}
+
+// context returns context to send requests with
+func (p *Provider) context() context.Context {
+	if p.ctx == nil {
+		return context.Background()
+	}
+	return p.ctx
+}
+
+// startSpan starts span of provider method, returns provider copy which requests are children of the span
+// and a function to end the span with method error.
+// Provider created without NewProvider() or without TracerProvider gets no spans.
+func (p *Provider) startSpan(name string, attributes ...attribute.KeyValue) (*Provider, func(err *error)) {
+	if p.tracer == nil {
+		return p, func(err *error) {}
+	}
+
+	ctx, span := p.tracer.Start(
+		p.context(),
+		"ns."+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(append(attributes, attrAddress.String(p.Address))...),
+	)
+
+	return p.WithContext(ctx), func(err *error) {
+		endSpan(span, *err)
+	}
+}
+
+// endSpan records method error and ends the span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		if code := GetNefErrorCode(err); code != "" {
+			span.SetAttributes(attrNefError.String(code))
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
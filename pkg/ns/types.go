@@ -1,6 +1,7 @@
 package ns
 
 import (
+	"fmt"
 	"strings"
 	"time"
 )
@@ -30,6 +31,13 @@ type Filesystem struct {
 	SharedOverSmb  bool   `json:"sharedOverSmb"`
 	BytesAvailable int64  `json:"bytesAvailable"`
 	BytesUsed      int64  `json:"bytesUsed"`
+	ReferencedQuotaSize int64 `json:"referencedQuotaSize"`
+
+	// not in the default field set, request with ListOptions.Fields
+	BytesReferenced      int64             `json:"bytesReferenced"`
+	BytesUsedBySnapshots int64             `json:"bytesUsedBySnapshots"`
+	CompressionRatio     float64           `json:"compressionRatio"`
+	UserProperties       map[string]string `json:"userProperties"`
 }
 
 // Volume - NexentaStor volume
@@ -38,6 +46,18 @@ type Volume struct {
 	BytesAvailable int64  `json:"bytesAvailable"`
 	BytesUsed      int64  `json:"bytesUsed"`
 	VolumeSize     int64  `json:"volumeSize"`
+	VolumeBlockSize int64 `json:"volumeBlockSize"`
+	SparseVolume    bool  `json:"sparseVolume"`
+	CompressionMode string `json:"compressionMode"`
+	SyncMode        string `json:"syncMode"`
+	LogBias         string `json:"logBias"`
+	ReservationSize int64  `json:"reservationSize"`
+
+	// not in the default field set, request with ListOptions.Fields
+	BytesReferenced      int64             `json:"bytesReferenced"`
+	BytesUsedBySnapshots int64             `json:"bytesUsedBySnapshots"`
+	CompressionRatio     float64           `json:"compressionRatio"`
+	UserProperties       map[string]string `json:"userProperties"`
 }
 
 // VolumeGroup - NexentaStor volumeGroup
@@ -56,6 +76,125 @@ type LunMapping struct {
 	Lun 		int    `json:"lun"`
 }
 
+// FCPortMode - Fibre Channel port mode
+type FCPortMode string
+
+const (
+	// FCPortModeTarget - port serves LUNs to FC hosts
+	FCPortModeTarget FCPortMode = "target"
+
+	// FCPortModeInitiator - port is used to access remote FC targets
+	FCPortModeInitiator FCPortMode = "initiator"
+)
+
+// FCPort - NexentaStor Fibre Channel port
+type FCPort struct {
+	WWPN  string     `json:"wwpn"`
+	State string     `json:"state"`
+	Speed string     `json:"speed"`
+	Mode  FCPortMode `json:"mode"`
+}
+
+// HostGroup - NexentaStor SAN host group
+type HostGroup struct {
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+}
+
+// AlertSource - NexentaStor list an alert comes from
+type AlertSource string
+
+const (
+	// AlertSourceFault - hardware and pool faults, e.g. degraded pool
+	AlertSourceFault AlertSource = "fault"
+
+	// AlertSourceAlert - system alerts, e.g. RSF service failover
+	AlertSourceAlert AlertSource = "alert"
+)
+
+// AlertSeverity - severity of NexentaStor alert
+type AlertSeverity string
+
+const (
+	AlertSeverityInfo     AlertSeverity = "info"
+	AlertSeverityWarning  AlertSeverity = "warning"
+	AlertSeverityCritical AlertSeverity = "critical"
+)
+
+// Alert - NexentaStor fault or alert, ID is unique within the source
+type Alert struct {
+	Id           string        `json:"id"`
+	Source       AlertSource   `json:"source"`
+	Severity     AlertSeverity `json:"severity"`
+	Component    string        `json:"component"`
+	Message      string        `json:"message"`
+	Time         time.Time     `json:"time"`
+	Acknowledged bool          `json:"acknowledged"`
+}
+
+func (alert *Alert) String() string {
+	return fmt.Sprintf("%s %s [%s] %s: %s", alert.Source, alert.Id, alert.Severity, alert.Component, alert.Message)
+}
+
+// AnalyticsQuery - time range and resolution of performance counters query
+type AnalyticsQuery struct {
+	Start time.Time
+
+	// current time if not set
+	End time.Time
+
+	// interval between points, rounded up to seconds
+	Resolution time.Duration
+}
+
+// IOStats - average pool, vdev or dataset I/O performance over one resolution interval
+type IOStats struct {
+	Time time.Time `json:"time"`
+
+	// operations per second
+	ReadIOPS  float64 `json:"readIops"`
+	WriteIOPS float64 `json:"writeIops"`
+
+	// bytes per second
+	ReadBandwidth  float64 `json:"readBandwidth"`
+	WriteBandwidth float64 `json:"writeBandwidth"`
+
+	// milliseconds per operation
+	ReadLatency  float64 `json:"readLatency"`
+	WriteLatency float64 `json:"writeLatency"`
+}
+
+// IOTimeSeries - I/O performance of the resource, points are ordered by time
+type IOTimeSeries struct {
+	// pool name, "pool/vdev" or dataset path
+	Resource   string
+	Resolution time.Duration
+	Points     []IOStats
+}
+
+// NICStats - average network interface performance over one resolution interval
+type NICStats struct {
+	Time time.Time `json:"time"`
+
+	// bytes per second
+	ReceiveBandwidth  float64 `json:"receiveBandwidth"`
+	TransmitBandwidth float64 `json:"transmitBandwidth"`
+
+	// packets per second
+	ReceivePackets  float64 `json:"receivePackets"`
+	TransmitPackets float64 `json:"transmitPackets"`
+
+	// errors per second
+	Errors float64 `json:"errors"`
+}
+
+// NICTimeSeries - performance of the network interface, points are ordered by time
+type NICTimeSeries struct {
+	Interface  string
+	Resolution time.Duration
+	Points     []NICStats
+}
+
 func (fs *Filesystem) String() string {
 	return fs.Path
 }
@@ -99,11 +238,11 @@ type Pool struct {
 
 type nefAuthLoginRequest struct {
 	Username string `json:"username"`
-	Password string `json:"password"`
+	Password string `json:"password" secret:"true"`
 }
 
 type nefAuthLoginResponse struct {
-	Token string `json:"token"`
+	Token string `json:"token" secret:"true"`
 }
 
 type nefStoragePoolsResponse struct {
@@ -126,19 +265,48 @@ type nefLunMappingsResponse struct {
 	Data[]LunMapping `json:"data"`
 }
 
+type nefFCPortsResponse struct {
+	Data []FCPort `json:"data"`
+}
+
+type nefHostGroupsResponse struct {
+	Data []HostGroup `json:"data"`
+}
+
+type nefAlertsResponse struct {
+	Data []Alert `json:"data"`
+}
+
+type nefIOStatsResponse struct {
+	Data []IOStats `json:"data"`
+}
+
+type nefNICStatsResponse struct {
+	Data []NICStats `json:"data"`
+}
+
 type nefStorageSnapshotsResponse struct {
 	Data []Snapshot `json:"data"`
 }
 
 type nefNasNfsRequest struct {
-	Filesystem       string                            `json:"filesystem"`
-	Anon             string                            `json:"anon"`
-	SecurityContexts []nefNasNfsRequestSecurityContext `json:"securityContexts"`
+	Filesystem       string               `json:"filesystem"`
+	Anon             string               `json:"anon"`
+	SecurityContexts []NfsSecurityContext `json:"securityContexts"`
+}
+
+// NfsShare - NFS share of a filesystem
+type NfsShare struct {
+	Filesystem       string               `json:"filesystem"`
+	Anon             string               `json:"anon"`
+	SecurityContexts []NfsSecurityContext `json:"securityContexts"`
 }
-type nefNasNfsRequestSecurityContext struct {
-	SecurityModes []string 							`json:"securityModes"`
-	ReadWriteList []NfsRuleList						`json:"readWriteList"`
-	ReadOnlyList  []NfsRuleList						`json:"readOnlyList"`
+
+// NfsSecurityContext - NFS share access rules for the security modes
+type NfsSecurityContext struct {
+	SecurityModes []string      `json:"securityModes"`
+	ReadWriteList []NfsRuleList `json:"readWriteList"`
+	ReadOnlyList  []NfsRuleList `json:"readOnlyList"`
 }
 
 type NfsRuleList struct {
@@ -156,6 +324,33 @@ type nefNasSmbResponse struct {
 	ShareName string `json:"shareName"`
 }
 
+type nefStorageFilesystemsACLResponse struct {
+	Data []ACLRule `json:"data"`
+}
+
+// ACLRule - filesystem ACL entry
+type ACLRule struct {
+	Index       int      `json:"index"`
+	Type        string   `json:"type"`
+	Principal   string   `json:"principal"`
+	Flags       []string `json:"flags"`
+	Permissions []string `json:"permissions"`
+}
+
+// RuleSet returns rule set of the entry added by SetFilesystemACL, false if it's another entry
+func (r ACLRule) RuleSet() (ACLRuleSet, bool) {
+	if r.Type != "allow" || r.Principal != "everyone@" || len(r.Permissions) != 1 {
+		return 0, false
+	}
+	switch r.Permissions[0] {
+	case "read_set":
+		return ACLReadOnly, true
+	case "full_set":
+		return ACLReadWrite, true
+	}
+	return 0, false
+}
+
 type nefStorageFilesystemsACLRequest struct {
 	Type        string   `json:"type"`
 	Principal   string   `json:"principal"`
@@ -0,0 +1,277 @@
+package ns
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Nexenta/go-nexentastor/pkg/logger"
+)
+
+// default interval between list requests of DatasetWatcher
+const defaultDatasetWatchInterval = 30 * time.Second
+
+// WatchEventType - kind of dataset change found by DatasetWatcher
+type WatchEventType string
+
+const (
+	// WatchEventAdded - dataset appeared in the list, all datasets of the first list are added
+	WatchEventAdded WatchEventType = "added"
+
+	// WatchEventModified - properties of the dataset have been changed
+	WatchEventModified WatchEventType = "modified"
+
+	// WatchEventDeleted - dataset disappeared from the list
+	WatchEventDeleted WatchEventType = "deleted"
+
+	// WatchEventSynced - dataset is not changed, sent for each dataset on resync
+	WatchEventSynced WatchEventType = "synced"
+
+	// WatchEventError - list request failed, watching continues
+	WatchEventError WatchEventType = "error"
+)
+
+// FilesystemEvent - filesystem change, Previous is set for modified filesystems, Err for errors
+type FilesystemEvent struct {
+	Type       WatchEventType
+	Filesystem Filesystem
+	Previous   Filesystem
+	Err        error
+}
+
+// VolumeEvent - volume change, Previous is set for modified volumes, Err for errors
+type VolumeEvent struct {
+	Type     WatchEventType
+	Volume   Volume
+	Previous Volume
+	Err      error
+}
+
+// watchItem - listed dataset with its path to diff lists by
+type watchItem struct {
+	path  string
+	value interface{}
+}
+
+// watchSendFunc sends the event to the watch channel, returns false if the context is done
+type watchSendFunc func(eventType WatchEventType, current, previous interface{}, err error) bool
+
+// DatasetWatcher - reports changes of NexentaStor filesystems and volumes by diffing successive lists
+type DatasetWatcher struct {
+	Provider ProviderInterface
+	Log      logger.Logger
+
+	// period to send unchanged datasets as WatchEventSynced, resync is disabled if 0
+	ResyncInterval time.Duration
+
+	// options of watched lists, e.g. Fields to limit the compared properties
+	ListOptions ListOptions
+}
+
+// WatchFilesystems lists children of the parent filesystem each interval until the context is done,
+// then the channel is closed
+func (w *DatasetWatcher) WatchFilesystems(
+	ctx context.Context,
+	parent string,
+	interval time.Duration,
+) <-chan FilesystemEvent {
+	events := make(chan FilesystemEvent)
+
+	list := func() ([]watchItem, error) {
+		filesystems, err := w.Provider.ListFilesystems(parent, w.ListOptions)
+		if err != nil {
+			return nil, err
+		}
+		items := make([]watchItem, len(filesystems))
+		for i, fs := range filesystems {
+			items[i] = watchItem{fs.Path, fs}
+		}
+		return items, nil
+	}
+
+	send := func(eventType WatchEventType, current, previous interface{}, err error) bool {
+		event := FilesystemEvent{Type: eventType, Err: err}
+		event.Filesystem, _ = current.(Filesystem)
+		event.Previous, _ = previous.(Filesystem)
+		select {
+		case events <- event:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	go func() {
+		defer close(events)
+		w.watch(ctx, fmt.Sprintf("filesystems of '%s'", parent), interval, list, send)
+	}()
+
+	return events
+}
+
+// WatchVolumes lists volumes of the volumeGroup each interval until the context is done,
+// then the channel is closed
+func (w *DatasetWatcher) WatchVolumes(ctx context.Context, parent string, interval time.Duration) <-chan VolumeEvent {
+	events := make(chan VolumeEvent)
+
+	list := func() ([]watchItem, error) {
+		volumes, err := w.Provider.ListVolumes(parent, w.ListOptions)
+		if err != nil {
+			return nil, err
+		}
+		items := make([]watchItem, len(volumes))
+		for i, volume := range volumes {
+			items[i] = watchItem{volume.Path, volume}
+		}
+		return items, nil
+	}
+
+	send := func(eventType WatchEventType, current, previous interface{}, err error) bool {
+		event := VolumeEvent{Type: eventType, Err: err}
+		event.Volume, _ = current.(Volume)
+		event.Previous, _ = previous.(Volume)
+		select {
+		case events <- event:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	go func() {
+		defer close(events)
+		w.watch(ctx, fmt.Sprintf("volumes of '%s'", parent), interval, list, send)
+	}()
+
+	return events
+}
+
+// watch sends differences between successive lists until the context is done
+func (w *DatasetWatcher) watch(
+	ctx context.Context,
+	name string,
+	interval time.Duration,
+	list func() ([]watchItem, error),
+	send watchSendFunc,
+) {
+	l := w.Log.WithField("func", "DatasetWatcher.watch()")
+
+	if interval <= 0 {
+		interval = defaultDatasetWatchInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var known map[string]interface{}
+	var lastResync time.Time
+
+	for {
+		items, err := list()
+		if err != nil {
+			if !send(WatchEventError, nil, nil, err) {
+				return
+			}
+		} else {
+			resync := known != nil && w.ResyncInterval > 0 && time.Since(lastResync) >= w.ResyncInterval
+			if known == nil || resync {
+				lastResync = time.Now()
+			}
+			current, ok := diffWatchItems(known, items, resync, send)
+			if !ok {
+				return
+			}
+			known = current
+		}
+
+		select {
+		case <-ctx.Done():
+			l.Debugf("stopped watching %s: %v", name, ctx.Err())
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// diffWatchItems sends events for differences between the known datasets and the listed ones,
+// returns listed datasets by path and false if the context is done
+func diffWatchItems(
+	known map[string]interface{},
+	items []watchItem,
+	resync bool,
+	send watchSendFunc,
+) (map[string]interface{}, bool) {
+	current := make(map[string]interface{}, len(items))
+
+	for _, item := range items {
+		current[item.path] = item.value
+
+		var ok bool
+		previous, exists := known[item.path]
+		switch {
+		case !exists:
+			ok = send(WatchEventAdded, item.value, nil, nil)
+		case !reflect.DeepEqual(previous, item.value):
+			ok = send(WatchEventModified, item.value, previous, nil)
+		case resync:
+			ok = send(WatchEventSynced, item.value, previous, nil)
+		default:
+			ok = true
+		}
+		if !ok {
+			return nil, false
+		}
+	}
+
+	deleted := []string{}
+	for path := range known {
+		if _, ok := current[path]; !ok {
+			deleted = append(deleted, path)
+		}
+	}
+	sort.Strings(deleted)
+	for _, path := range deleted {
+		if !send(WatchEventDeleted, known[path], nil, nil) {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+// DatasetWatcherArgs - params to create DatasetWatcher instance
+type DatasetWatcherArgs struct {
+	Provider ProviderInterface
+
+	// ResyncInterval - period to send unchanged datasets as WatchEventSynced, resync is disabled if not set
+	ResyncInterval time.Duration
+
+	// ListOptions - options of watched lists
+	ListOptions ListOptions
+
+	// Log - logrus entry to write to, not used if Logger is set
+	Log *logrus.Entry
+
+	// Logger - logger to write to, see ProviderArgs
+	Logger logger.Logger
+}
+
+// NewDatasetWatcher creates NexentaStor filesystems and volumes watcher
+func NewDatasetWatcher(args DatasetWatcherArgs) (*DatasetWatcher, error) {
+	if args.Provider == nil {
+		return nil, fmt.Errorf("NexentaStor provider not specified")
+	}
+	if args.ResyncInterval < 0 {
+		return nil, fmt.Errorf("Resync interval must not be negative, got: %v", args.ResyncInterval)
+	}
+
+	return &DatasetWatcher{
+		Provider:       args.Provider,
+		Log:            logger.Pick(args.Logger, args.Log).WithField("cmp", "DatasetWatcher"),
+		ResyncInterval: args.ResyncInterval,
+		ListOptions:    args.ListOptions,
+	}, nil
+}
@@ -0,0 +1,585 @@
+package reconcile
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Nexenta/go-nexentastor/pkg/logger"
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+)
+
+// ActionType - kind of change
+type ActionType string
+
+const (
+	// ActionCreate - resource will be created
+	ActionCreate ActionType = "create"
+
+	// ActionUpdate - resource will be changed in place
+	ActionUpdate ActionType = "update"
+
+	// ActionDelete - resource will be deleted
+	ActionDelete ActionType = "delete"
+)
+
+var actionSigns = map[ActionType]string{
+	ActionCreate: "+",
+	ActionUpdate: "~",
+	ActionDelete: "-",
+}
+
+// Resource types
+const (
+	ResourceFilesystem = "filesystem"
+	ResourceNfsShare   = "nfsShare"
+	ResourceSmbShare   = "smbShare"
+	ResourceACL        = "acl"
+)
+
+// Action - one change to bring NexentaStor to the desired state
+type Action struct {
+	Type     ActionType
+	Resource string
+	Path     string
+
+	// human readable description of the change
+	Details string
+
+	// deletion is not allowed (see Reconciler.AllowDelete), action is skipped on apply
+	Protected bool
+
+	apply func(p ns.ProviderInterface) error
+}
+
+func (a Action) String() string {
+	sign := actionSigns[a.Type]
+	if a.Protected {
+		sign = "!"
+	}
+
+	s := fmt.Sprintf("%s %s %s %s", sign, a.Type, a.Resource, a.Path)
+	if a.Details != "" {
+		s = fmt.Sprintf("%s (%s)", s, a.Details)
+	}
+	if a.Protected {
+		s = fmt.Sprintf("%s: protected, deletion is not allowed", s)
+	}
+
+	return s
+}
+
+// Plan - ordered list of actions to bring NexentaStor to the desired state
+type Plan struct {
+	Actions []Action
+}
+
+// IsEmpty returns true if NexentaStor is already in the desired state
+func (p *Plan) IsEmpty() bool {
+	return len(p.Actions) == 0
+}
+
+// Count returns count of actions of the type, protected actions are not counted
+func (p *Plan) Count(actionType ActionType) int {
+	count := 0
+	for _, action := range p.Actions {
+		if action.Type == actionType && !action.Protected {
+			count++
+		}
+	}
+	return count
+}
+
+// String returns human readable plan, similar to `terraform plan` output
+func (p *Plan) String() string {
+	if p.IsEmpty() {
+		return "No changes, NexentaStor is in the desired state.\n"
+	}
+
+	var buf bytes.Buffer
+	protected := 0
+	for _, action := range p.Actions {
+		fmt.Fprintln(&buf, action)
+		if action.Protected {
+			protected++
+		}
+	}
+
+	fmt.Fprintf(
+		&buf,
+		"\nPlan: %d to create, %d to update, %d to delete, %d protected.\n",
+		p.Count(ActionCreate),
+		p.Count(ActionUpdate),
+		p.Count(ActionDelete),
+		protected,
+	)
+
+	return buf.String()
+}
+
+func (p *Plan) add(action Action) {
+	p.Actions = append(p.Actions, action)
+}
+
+// Reconciler - computes and applies changes to bring NexentaStor filesystems and shares to the desired state
+type Reconciler struct {
+	Provider ns.ProviderInterface
+	Log      logger.Logger
+
+	// If set to `true`, filesystems and shares which are not in the spec are deleted,
+	// otherwise such deletions are reported in the plan as protected and skipped.
+	AllowDelete bool
+}
+
+// Plan compares the spec with the current NexentaStor state and returns actions to apply
+func (r *Reconciler) Plan(spec *Spec) (*Plan, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{}
+
+	// parent filesystems must be created before their children
+	filesystems := append([]FilesystemSpec{}, spec.Filesystems...)
+	sort.SliceStable(filesystems, func(i, j int) bool {
+		return filesystems[i].Path < filesystems[j].Path
+	})
+
+	for _, fs := range filesystems {
+		if err := r.planFilesystem(plan, fs); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, parent := range spec.Parents {
+		if err := r.planUnmanagedChildren(plan, spec, parent); err != nil {
+			return nil, err
+		}
+	}
+
+	return plan, nil
+}
+
+// Apply applies plan actions in order, stops on the first failed action
+// Already existing resources on creation and missing resources on deletion are not errors,
+// so a partially applied plan can be safely planned and applied again.
+func (r *Reconciler) Apply(plan *Plan) error {
+	l := r.Log.WithField("func", "Apply()")
+
+	for _, action := range plan.Actions {
+		if action.Protected {
+			l.Infof("skip: %s", action)
+			continue
+		}
+
+		l.Infof("apply: %s", action)
+		err := action.apply(r.Provider)
+		if action.Type == ActionCreate && ns.IsAlreadyExistNefError(err) {
+			l.Debugf("already exists: %s", action)
+		} else if action.Type == ActionDelete && ns.IsNotExistNefError(err) {
+			l.Debugf("already deleted: %s", action)
+		} else if err != nil {
+			return fmt.Errorf("Failed to %s %s '%s': %s", action.Type, action.Resource, action.Path, err)
+		}
+	}
+
+	return nil
+}
+
+// Reconcile plans the changes and applies them, unless dryRun is set
+func (r *Reconciler) Reconcile(spec *Spec, dryRun bool) (*Plan, error) {
+	plan, err := r.Plan(spec)
+	if err != nil || dryRun {
+		return plan, err
+	}
+
+	return plan, r.Apply(plan)
+}
+
+func (r *Reconciler) planFilesystem(plan *Plan, fs FilesystemSpec) error {
+	current, err := r.Provider.GetFilesystem(fs.Path)
+	if ns.IsNotExistNefError(err) {
+		r.planNewFilesystem(plan, fs)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("Cannot get filesystem '%s': %s", fs.Path, err)
+	}
+
+	if fs.ReferencedQuotaSize != 0 && fs.ReferencedQuotaSize != current.ReferencedQuotaSize {
+		plan.add(Action{
+			Type:     ActionUpdate,
+			Resource: ResourceFilesystem,
+			Path:     fs.Path,
+			Details:  fmt.Sprintf("referencedQuotaSize: %d -> %d", current.ReferencedQuotaSize, fs.ReferencedQuotaSize),
+			apply: func(p ns.ProviderInterface) error {
+				return p.UpdateFilesystem(fs.Path, ns.UpdateFilesystemParams{
+					ReferencedQuotaSize: fs.ReferencedQuotaSize,
+				})
+			},
+		})
+	}
+
+	if err := r.planACL(plan, fs); err != nil {
+		return err
+	}
+
+	if fs.Nfs != nil && !current.SharedOverNfs {
+		plan.add(createNfsShareAction(fs))
+	} else if fs.Nfs != nil {
+		if err := r.planNfsShareUpdate(plan, fs); err != nil {
+			return err
+		}
+	} else if current.SharedOverNfs {
+		plan.add(r.deleteAction(ResourceNfsShare, fs.Path, "", func(p ns.ProviderInterface) error {
+			return p.DeleteNfsShare(fs.Path)
+		}))
+	}
+
+	if fs.Smb != nil && !current.SharedOverSmb {
+		plan.add(createSmbShareAction(fs))
+	} else if fs.Smb != nil {
+		shareName, err := r.Provider.GetSmbShareName(fs.Path)
+		if err != nil {
+			return fmt.Errorf("Cannot get SMB share name of '%s': %s", fs.Path, err)
+		}
+
+		expectedShareName := fs.Smb.ShareName
+		if expectedShareName == "" {
+			expectedShareName = current.GetDefaultSmbShareName()
+		}
+
+		if shareName != expectedShareName {
+			plan.add(Action{
+				Type:     ActionUpdate,
+				Resource: ResourceSmbShare,
+				Path:     fs.Path,
+				Details:  fmt.Sprintf("shareName: %s -> %s", shareName, expectedShareName),
+				apply: func(p ns.ProviderInterface) error {
+					if err := p.DeleteSmbShare(fs.Path); err != nil && !ns.IsNotExistNefError(err) {
+						return err
+					}
+					return p.CreateSmbShare(ns.CreateSmbShareParams{
+						Filesystem: fs.Path,
+						ShareName:  fs.Smb.ShareName,
+					})
+				},
+			})
+		}
+	} else if current.SharedOverSmb {
+		plan.add(r.deleteAction(ResourceSmbShare, fs.Path, "", func(p ns.ProviderInterface) error {
+			return p.DeleteSmbShare(fs.Path)
+		}))
+	}
+
+	return nil
+}
+
+func (r *Reconciler) planNewFilesystem(plan *Plan, fs FilesystemSpec) {
+	details := ""
+	if fs.ReferencedQuotaSize != 0 {
+		details = fmt.Sprintf("referencedQuotaSize: %d", fs.ReferencedQuotaSize)
+	}
+
+	plan.add(Action{
+		Type:     ActionCreate,
+		Resource: ResourceFilesystem,
+		Path:     fs.Path,
+		Details:  details,
+		apply: func(p ns.ProviderInterface) error {
+			return p.CreateFilesystem(ns.CreateFilesystemParams{
+				Path:                fs.Path,
+				ReferencedQuotaSize: fs.ReferencedQuotaSize,
+			})
+		},
+	})
+
+	if fs.ACL != "" {
+		aclRuleSet := aclRuleSets[fs.ACL]
+		plan.add(Action{
+			Type:     ActionCreate,
+			Resource: ResourceACL,
+			Path:     fs.Path,
+			Details:  fs.ACL,
+			apply: func(p ns.ProviderInterface) error {
+				return p.SetFilesystemACL(fs.Path, aclRuleSet)
+			},
+		})
+	}
+
+	if fs.Nfs != nil {
+		plan.add(createNfsShareAction(fs))
+	}
+
+	if fs.Smb != nil {
+		plan.add(createSmbShareAction(fs))
+	}
+}
+
+// planACL plans setting ACL rule set of an existing filesystem, ACL isn't managed if the spec has no rule set
+func (r *Reconciler) planACL(plan *Plan, fs FilesystemSpec) error {
+	if fs.ACL == "" {
+		return nil
+	}
+
+	rules, err := r.Provider.GetFilesystemACL(fs.Path)
+	if err != nil {
+		return fmt.Errorf("Cannot get ACL of '%s': %s", fs.Path, err)
+	}
+
+	var current []string
+	for _, rule := range rules {
+		if ruleSet, ok := rule.RuleSet(); ok {
+			current = append(current, aclRuleSetNames[ruleSet])
+		}
+	}
+	if len(current) == 1 && current[0] == fs.ACL {
+		return nil
+	}
+
+	currentDetails := "none"
+	if len(current) != 0 {
+		currentDetails = strings.Join(current, ",")
+	}
+
+	aclRuleSet := aclRuleSets[fs.ACL]
+	plan.add(Action{
+		Type:     ActionUpdate,
+		Resource: ResourceACL,
+		Path:     fs.Path,
+		Details:  fmt.Sprintf("%s -> %s", currentDetails, fs.ACL),
+		apply: func(p ns.ProviderInterface) error {
+			return setACLRuleSet(p, fs.Path, aclRuleSet)
+		},
+	})
+
+	return nil
+}
+
+// planNfsShareUpdate plans re-creation of NFS share which rules differ from the spec
+func (r *Reconciler) planNfsShareUpdate(plan *Plan, fs FilesystemSpec) error {
+	share, err := r.Provider.GetNfsShare(fs.Path)
+	if err != nil {
+		return fmt.Errorf("Cannot get NFS share of '%s': %s", fs.Path, err)
+	}
+
+	params := nfsShareParams(fs)
+	expected := params.SecurityContext()
+	if len(share.SecurityContexts) == 1 && equalNfsSecurityContexts(share.SecurityContexts[0], expected) {
+		return nil
+	}
+
+	plan.add(Action{
+		Type:     ActionUpdate,
+		Resource: ResourceNfsShare,
+		Path:     fs.Path,
+		Details: fmt.Sprintf(
+			"%s -> %s",
+			formatNfsSecurityContexts(share.SecurityContexts),
+			formatNfsSecurityContexts([]ns.NfsSecurityContext{expected}),
+		),
+		apply: func(p ns.ProviderInterface) error {
+			if err := p.DeleteNfsShare(fs.Path); err != nil && !ns.IsNotExistNefError(err) {
+				return err
+			}
+			return p.CreateNfsShare(params)
+		},
+	})
+
+	return nil
+}
+
+// planUnmanagedChildren plans deletion of parent's children which are not in the spec
+func (r *Reconciler) planUnmanagedChildren(plan *Plan, spec *Spec, parent string) error {
+	children, err := r.Provider.GetFilesystems(parent)
+	if ns.IsNotExistNefError(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("Cannot get children of '%s': %s", parent, err)
+	}
+
+	for _, child := range children {
+		if isManaged(spec, child.Path) {
+			continue
+		}
+
+		path := child.Path
+		plan.add(r.deleteAction(ResourceFilesystem, path, "with snapshots", func(p ns.ProviderInterface) error {
+			return p.DestroyFilesystem(path, ns.DestroyFilesystemParams{
+				DestroySnapshots:               true,
+				PromoteMostRecentCloneIfExists: true,
+			})
+		}))
+	}
+
+	return nil
+}
+
+func (r *Reconciler) deleteAction(resource, path, details string, apply func(p ns.ProviderInterface) error) Action {
+	return Action{
+		Type:      ActionDelete,
+		Resource:  resource,
+		Path:      path,
+		Details:   details,
+		Protected: !r.AllowDelete,
+		apply:     apply,
+	}
+}
+
+func createNfsShareAction(fs FilesystemSpec) Action {
+	return Action{
+		Type:     ActionCreate,
+		Resource: ResourceNfsShare,
+		Path:     fs.Path,
+		apply: func(p ns.ProviderInterface) error {
+			return p.CreateNfsShare(nfsShareParams(fs))
+		},
+	}
+}
+
+func nfsShareParams(fs FilesystemSpec) ns.CreateNfsShareParams {
+	return ns.CreateNfsShareParams{
+		Filesystem:    fs.Path,
+		ReadWriteList: fs.Nfs.ReadWriteList,
+		ReadOnlyList:  fs.Nfs.ReadOnlyList,
+	}
+}
+
+// equalNfsSecurityContexts compares security modes and rules ignoring their order
+func equalNfsSecurityContexts(a, b ns.NfsSecurityContext) bool {
+	return equalStringSets(a.SecurityModes, b.SecurityModes) &&
+		equalStringSets(formatNfsRules(a.ReadWriteList), formatNfsRules(b.ReadWriteList)) &&
+		equalStringSets(formatNfsRules(a.ReadOnlyList), formatNfsRules(b.ReadOnlyList))
+}
+
+func equalStringSets(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string{}, a...)
+	b = append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// formatNfsRules returns rule entities, networks with their masks, e.g. "10.3.0.0/16"
+func formatNfsRules(rules []ns.NfsRuleList) []string {
+	entities := make([]string, len(rules))
+	for i, rule := range rules {
+		entities[i] = rule.Entity
+		if rule.Etype == "network" {
+			entities[i] = fmt.Sprintf("%s/%d", rule.Entity, rule.Mask)
+		}
+	}
+	return entities
+}
+
+// formatNfsSecurityContexts returns security contexts for plan details, e.g. "sys rw=* ro=none"
+func formatNfsSecurityContexts(contexts []ns.NfsSecurityContext) string {
+	if len(contexts) == 0 {
+		return "none"
+	}
+
+	formatted := make([]string, len(contexts))
+	for i, context := range contexts {
+		formatted[i] = fmt.Sprintf(
+			"%s rw=%s ro=%s",
+			strings.Join(context.SecurityModes, ","),
+			strings.Join(formatNfsRules(context.ReadWriteList), ","),
+			strings.Join(formatNfsRules(context.ReadOnlyList), ","),
+		)
+	}
+	return strings.Join(formatted, "; ")
+}
+
+// setACLRuleSet removes entries of other rule sets and duplicates from filesystem ACL,
+// the rule set entry is added if it's missing
+func setACLRuleSet(p ns.ProviderInterface, path string, aclRuleSet ns.ACLRuleSet) error {
+	rules, err := p.GetFilesystemACL(path)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	var stale []int
+	for _, rule := range rules {
+		if ruleSet, ok := rule.RuleSet(); !ok {
+			continue
+		} else if ruleSet == aclRuleSet && !found {
+			found = true
+		} else {
+			stale = append(stale, rule.Index)
+		}
+	}
+
+	// following entries are shifted on deletion
+	sort.Sort(sort.Reverse(sort.IntSlice(stale)))
+	for _, index := range stale {
+		if err := p.DeleteFilesystemACLRule(path, index); err != nil {
+			return err
+		}
+	}
+
+	if found {
+		return nil
+	}
+	return p.SetFilesystemACL(path, aclRuleSet)
+}
+
+func createSmbShareAction(fs FilesystemSpec) Action {
+	return Action{
+		Type:     ActionCreate,
+		Resource: ResourceSmbShare,
+		Path:     fs.Path,
+		Details:  fs.Smb.ShareName,
+		apply: func(p ns.ProviderInterface) error {
+			return p.CreateSmbShare(ns.CreateSmbShareParams{
+				Filesystem: fs.Path,
+				ShareName:  fs.Smb.ShareName,
+			})
+		},
+	}
+}
+
+// isManaged returns true if the path or any of its descendants is in the spec
+func isManaged(spec *Spec, path string) bool {
+	for _, fs := range spec.Filesystems {
+		if fs.Path == path || strings.HasPrefix(fs.Path, path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ReconcilerArgs - params to create Reconciler instance
+type ReconcilerArgs struct {
+	Provider    ns.ProviderInterface
+	AllowDelete bool
+
+	// Log - logrus entry to write to, not used if Logger is set
+	Log *logrus.Entry
+
+	// Logger - logger to write to, see ns.ProviderArgs
+	Logger logger.Logger
+}
+
+// NewReconciler creates desired state reconciler for NexentaStor provider
+func NewReconciler(args ReconcilerArgs) (*Reconciler, error) {
+	if args.Provider == nil {
+		return nil, fmt.Errorf("NexentaStor provider not specified")
+	}
+
+	return &Reconciler{
+		Provider:    args.Provider,
+		Log:         logger.Pick(args.Logger, args.Log).WithField("cmp", "Reconciler"),
+		AllowDelete: args.AllowDelete,
+	}, nil
+}
@@ -0,0 +1,145 @@
+package reconcile
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+)
+
+// ACL rule set names used in the spec
+const (
+	ACLReadOnly  = "readOnly"
+	ACLReadWrite = "readWrite"
+)
+
+// ACL rule sets by their names in the spec
+var aclRuleSets = map[string]ns.ACLRuleSet{
+	ACLReadOnly:  ns.ACLReadOnly,
+	ACLReadWrite: ns.ACLReadWrite,
+}
+
+var aclRuleSetNames = map[ns.ACLRuleSet]string{
+	ns.ACLReadOnly:  ACLReadOnly,
+	ns.ACLReadWrite: ACLReadWrite,
+}
+
+// Spec - desired state of NexentaStor filesystems and shares
+type Spec struct {
+	// filesystems under these parents are managed by the spec:
+	// children that are not in the spec get deleted (see Reconciler.AllowDelete)
+	Parents []string `yaml:"parents"`
+
+	Filesystems []FilesystemSpec `yaml:"filesystems"`
+}
+
+// FilesystemSpec - desired state of a filesystem
+type FilesystemSpec struct {
+	// filesystem path w/o leading slash
+	Path string `yaml:"path"`
+
+	// filesystem referenced quota size in bytes, quota isn't managed if not set
+	ReferencedQuotaSize int64 `yaml:"referencedQuotaSize"`
+
+	// NFS share, filesystem must not be shared over NFS if not set
+	Nfs *NfsShareSpec `yaml:"nfs"`
+
+	// SMB share, filesystem must not be shared over SMB if not set
+	Smb *SmbShareSpec `yaml:"smb"`
+
+	// ACL rule set: "readOnly" or "readWrite", ACL isn't managed if not set;
+	// everyone@ entries of the other rule set are removed from existing filesystems (see ns.ACLRule.RuleSet)
+	ACL string `yaml:"acl"`
+}
+
+// NfsShareSpec - desired state of NFS share
+type NfsShareSpec struct {
+	ReadWriteList []ns.NfsRuleList `yaml:"readWriteList"`
+	ReadOnlyList  []ns.NfsRuleList `yaml:"readOnlyList"`
+}
+
+// SmbShareSpec - desired state of SMB share
+type SmbShareSpec struct {
+	// share name, default one is generated if not set (see Filesystem.GetDefaultSmbShareName)
+	ShareName string `yaml:"shareName"`
+}
+
+// LoadSpec reads spec from YAML or JSON file
+func LoadSpec(file string) (*Spec, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read spec file '%s': %s", file, err)
+	}
+
+	spec, err := ParseSpec(data)
+	if err != nil {
+		return nil, fmt.Errorf("Spec file '%s': %s", file, err)
+	}
+
+	return spec, nil
+}
+
+// ParseSpec parses spec from YAML or JSON and validates it
+func ParseSpec(data []byte) (*Spec, error) {
+	spec := &Spec{}
+	if err := yaml.UnmarshalStrict(data, spec); err != nil {
+		return nil, fmt.Errorf("Cannot parse spec: %s", err)
+	}
+
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	return spec, nil
+}
+
+// Validate checks that spec paths are unique and well formed
+func (s *Spec) Validate() error {
+	for _, parent := range s.Parents {
+		if err := validatePath(parent); err != nil {
+			return fmt.Errorf("Invalid parent '%s': %s", parent, err)
+		}
+	}
+
+	paths := map[string]bool{}
+	for _, fs := range s.Filesystems {
+		if err := validatePath(fs.Path); err != nil {
+			return fmt.Errorf("Invalid filesystem path '%s': %s", fs.Path, err)
+		} else if paths[fs.Path] {
+			return fmt.Errorf("Filesystem '%s' is specified more than once", fs.Path)
+		} else if fs.ReferencedQuotaSize < 0 {
+			return fmt.Errorf("Filesystem '%s': referenced quota size cannot be negative", fs.Path)
+		}
+
+		switch fs.ACL {
+		case "", ACLReadOnly, ACLReadWrite:
+		default:
+			return fmt.Errorf(
+				"Filesystem '%s': unknown ACL '%s', expected '%s' or '%s'",
+				fs.Path,
+				fs.ACL,
+				ACLReadOnly,
+				ACLReadWrite,
+			)
+		}
+
+		paths[fs.Path] = true
+	}
+
+	return nil
+}
+
+func validatePath(p string) error {
+	if p == "" {
+		return fmt.Errorf("path is empty")
+	} else if strings.HasPrefix(p, "/") || strings.HasSuffix(p, "/") {
+		return fmt.Errorf("path must not start or end with '/'")
+	} else if path.Clean(p) != p {
+		return fmt.Errorf("path is not clean")
+	}
+	return nil
+}
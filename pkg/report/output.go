@@ -0,0 +1,95 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteDatasetsCSV writes datasets usage as CSV with a header line, user properties are not written
+func (r *Report) WriteDatasetsCSV(w io.Writer) error {
+	rows := [][]string{{
+		"path",
+		"type",
+		"group",
+		"bytesUsed",
+		"bytesAvailable",
+		"bytesReferenced",
+		"bytesUsedBySnapshots",
+		"compressionRatio",
+		"quotaSize",
+		"quotaUtilization",
+		"overThreshold",
+	}}
+
+	for _, dataset := range r.Datasets {
+		rows = append(rows, []string{
+			dataset.Path,
+			dataset.Type,
+			dataset.Group,
+			formatInt(dataset.BytesUsed),
+			formatInt(dataset.BytesAvailable),
+			formatInt(dataset.BytesReferenced),
+			formatInt(dataset.BytesUsedBySnapshots),
+			formatRatio(dataset.CompressionRatio),
+			formatInt(dataset.QuotaSize),
+			formatRatio(dataset.QuotaUtilization),
+			strconv.FormatBool(dataset.OverThreshold),
+		})
+	}
+
+	return writeCSV(w, rows)
+}
+
+// WriteGroupsCSV writes groups usage as CSV with a header line
+func (r *Report) WriteGroupsCSV(w io.Writer) error {
+	rows := [][]string{{
+		"group",
+		"datasets",
+		"overThreshold",
+		"bytesUsed",
+		"bytesAvailable",
+		"bytesUsedBySnapshots",
+		"quotaSize",
+		"compressionRatio",
+	}}
+
+	for _, group := range r.Groups {
+		rows = append(rows, []string{
+			group.Key,
+			strconv.Itoa(group.Datasets),
+			strconv.Itoa(group.OverThreshold),
+			formatInt(group.BytesUsed),
+			formatInt(group.BytesAvailable),
+			formatInt(group.BytesUsedBySnapshots),
+			formatInt(group.QuotaSize),
+			formatRatio(group.CompressionRatio),
+		})
+	}
+
+	return writeCSV(w, rows)
+}
+
+func writeCSV(w io.Writer, rows [][]string) error {
+	writer := csv.NewWriter(w)
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+func formatInt(value int64) string {
+	return strconv.FormatInt(value, 10)
+}
+
+func formatRatio(value float64) string {
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
@@ -0,0 +1,575 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Nexenta/go-nexentastor/pkg/logger"
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+)
+
+// default count of concurrent list requests
+const defaultConcurrency = 10
+
+// default quota utilization to flag datasets at, 90%
+const defaultQuotaThreshold = 0.9
+
+// Dataset types
+const (
+	TypeFilesystem = "filesystem"
+	TypeVolume     = "volume"
+)
+
+// fields requested for each dataset, usage properties are not in the default field set
+var (
+	filesystemFields = []string{
+		"path",
+		"bytesAvailable",
+		"bytesUsed",
+		"bytesReferenced",
+		"bytesUsedBySnapshots",
+		"referencedQuotaSize",
+		"compressionRatio",
+		"userProperties",
+	}
+	volumeFields = []string{
+		"path",
+		"bytesAvailable",
+		"bytesUsed",
+		"bytesReferenced",
+		"bytesUsedBySnapshots",
+		"volumeSize",
+		"compressionRatio",
+		"userProperties",
+	}
+)
+
+// Pool - space usage of the pool root filesystem
+type Pool struct {
+	Name           string `json:"name"`
+	BytesUsed      int64  `json:"bytesUsed"`
+	BytesAvailable int64  `json:"bytesAvailable"`
+}
+
+// Dataset - space usage of one filesystem or volume
+type Dataset struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+
+	// group key, empty if the dataset is not in any group
+	Group string `json:"group,omitempty"`
+
+	BytesUsed            int64   `json:"bytesUsed"`
+	BytesAvailable       int64   `json:"bytesAvailable"`
+	BytesReferenced      int64   `json:"bytesReferenced"`
+	BytesUsedBySnapshots int64   `json:"bytesUsedBySnapshots"`
+	CompressionRatio     float64 `json:"compressionRatio"`
+
+	// referenced quota of filesystem or size of volume, 0 if there is no quota
+	QuotaSize int64 `json:"quotaSize"`
+
+	// referenced bytes to quota ratio, 0 if there is no quota
+	QuotaUtilization float64 `json:"quotaUtilization"`
+
+	// quota utilization is not less than Report.QuotaThreshold
+	OverThreshold bool `json:"overThreshold"`
+
+	UserProperties map[string]string `json:"userProperties,omitempty"`
+}
+
+// Group - aggregated space usage of datasets with the same group key
+//
+// Used space of a dataset includes its descendants, so used, available and quota sizes are summed
+// for the top datasets of the group only (datasets without an ancestor in the same group).
+// Snapshot usage doesn't include descendants and is summed for all datasets of the group.
+type Group struct {
+	Key string `json:"key"`
+
+	// count of datasets in the group and count of datasets over quota threshold
+	Datasets      int `json:"datasets"`
+	OverThreshold int `json:"overThreshold"`
+
+	BytesUsed            int64 `json:"bytesUsed"`
+	BytesAvailable       int64 `json:"bytesAvailable"`
+	BytesUsedBySnapshots int64 `json:"bytesUsedBySnapshots"`
+	QuotaSize            int64 `json:"quotaSize"`
+
+	// compression ratio of the top datasets weighted by used space
+	CompressionRatio float64 `json:"compressionRatio"`
+}
+
+// Report - space usage of pools and datasets
+type Report struct {
+	Time           time.Time `json:"time"`
+	QuotaThreshold float64   `json:"quotaThreshold"`
+	Pools          []Pool    `json:"pools"`
+
+	// datasets sorted by path
+	Datasets []Dataset `json:"datasets"`
+
+	// groups sorted by key, empty if Reporter.GroupBy is not set
+	Groups []Group `json:"groups"`
+}
+
+// Flagged returns datasets with quota utilization over the threshold
+func (r *Report) Flagged() []Dataset {
+	flagged := []Dataset{}
+	for _, dataset := range r.Datasets {
+		if dataset.OverThreshold {
+			flagged = append(flagged, dataset)
+		}
+	}
+	return flagged
+}
+
+// GroupFunc returns group key of the dataset, empty key leaves the dataset out of groups
+type GroupFunc func(dataset Dataset) string
+
+// GroupByPathPrefix groups datasets by the longest matching path prefix,
+// prefix matches the dataset itself and its descendants, e.g. "pool/tenants/a"
+func GroupByPathPrefix(prefixes ...string) GroupFunc {
+	return func(dataset Dataset) string {
+		key := ""
+		for _, prefix := range prefixes {
+			prefix = strings.TrimSuffix(prefix, "/")
+			if (dataset.Path == prefix || strings.HasPrefix(dataset.Path, prefix+"/")) && len(prefix) > len(key) {
+				key = prefix
+			}
+		}
+		return key
+	}
+}
+
+// GroupByUserProperty groups datasets by value of the user property, e.g. "com.example:tenant"
+func GroupByUserProperty(name string) GroupFunc {
+	return func(dataset Dataset) string {
+		return dataset.UserProperties[name]
+	}
+}
+
+// Reporter - collects space usage reports of NexentaStor datasets,
+// NewReporter() validates the settings, but zero values of the fields are usable as well
+type Reporter struct {
+	Provider ns.ProviderInterface
+
+	// nothing is logged if not set
+	Log logger.Logger
+
+	// filesystems to report descendants of, all pools if empty
+	Parents []string
+
+	// volumeGroups to report volumes of, all volumeGroups of the pools under Parents if empty
+	VolumeGroups []string
+
+	// maximum count of concurrent list requests, defaultConcurrency if not set
+	Concurrency int
+
+	// quota utilization to flag datasets at, from 0 to 1, defaultQuotaThreshold if not set
+	QuotaThreshold float64
+
+	// datasets aggregation, no groups are reported if not set
+	GroupBy GroupFunc
+
+	// clock for report time, time.Now if not set
+	now func() time.Time
+}
+
+// Generate walks dataset trees and returns the report, the first request error stops the walk
+func (r *Reporter) Generate(ctx context.Context) (*Report, error) {
+	if r.Provider == nil {
+		return nil, fmt.Errorf("NexentaStor provider not specified")
+	}
+
+	log := r.Log
+	if log == nil {
+		log = logger.Nop{}
+	}
+	l := log.WithField("func", "Generate()")
+
+	now := r.now
+	if now == nil {
+		now = time.Now
+	}
+
+	quotaThreshold := r.QuotaThreshold
+	if quotaThreshold <= 0 {
+		quotaThreshold = defaultQuotaThreshold
+	}
+
+	parents := r.Parents
+	if len(parents) == 0 {
+		pools, err := r.Provider.GetPools()
+		if err != nil {
+			return nil, err
+		}
+		for _, pool := range pools {
+			parents = append(parents, pool.Name)
+		}
+	}
+
+	report := &Report{
+		Time:           now(),
+		QuotaThreshold: quotaThreshold,
+		Pools:          []Pool{},
+		Groups:         []Group{},
+	}
+
+	poolNames := map[string]bool{}
+	for _, parent := range parents {
+		poolNames[strings.Split(parent, "/")[0]] = true
+	}
+	for _, path := range r.VolumeGroups {
+		poolNames[strings.Split(path, "/")[0]] = true
+	}
+
+	volumeGroups := r.VolumeGroups
+	if len(volumeGroups) == 0 {
+		var err error
+		if volumeGroups, err = r.discoverVolumeGroups(poolNames, parents); err != nil {
+			return nil, err
+		}
+	}
+
+	for name := range poolNames {
+		fs, err := r.Provider.GetFilesystem(name)
+		if err != nil {
+			return nil, err
+		}
+		report.Pools = append(report.Pools, Pool{
+			Name:           name,
+			BytesUsed:      fs.BytesUsed,
+			BytesAvailable: fs.BytesAvailable,
+		})
+	}
+	sort.Slice(report.Pools, func(i, j int) bool { return report.Pools[i].Name < report.Pools[j].Name })
+
+	w := newWalker(ctx, r)
+	for _, parent := range parents {
+		w.walkFilesystems(parent)
+	}
+	for _, volumeGroup := range volumeGroups {
+		w.walkVolumes(volumeGroup)
+	}
+	datasets, err := w.wait()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(datasets, func(i, j int) bool { return datasets[i].Path < datasets[j].Path })
+	for i := range datasets {
+		setQuotaUtilization(&datasets[i], quotaThreshold)
+		if r.GroupBy != nil {
+			datasets[i].Group = r.GroupBy(datasets[i])
+		}
+	}
+	report.Datasets = datasets
+	if r.GroupBy != nil {
+		report.Groups = aggregate(datasets)
+	}
+
+	l.Debugf(
+		"%d datasets in %d groups, %d over quota threshold",
+		len(report.Datasets),
+		len(report.Groups),
+		len(report.Flagged()),
+	)
+
+	return report, nil
+}
+
+// discoverVolumeGroups returns volumeGroups of the pools which are parents themselves or are under parents
+func (r *Reporter) discoverVolumeGroups(poolNames map[string]bool, parents []string) ([]string, error) {
+	volumeGroups := []string{}
+	for pool := range poolNames {
+		poolVolumeGroups, err := r.Provider.GetVolumeGroups(pool)
+		if err != nil {
+			return nil, err
+		}
+		for _, volumeGroup := range poolVolumeGroups {
+			for _, parent := range parents {
+				if strings.HasPrefix(volumeGroup.Path, strings.TrimSuffix(parent, "/")+"/") {
+					volumeGroups = append(volumeGroups, volumeGroup.Path)
+					break
+				}
+			}
+		}
+	}
+	sort.Strings(volumeGroups)
+	return volumeGroups, nil
+}
+
+func setQuotaUtilization(dataset *Dataset, quotaThreshold float64) {
+	if dataset.QuotaSize <= 0 {
+		return
+	}
+
+	// referenced bytes may be missing on older NexentaStor versions
+	used := dataset.BytesReferenced
+	if used == 0 {
+		used = dataset.BytesUsed
+	}
+
+	dataset.QuotaUtilization = float64(used) / float64(dataset.QuotaSize)
+	dataset.OverThreshold = dataset.QuotaUtilization >= quotaThreshold
+}
+
+// aggregate sums usage of sorted datasets by group keys
+func aggregate(datasets []Dataset) []Group {
+	groups := map[string]*Group{}
+	pathGroups := make(map[string]string, len(datasets))
+	weightedRatios := map[string]float64{}
+
+	for _, dataset := range datasets {
+		pathGroups[dataset.Path] = dataset.Group
+		if dataset.Group == "" {
+			continue
+		}
+
+		group, ok := groups[dataset.Group]
+		if !ok {
+			group = &Group{Key: dataset.Group}
+			groups[dataset.Group] = group
+		}
+
+		group.Datasets++
+		group.BytesUsedBySnapshots += dataset.BytesUsedBySnapshots
+		if dataset.OverThreshold {
+			group.OverThreshold++
+		}
+
+		if hasAncestorInGroup(pathGroups, dataset.Path, dataset.Group) {
+			continue
+		}
+		group.BytesUsed += dataset.BytesUsed
+		group.BytesAvailable += dataset.BytesAvailable
+		group.QuotaSize += dataset.QuotaSize
+		weightedRatios[dataset.Group] += dataset.CompressionRatio * float64(dataset.BytesUsed)
+	}
+
+	result := make([]Group, 0, len(groups))
+	for key, group := range groups {
+		if group.BytesUsed > 0 {
+			group.CompressionRatio = weightedRatios[key] / float64(group.BytesUsed)
+		}
+		result = append(result, *group)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+
+	return result
+}
+
+// hasAncestorInGroup returns true if any of already aggregated dataset ancestors is in the group
+func hasAncestorInGroup(pathGroups map[string]string, path, group string) bool {
+	for i := strings.LastIndex(path, "/"); i > 0; i = strings.LastIndex(path, "/") {
+		path = path[:i]
+		if ancestorGroup, ok := pathGroups[path]; ok && ancestorGroup == group {
+			return true
+		}
+	}
+	return false
+}
+
+// walker - lists dataset trees, at most Reporter.Concurrency requests are sent at the same time
+type walker struct {
+	ctx      context.Context
+	reporter *Reporter
+	slots    chan struct{}
+	wg       sync.WaitGroup
+
+	mux      sync.Mutex
+	datasets []Dataset
+	err      error
+}
+
+func newWalker(ctx context.Context, reporter *Reporter) *walker {
+	concurrency := reporter.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	return &walker{
+		ctx:      ctx,
+		reporter: reporter,
+		slots:    make(chan struct{}, concurrency),
+		datasets: []Dataset{},
+	}
+}
+
+// wait blocks until all trees are walked, returns the first error
+func (w *walker) wait() ([]Dataset, error) {
+	w.wg.Wait()
+	return w.datasets, w.err
+}
+
+// walkFilesystems adds children of the filesystem and walks them in new goroutines
+func (w *walker) walkFilesystems(parent string) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		var filesystems []ns.Filesystem
+		err := w.request(func() (err error) {
+			filesystems, err = w.reporter.Provider.ListFilesystems(parent, ns.ListOptions{Fields: filesystemFields})
+			return err
+		})
+		if err != nil {
+			return
+		}
+
+		datasets := make([]Dataset, len(filesystems))
+		for i, fs := range filesystems {
+			datasets[i] = Dataset{
+				Path:                 fs.Path,
+				Type:                 TypeFilesystem,
+				BytesUsed:            fs.BytesUsed,
+				BytesAvailable:       fs.BytesAvailable,
+				BytesReferenced:      fs.BytesReferenced,
+				BytesUsedBySnapshots: fs.BytesUsedBySnapshots,
+				CompressionRatio:     fs.CompressionRatio,
+				QuotaSize:            fs.ReferencedQuotaSize,
+				UserProperties:       fs.UserProperties,
+			}
+		}
+		w.add(datasets)
+
+		for _, fs := range filesystems {
+			w.walkFilesystems(fs.Path)
+		}
+	}()
+}
+
+// walkVolumes adds volumes of the volumeGroup
+func (w *walker) walkVolumes(volumeGroup string) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		var volumes []ns.Volume
+		err := w.request(func() (err error) {
+			volumes, err = w.reporter.Provider.ListVolumes(volumeGroup, ns.ListOptions{Fields: volumeFields})
+			return err
+		})
+		if err != nil {
+			return
+		}
+
+		datasets := make([]Dataset, len(volumes))
+		for i, volume := range volumes {
+			datasets[i] = Dataset{
+				Path:                 volume.Path,
+				Type:                 TypeVolume,
+				BytesUsed:            volume.BytesUsed,
+				BytesAvailable:       volume.BytesAvailable,
+				BytesReferenced:      volume.BytesReferenced,
+				BytesUsedBySnapshots: volume.BytesUsedBySnapshots,
+				CompressionRatio:     volume.CompressionRatio,
+				QuotaSize:            volume.VolumeSize,
+				UserProperties:       volume.UserProperties,
+			}
+		}
+		w.add(datasets)
+	}()
+}
+
+// request sends the request when there is a free slot, it's skipped if the walk is already failed
+func (w *walker) request(send func() error) error {
+	select {
+	case w.slots <- struct{}{}:
+	case <-w.ctx.Done():
+		w.fail(w.ctx.Err())
+		return w.ctx.Err()
+	}
+	defer func() { <-w.slots }()
+
+	w.mux.Lock()
+	err := w.err
+	w.mux.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := send(); err != nil {
+		w.fail(err)
+		return err
+	}
+	return nil
+}
+
+func (w *walker) add(datasets []Dataset) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	w.datasets = append(w.datasets, datasets...)
+}
+
+func (w *walker) fail(err error) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+// ReporterArgs - params to create Reporter instance
+type ReporterArgs struct {
+	Provider ns.ProviderInterface
+
+	// Parents - filesystems to report descendants of, e.g. "pool/tenants", all pools if not set
+	Parents []string
+
+	// VolumeGroups - volumeGroups to report volumes of, all volumeGroups under Parents if not set
+	VolumeGroups []string
+
+	// Concurrency - maximum count of concurrent list requests, defaultConcurrency if not set
+	Concurrency int
+
+	// QuotaThreshold - quota utilization to flag datasets at, from 0 to 1, defaultQuotaThreshold if not set
+	QuotaThreshold float64
+
+	// GroupBy - datasets aggregation, see GroupByPathPrefix() and GroupByUserProperty()
+	GroupBy GroupFunc
+
+	// Log - logrus entry to write to, not used if Logger is set
+	Log *logrus.Entry
+
+	// Logger - logger to write to, see ns.ProviderArgs
+	Logger logger.Logger
+
+	// Now - clock for report time, time.Now if not set
+	Now func() time.Time
+}
+
+// NewReporter creates space usage reporter for NexentaStor provider
+func NewReporter(args ReporterArgs) (*Reporter, error) {
+	if args.Provider == nil {
+		return nil, fmt.Errorf("NexentaStor provider not specified")
+	}
+
+	quotaThreshold := args.QuotaThreshold
+	if quotaThreshold == 0 {
+		quotaThreshold = defaultQuotaThreshold
+	} else if quotaThreshold < 0 || quotaThreshold > 1 {
+		return nil, fmt.Errorf("Quota threshold must be between 0 and 1, got: %v", quotaThreshold)
+	}
+
+	now := args.Now
+	if now == nil {
+		now = time.Now
+	}
+
+	return &Reporter{
+		Provider:       args.Provider,
+		Log:            logger.Pick(args.Logger, args.Log).WithField("cmp", "Reporter"),
+		Parents:        args.Parents,
+		VolumeGroups:   args.VolumeGroups,
+		Concurrency:    args.Concurrency,
+		QuotaThreshold: quotaThreshold,
+		GroupBy:        args.GroupBy,
+		now:            now,
+	}, nil
+}
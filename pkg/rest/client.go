@@ -1,6 +1,7 @@
 package rest
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
@@ -8,24 +9,43 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Nexenta/go-nexentastor/pkg/logger"
+	"github.com/Nexenta/go-nexentastor/pkg/metrics"
 )
 
-const requestTimeout = 30 * time.Second
+const (
+	requestTimeout         = 30 * time.Second
+	defaultDialTimeout     = 30 * time.Second
+	defaultKeepAlive       = 30 * time.Second
+	defaultIdleConnTimeout = 60 * time.Second
+	tlsHandshakeTimeout    = 10 * time.Second
+)
 
 // Client - request client for any REST API
 type Client struct {
 	address    string
-	authToken  string
 	httpClient *http.Client
-	log        *logrus.Entry
+	timeout    time.Duration
+	log        logger.Logger
+	metrics    metrics.Metrics
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
 
 	mux       sync.Mutex
 	requestID int64
+
+	// auth token is replaced by re-login while other requests are being sent
+	authTokenMux sync.RWMutex
+	authToken    string
 }
 
 // ClientInterface - request client interface
@@ -35,6 +55,13 @@ type ClientInterface interface {
 	SetAuthToken(token string)
 }
 
+// ContextClient - request client which cancels requests with the context, implemented by Client
+// Custom ClientInterface implementations without it send requests with Send().
+type ContextClient interface {
+	ClientInterface
+	SendWithContext(ctx context.Context, method, path string, data interface{}) (int, []byte, error)
+}
+
 // BuildURI builds request URI using [path?params...] format
 func (c *Client) BuildURI(uri string, params map[string]string) string {
 	paramsStr := ""
@@ -57,18 +84,42 @@ func (c *Client) BuildURI(uri string, params map[string]string) string {
 // Send sends request to REST server
 // data interface{} - request payload, any interface for json.Marshal()
 func (c *Client) Send(method, path string, data interface{}) (int, []byte, error) {
+	return c.SendWithContext(context.Background(), method, path, data)
+}
+
+// SendWithContext sends request to REST server, the request is canceled with the context
+// Client timeout is applied only if the context has no deadline, so the deadline overrides the timeout.
+func (c *Client) SendWithContext(ctx context.Context, method, path string, data interface{}) (int, []byte, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
 	c.mux.Lock()
 	c.requestID++
-	l := c.log.WithFields(logrus.Fields{
+	requestID := c.requestID
+	c.mux.Unlock()
+
+	l := c.log.WithFields(logger.Fields{
 		"func":  "Send()",
 		"req":   fmt.Sprintf("%s %s", method, path),
-		"reqID": c.requestID,
+		"reqID": requestID,
 	})
-	c.mux.Unlock()
+
+	ctx, span := c.startSpan(ctx, method, path, requestID)
+	statusCode, bodyBytes, err := c.send(ctx, l, method, path, data)
+	endSpan(span, statusCode, err)
+
+	return statusCode, bodyBytes, err
+}
+
+// send sends request and reads response body
+func (c *Client) send(ctx context.Context, l logger.Logger, method, path string, data interface{}) (int, []byte, error) {
 
 	uri := fmt.Sprintf("%s/%s", c.address, path)
 
-	l.Debug("send request")
+	l.Debugf("send request")
 
 	// send request data as json
 	var jsonDataReader io.Reader
@@ -78,7 +129,7 @@ func (c *Client) Send(method, path string, data interface{}) (int, []byte, error
 			return 0, nil, err
 		}
 		jsonDataReader = strings.NewReader(string(jsonData))
-		l.Debugf("data: %+v", data) //TODO hide passwords
+		l.Debugf("data: %+v", data) // secret fields are masked by the logger
 	}
 
 	req, err := http.NewRequest(method, uri, jsonDataReader)
@@ -86,15 +137,19 @@ func (c *Client) Send(method, path string, data interface{}) (int, []byte, error
 		l.Errorf("request creation error: %s", err)
 		return 0, nil, err
 	}
+	req = req.WithContext(ctx)
+	c.injectTraceContext(ctx, req)
 
 	req.Header.Set("Content-Type", "application/json")
-	if len(c.authToken) != 0 {
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.authToken))
+	if authToken := c.getAuthToken(); len(authToken) != 0 {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", authToken))
 	}
 
+	startTime := time.Now()
 	res, err := c.httpClient.Do(req)
 	if err != nil {
 		l.Debugf("request error: %s", err)
+		c.metrics.ObserveRequest(c.address, method, metrics.EndpointTemplate(path), metrics.CodeError, time.Since(startTime))
 		return 0, nil, err
 	}
 
@@ -104,6 +159,8 @@ func (c *Client) Send(method, path string, data interface{}) (int, []byte, error
 
 	// validate response body
 	bodyBytes, err := ioutil.ReadAll(res.Body)
+	c.metrics.ObserveRequest(
+		c.address, method, metrics.EndpointTemplate(path), strconv.Itoa(res.StatusCode), time.Since(startTime))
 	if err != nil {
 		err = fmt.Errorf("Cannot read body of request '%s %s': '%s'", method, uri, err)
 		return res.StatusCode, nil, err
@@ -114,39 +171,151 @@ func (c *Client) Send(method, path string, data interface{}) (int, []byte, error
 
 // SetAuthToken sets Bearer auth token for all requests
 func (c *Client) SetAuthToken(token string) {
+	c.authTokenMux.Lock()
+	defer c.authTokenMux.Unlock()
 	c.authToken = token
 }
 
+func (c *Client) getAuthToken() string {
+	c.authTokenMux.RLock()
+	defer c.authTokenMux.RUnlock()
+	return c.authToken
+}
+
+// TransportOptions - connection settings of the transport created by NewClient
+type TransportOptions struct {
+	// Proxy - proxy URL, e.g. "http://proxy:3128", $HTTPS_PROXY, $HTTP_PROXY and $NO_PROXY are used if not set
+	Proxy string
+
+	// MaxIdleConnsPerHost - idle connections to keep open, http.DefaultMaxIdleConnsPerHost if not set
+	MaxIdleConnsPerHost int
+
+	// DialTimeout - TCP connection timeout, defaultDialTimeout if not set
+	DialTimeout time.Duration
+
+	// KeepAlive - TCP keep-alive period, defaultKeepAlive if not set
+	KeepAlive time.Duration
+
+	// ResponseHeaderTimeout - time to wait for response headers after the request is sent, no limit if not set
+	ResponseHeaderTimeout time.Duration
+
+	// IdleConnTimeout - time to keep idle connections open, defaultIdleConnTimeout if not set
+	IdleConnTimeout time.Duration
+}
+
 // ClientArgs - params to create Client instance
 type ClientArgs struct {
 	Address string
-	Log     *logrus.Entry
+
+	// Log - logrus entry to write to, not used if Logger is set
+	Log *logrus.Entry
+
+	// Logger - logger to write to, secrets are always redacted, nothing is logged if neither Log nor Logger is set
+	Logger logger.Logger
 
 	// InsecureSkipVerify controls whether a client verifies the server's certificate chain and host name.
 	InsecureSkipVerify bool
+
+	// TLSConfig - base TLS configuration, InsecureSkipVerify and TLS options are applied on top of it
+	TLSConfig *tls.Config
+
+	// TLS - CA bundle, client certificate, certificate pinning and TLS version options
+	TLS TLSOptions
+
+	// Timeout - overall request timeout, requestTimeout if not set, see Client.SendWithContext()
+	Timeout time.Duration
+
+	// Transport - connection settings, not used if custom RoundTripper is set
+	Transport TransportOptions
+
+	// RoundTripper - custom transport to send requests with, TLS and Transport options are not applied to it
+	RoundTripper http.RoundTripper
+
+	// WrapTransport - wraps the transport (created or custom one), e.g. to trace or record requests
+	WrapTransport func(rt http.RoundTripper) http.RoundTripper
+
+	// Metrics - receives request counts and latencies, measurements are dropped if not set
+	Metrics metrics.Metrics
+
+	// TracerProvider - creates a span for each request, no spans are created if not set
+	TracerProvider trace.TracerProvider
+
+	// Propagator - injects trace context into request headers, e.g. propagation.TraceContext{}
+	Propagator propagation.TextMapPropagator
 }
 
-// NewClient creates new REST client
+// NewClient creates new REST client, configuration errors are logged, see NewClientE
+// Requests of the client fail if the configuration is invalid, e.g. with TLS errors or with invalid proxy URL.
 func NewClient(args ClientArgs) ClientInterface {
-	l := args.Log.WithField("cmp", "RestClient")
+	client, err := newClient(args)
+	if err != nil {
+		client.log.Errorf("configuration: %s", err)
+	}
+	return client
+}
 
-	tr := &http.Transport{
-		IdleConnTimeout: 60 * time.Second,
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: args.InsecureSkipVerify,
-		},
+// NewClientE creates new REST client, returns an error if TLS or transport configuration is invalid
+func NewClientE(args ClientArgs) (ClientInterface, error) {
+	client, err := newClient(args)
+	if err != nil {
+		return nil, err
 	}
+	return client, nil
+}
+
+// newClient creates a client even if the configuration is invalid, the client fails requests then
+func newClient(args ClientArgs) (*Client, error) {
+	l := logger.Pick(args.Logger, args.Log).WithField("cmp", "RestClient")
 
+	var err error
+	transport := args.RoundTripper
+	if transport == nil {
+		tlsConfig, tlsErr := newTLSConfig(args.Address, args.TLSConfig, args.InsecureSkipVerify, args.TLS)
+		if tlsErr != nil {
+			err = fmt.Errorf("TLS configuration: %s", tlsErr)
+		}
+
+		tr, trErr := newTransport(tlsConfig, args.Transport)
+		if trErr != nil && err == nil {
+			err = fmt.Errorf("transport configuration: %s", trErr)
+		}
+		transport = tr
+	}
+
+	if args.WrapTransport != nil {
+		transport = args.WrapTransport(transport)
+	}
+
+	timeout := args.Timeout
+	if timeout <= 0 {
+		timeout = requestTimeout
+	}
+
+	// timeout is set to each request context, so it can be overridden per request
 	httpClient := &http.Client{
-		Transport: tr,
-		Timeout:   requestTimeout,
+		Transport: transport,
+	}
+
+	clientMetrics := args.Metrics
+	if clientMetrics == nil {
+		clientMetrics = metrics.Nop{}
+	}
+
+	var tracer trace.Tracer
+	if args.TracerProvider != nil {
+		tracer = args.TracerProvider.Tracer(tracerName)
 	}
 
 	l.Debugf("created for '%s'", args.Address)
-	return &Client{
+	client := &Client{
 		address:    args.Address,
 		httpClient: httpClient,
+		timeout:    timeout,
 		log:        l,
+		metrics:    clientMetrics,
+		tracer:     tracer,
+		propagator: args.Propagator,
 		requestID:  0,
 	}
+	return client, err
 }
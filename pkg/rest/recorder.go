@@ -0,0 +1,388 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/Nexenta/go-nexentastor/pkg/logger"
+)
+
+// RecorderMode - whether Recorder records real exchanges or serves requests from the cassette
+type RecorderMode string
+
+const (
+	// RecorderModeRecord - requests are sent with the wrapped transport and recorded, see Recorder.Save()
+	RecorderModeRecord RecorderMode = "record"
+	// RecorderModeReplay - responses are served from the cassette, nothing is sent
+	RecorderModeReplay RecorderMode = "replay"
+)
+
+// RecorderMatching - how replayed requests are matched to recorded interactions
+type RecorderMatching string
+
+const (
+	// RecorderMatchStrict - requests must come in recorded order with the same method, URI and body
+	RecorderMatchStrict RecorderMatching = "strict"
+	// RecorderMatchLenient - the first not yet replayed interaction with the same method and path is used,
+	// query and body are ignored, the last matching interaction is repeated when all are replayed (e.g. polling)
+	RecorderMatchLenient RecorderMatching = "lenient"
+)
+
+// Cassette - recorded HTTP interactions, stored as JSON file
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction - recorded request and its response
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest - request without address and headers, secrets are scrubbed
+type RecordedRequest struct {
+	Method string `json:"method"`
+	// URI - path and query, e.g. "storage/filesystems?parent=pool"
+	URI string `json:"uri"`
+	// Body - JSON payload, non-JSON payload is stored as JSON string
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+// RecordedResponse - response status code and body, secrets are scrubbed
+type RecordedResponse struct {
+	StatusCode int             `json:"statusCode"`
+	Body       json.RawMessage `json:"body,omitempty"`
+}
+
+// RecorderArgs - params to create Recorder instance
+type RecorderArgs struct {
+	// CassettePath - JSON file to replay interactions from or to save them to
+	CassettePath string
+
+	// Mode - RecorderModeReplay if not set
+	Mode RecorderMode
+
+	// Matching - RecorderMatchStrict if not set
+	Matching RecorderMatching
+}
+
+// Recorder records HTTP exchanges to a cassette or replays them, use Recorder.Wrap as ClientArgs.WrapTransport:
+//
+//	recorder, err := rest.NewRecorder(rest.RecorderArgs{CassettePath: "testdata/pools.json"})
+//	nsp, err := ns.NewProvider(ns.ProviderArgs{Address: "https://10.3.199.254:8443", WrapTransport: recorder.Wrap})
+//
+// Secrets (passwords, tokens, see logger.IsSecretKey()) in JSON bodies and query params are scrubbed when recorded.
+type Recorder struct {
+	path     string
+	mode     RecorderMode
+	matching RecorderMatching
+
+	mux      sync.Mutex
+	cassette Cassette
+	replayed []bool
+	next     int
+	last     map[string]int
+}
+
+// NewRecorder creates Recorder, in replay mode the cassette is loaded
+func NewRecorder(args RecorderArgs) (*Recorder, error) {
+	if args.CassettePath == "" {
+		return nil, fmt.Errorf("Cassette path not specified")
+	}
+
+	mode := args.Mode
+	if mode == "" {
+		mode = RecorderModeReplay
+	} else if mode != RecorderModeRecord && mode != RecorderModeReplay {
+		return nil, fmt.Errorf("Unknown recorder mode: '%s'", mode)
+	}
+
+	matching := args.Matching
+	if matching == "" {
+		matching = RecorderMatchStrict
+	} else if matching != RecorderMatchStrict && matching != RecorderMatchLenient {
+		return nil, fmt.Errorf("Unknown recorder matching: '%s'", matching)
+	}
+
+	r := &Recorder{
+		path:     args.CassettePath,
+		mode:     mode,
+		matching: matching,
+		last:     map[string]int{},
+	}
+
+	if mode == RecorderModeReplay {
+		data, err := ioutil.ReadFile(args.CassettePath)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot read cassette: %s", err)
+		}
+		if err := json.Unmarshal(data, &r.cassette); err != nil {
+			return nil, fmt.Errorf("Cannot parse cassette '%s': %s", args.CassettePath, err)
+		}
+		r.replayed = make([]bool, len(r.cassette.Interactions))
+	}
+
+	return r, nil
+}
+
+// Wrap returns transport which records exchanges sent with rt, or replays them without sending in replay mode
+func (r *Recorder) Wrap(rt http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if r.mode == RecorderModeReplay {
+			return r.replay(req)
+		}
+		return r.record(rt, req)
+	})
+}
+
+// Save writes recorded interactions to the cassette file
+func (r *Recorder) Save() error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.mode != RecorderModeRecord {
+		return fmt.Errorf("Recorder is not in record mode")
+	}
+
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(r.path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("Cannot write cassette: %s", err)
+	}
+
+	return nil
+}
+
+// Unused returns recorded interactions which have not been replayed, e.g. to check all requests were sent
+func (r *Recorder) Unused() []Interaction {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	unused := []Interaction{}
+	for i, interaction := range r.cassette.Interactions {
+		if !r.replayed[i] {
+			unused = append(unused, interaction)
+		}
+	}
+	return unused
+}
+
+func (r *Recorder) record(rt http.RoundTripper, req *http.Request) (*http.Response, error) {
+	requestBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := rt.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	responseBody, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(responseBody))
+
+	interaction := Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URI:    scrubURI(req.URL),
+			Body:   scrubBody(requestBody),
+		},
+		Response: RecordedResponse{
+			StatusCode: res.StatusCode,
+			Body:       scrubBody(responseBody),
+		},
+	}
+
+	r.mux.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.mux.Unlock()
+
+	return res, nil
+}
+
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	requestBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	request := RecordedRequest{
+		Method: req.Method,
+		URI:    scrubURI(req.URL),
+		Body:   scrubBody(requestBody),
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	var i int
+	if r.matching == RecorderMatchStrict {
+		i, err = r.matchStrict(request)
+	} else {
+		i, err = r.matchLenient(request)
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.replayed[i] = true
+
+	response := r.cassette.Interactions[i].Response
+	body := unwrapBody(response.Body)
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", response.StatusCode, http.StatusText(response.StatusCode)),
+		StatusCode:    response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func (r *Recorder) matchStrict(request RecordedRequest) (int, error) {
+	if r.next >= len(r.cassette.Interactions) {
+		return 0, fmt.Errorf(
+			"Cassette '%s' has no more interactions, got request '%s %s'", r.path, request.Method, request.URI)
+	}
+
+	i := r.next
+	expected := r.cassette.Interactions[i].Request
+	if expected.Method != request.Method || expected.URI != request.URI || !equalBodies(expected.Body, request.Body) {
+		return 0, fmt.Errorf(
+			"Cassette '%s' interaction %d: expected request '%s %s' %s, got '%s %s' %s",
+			r.path, i, expected.Method, expected.URI, expected.Body, request.Method, request.URI, request.Body,
+		)
+	}
+
+	r.next++
+	return i, nil
+}
+
+func (r *Recorder) matchLenient(request RecordedRequest) (int, error) {
+	key := request.Method + " " + uriPath(request.URI)
+	for i, interaction := range r.cassette.Interactions {
+		if !r.replayed[i] && interaction.Request.Method+" "+uriPath(interaction.Request.URI) == key {
+			r.last[key] = i
+			return i, nil
+		}
+	}
+
+	if i, ok := r.last[key]; ok {
+		return i, nil
+	}
+
+	return 0, fmt.Errorf("Cassette '%s' has no interaction for request '%s %s'", r.path, request.Method, request.URI)
+}
+
+// readRequestBody reads the body and restores it, so the request can be sent
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// scrubURI returns path and query with secret params masked
+func scrubURI(u *url.URL) string {
+	uri := strings.TrimLeft(u.Path, "/")
+	if u.RawQuery == "" {
+		return uri
+	}
+
+	query := u.Query()
+	for key := range query {
+		if logger.IsSecretKey(key) {
+			query.Set(key, logger.Mask)
+		}
+	}
+	return uri + "?" + query.Encode()
+}
+
+// scrubBody returns JSON body with secrets masked, non-JSON body is returned as JSON string
+func scrubBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+
+	var value interface{}
+	if err := decodeJSON(body, &value); err != nil {
+		data, _ := json.Marshal(string(body))
+		return data
+	}
+
+	data, err := json.Marshal(logger.Redact(value))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// unwrapBody returns recorded body as it was sent, JSON strings are non-JSON bodies
+func unwrapBody(body json.RawMessage) []byte {
+	var text string
+	if len(body) != 0 && body[0] == '"' && json.Unmarshal(body, &text) == nil {
+		return []byte(text)
+	}
+	return body
+}
+
+func equalBodies(a, b json.RawMessage) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	var aValue, bValue interface{}
+	if decodeJSON(a, &aValue) != nil || decodeJSON(b, &bValue) != nil {
+		return bytes.Equal(a, b)
+	}
+	return reflect.DeepEqual(aValue, bValue)
+}
+
+// decodeJSON works as json.Unmarshal, but keeps numbers as json.Number,
+// so sizes and ids above 2^53 are not rounded to float64
+func decodeJSON(data []byte, value interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(value); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return fmt.Errorf("Unexpected data after JSON value")
+	}
+	return nil
+}
+
+func uriPath(uri string) string {
+	if i := strings.IndexByte(uri, '?'); i != -1 {
+		return uri[:i]
+	}
+	return uri
+}
+
+// roundTripperFunc - http.RoundTripper implemented by a function
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip calls the function
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,295 @@
+package rest
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// TLSOptions - server certificate verification and client certificate settings
+// CA and client certificate files are read again on the next TLS handshake after they are rotated.
+type TLSOptions struct {
+	// CAFile - PEM file with CA certificates to verify the server certificate
+	CAFile string
+	// CAPEM - PEM encoded CA certificates, used along with CAFile and RootCAs of the base TLS config;
+	// base RootCAs or system CAs are used if none is set
+	CAPEM []byte
+
+	// CertFile and KeyFile - client certificate and key PEM files for mutual TLS
+	CertFile string
+	KeyFile  string
+
+	// SPKIPins - base64 encoded SHA-256 fingerprints of allowed public keys (SubjectPublicKeyInfo),
+	// one of the certificates in the server chain must match; with InsecureSkipVerify only pins are checked
+	SPKIPins []string
+
+	// MinVersion - minimum TLS version, e.g. tls.VersionTLS12, Go default if not set
+	MinVersion uint16
+
+	// ServerName - name to verify the server certificate against, host of the address is used if not set
+	ServerName string
+}
+
+// SPKIFingerprint returns base64 encoded SHA-256 fingerprint of certificate's public key, see TLSOptions.SPKIPins
+func SPKIFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// watchedFile - file content cached until file modification time changes
+type watchedFile struct {
+	path string
+
+	mux     sync.Mutex
+	modTime time.Time
+	size    int64
+	data    []byte
+}
+
+// read returns file content and true if the content has changed since the previous call
+func (f *watchedFile) read() ([]byte, bool, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if f.data != nil && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.data, false, nil
+	}
+
+	data, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	f.modTime = info.ModTime()
+	f.size = info.Size()
+	f.data = data
+	return data, true, nil
+}
+
+// tlsLoader - loads CA pool and client certificate, reloads them when files change
+type tlsLoader struct {
+	options TLSOptions
+	roots   *x509.CertPool // RootCAs of the base config, trusted along with the loaded CA pool
+	caFile  *watchedFile
+	keyFile *watchedFile
+	crtFile *watchedFile
+
+	mux    sync.Mutex
+	caPool *x509.CertPool
+	cert   *tls.Certificate
+}
+
+func newTLSLoader(options TLSOptions, roots *x509.CertPool) *tlsLoader {
+	loader := &tlsLoader{options: options, roots: roots}
+	if options.CAFile != "" {
+		loader.caFile = &watchedFile{path: options.CAFile}
+	}
+	if options.CertFile != "" {
+		loader.crtFile = &watchedFile{path: options.CertFile}
+		loader.keyFile = &watchedFile{path: options.KeyFile}
+	}
+	return loader
+}
+
+// getCAPool returns CA pool from CAFile and CAPEM, nil if none of them is set
+func (l *tlsLoader) getCAPool() (*x509.CertPool, error) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if l.caFile == nil && len(l.options.CAPEM) == 0 {
+		return nil, nil
+	}
+
+	var fileData []byte
+	changed := l.caPool == nil
+	if l.caFile != nil {
+		data, fileChanged, err := l.caFile.read()
+		if err != nil {
+			return nil, fmt.Errorf("Cannot read CA file: %s", err)
+		}
+		fileData = data
+		changed = changed || fileChanged
+	}
+
+	if !changed {
+		return l.caPool, nil
+	}
+
+	pool := x509.NewCertPool()
+	if len(l.options.CAPEM) != 0 && !pool.AppendCertsFromPEM(l.options.CAPEM) {
+		return nil, fmt.Errorf("CA PEM doesn't contain certificates")
+	}
+	if fileData != nil && !pool.AppendCertsFromPEM(fileData) {
+		return nil, fmt.Errorf("CA file '%s' doesn't contain PEM certificates", l.options.CAFile)
+	}
+
+	l.caPool = pool
+	return pool, nil
+}
+
+// getClientCertificate returns client certificate, see tls.Config.GetClientCertificate
+func (l *tlsLoader) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	certPEM, certChanged, err := l.crtFile.read()
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read client certificate file: %s", err)
+	}
+	keyPEM, keyChanged, err := l.keyFile.read()
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read client key file: %s", err)
+	}
+
+	if l.cert == nil || certChanged || keyChanged {
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot load client certificate '%s': %s", l.options.CertFile, err)
+		}
+		l.cert = &cert
+	}
+
+	return l.cert, nil
+}
+
+// verifyPeerCertificate verifies server chain against the current CA pool and checks SPKI pins
+func (l *tlsLoader) verifyPeerCertificate(serverName string, verifyChain bool) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		certs := make([]*x509.Certificate, len(rawCerts))
+		for i, rawCert := range rawCerts {
+			cert, err := x509.ParseCertificate(rawCert)
+			if err != nil {
+				return fmt.Errorf("Cannot parse server certificate: %s", err)
+			}
+			certs[i] = cert
+		}
+		if len(certs) == 0 {
+			return fmt.Errorf("Server didn't provide a certificate")
+		}
+
+		if verifyChain {
+			caPool, err := l.getCAPool()
+			if err != nil {
+				return err
+			}
+			intermediates := x509.NewCertPool()
+			for _, cert := range certs[1:] {
+				intermediates.AddCert(cert)
+			}
+			verify := func(roots *x509.CertPool) error {
+				_, err := certs[0].Verify(x509.VerifyOptions{
+					Roots:         roots,
+					Intermediates: intermediates,
+					DNSName:       serverName,
+				})
+				return err
+			}
+			// CA pool doesn't replace base RootCAs, the chain may be issued by any of them
+			if caPool == nil {
+				err = verify(l.roots)
+			} else if err = verify(caPool); err != nil && l.roots != nil && verify(l.roots) == nil {
+				err = nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(l.options.SPKIPins) == 0 {
+			return nil
+		}
+		for _, cert := range certs {
+			fingerprint := SPKIFingerprint(cert)
+			for _, pin := range l.options.SPKIPins {
+				if pin == fingerprint {
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf(
+			"Server certificate doesn't match any of SPKI pins, server key fingerprint: %s",
+			SPKIFingerprint(certs[0]),
+		)
+	}
+}
+
+// newTLSConfig builds client TLS configuration, base config is not changed
+// Returned error is about files which cannot be loaded now, the config is still usable once they are fixed,
+// handshakes requesting a client certificate fail if only one of its files is set.
+func newTLSConfig(address string, base *tls.Config, insecureSkipVerify bool, options TLSOptions) (*tls.Config, error) {
+	config := &tls.Config{}
+	if base != nil {
+		config = base.Clone()
+	}
+
+	if options.MinVersion != 0 {
+		config.MinVersion = options.MinVersion
+	}
+
+	if options.ServerName != "" {
+		config.ServerName = options.ServerName
+	}
+
+	var err error
+	if (options.CertFile == "") != (options.KeyFile == "") {
+		certErr := fmt.Errorf("Both client certificate and key files are required")
+		err = certErr
+		options.CertFile = ""
+		options.KeyFile = ""
+		// fail handshakes with servers which request the certificate instead of connecting without it
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return nil, certErr
+		}
+	}
+
+	loader := newTLSLoader(options, config.RootCAs)
+
+	if options.CertFile != "" {
+		// report wrong files early, the files are loaded again on handshakes
+		if _, certErr := loader.getClientCertificate(nil); certErr != nil {
+			err = certErr
+		}
+		config.GetClientCertificate = loader.getClientCertificate
+	}
+
+	verifyChain := !insecureSkipVerify && !config.InsecureSkipVerify
+	if options.CAFile != "" || len(options.CAPEM) != 0 || len(options.SPKIPins) != 0 {
+		if _, caErr := loader.getCAPool(); caErr != nil && err == nil {
+			err = caErr
+		}
+
+		serverName := config.ServerName
+		if serverName == "" {
+			serverName = addressHost(address)
+		}
+
+		// chain is verified by VerifyPeerCertificate to use reloaded CA pool
+		config.InsecureSkipVerify = true
+		config.VerifyPeerCertificate = loader.verifyPeerCertificate(serverName, verifyChain)
+	} else if insecureSkipVerify {
+		config.InsecureSkipVerify = true
+	}
+
+	return config, err
+}
+
+// addressHost returns host name or IP of the address
+func addressHost(address string) string {
+	u, err := url.Parse(address)
+	if err != nil || u.Host == "" {
+		return address
+	}
+	return u.Hostname()
+}
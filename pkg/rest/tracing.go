@@ -0,0 +1,65 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Nexenta/go-nexentastor/pkg/metrics"
+)
+
+// name of the tracer request spans are created by
+const tracerName = "github.com/Nexenta/go-nexentastor/pkg/rest"
+
+// span attributes, OpenTelemetry semantic conventions are used for HTTP ones
+const (
+	attrHTTPMethod     = attribute.Key("http.request.method")
+	attrHTTPStatusCode = attribute.Key("http.response.status_code")
+	attrURLPath        = attribute.Key("url.path")
+	attrServerAddress  = attribute.Key("server.address")
+	attrRequestID      = attribute.Key("nexentastor.request_id")
+)
+
+// startSpan starts request span, no-op span is returned if client has no tracer
+func (c *Client) startSpan(ctx context.Context, method, path string, requestID int64) (context.Context, trace.Span) {
+	if c.tracer == nil {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+
+	return c.tracer.Start(
+		ctx,
+		method+" "+metrics.EndpointTemplate(path),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attrHTTPMethod.String(method),
+			attrURLPath.String(path),
+			attrServerAddress.String(c.address),
+			attrRequestID.Int64(requestID),
+		),
+	)
+}
+
+// injectTraceContext adds trace context headers (e.g. "traceparent") to the request
+func (c *Client) injectTraceContext(ctx context.Context, req *http.Request) {
+	if c.propagator != nil {
+		c.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+	}
+}
+
+// endSpan records response status code or request error and ends the span
+func endSpan(span trace.Span, statusCode int, err error) {
+	if statusCode != 0 {
+		span.SetAttributes(attrHTTPStatusCode.Int(statusCode))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else if statusCode >= 400 {
+		span.SetStatus(codes.Error, http.StatusText(statusCode))
+	}
+	span.End()
+}
@@ -0,0 +1,54 @@
+package rest
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// newTransport creates HTTP transport, returned error is about invalid proxy URL,
+// requests of the transport fail with the error then
+func newTransport(tlsConfig *tls.Config, options TransportOptions) (*http.Transport, error) {
+	dialTimeout := options.DialTimeout
+	if dialTimeout <= 0 {
+		dialTimeout = defaultDialTimeout
+	}
+	keepAlive := options.KeepAlive
+	if keepAlive <= 0 {
+		keepAlive = defaultKeepAlive
+	}
+	idleConnTimeout := options.IdleConnTimeout
+	if idleConnTimeout <= 0 {
+		idleConnTimeout = defaultIdleConnTimeout
+	}
+
+	tr := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   dialTimeout,
+			KeepAlive: keepAlive,
+		}).DialContext,
+		MaxIdleConnsPerHost:   options.MaxIdleConnsPerHost,
+		IdleConnTimeout:       idleConnTimeout,
+		ResponseHeaderTimeout: options.ResponseHeaderTimeout,
+		TLSHandshakeTimeout:   tlsHandshakeTimeout,
+		TLSClientConfig:       tlsConfig,
+	}
+
+	if options.Proxy != "" {
+		proxyURL, err := url.Parse(options.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			err = fmt.Errorf("Invalid proxy URL '%s', expected 'http://host:port'", options.Proxy)
+			// don't fall back to the environment proxy or to a direct connection
+			tr.Proxy = func(*http.Request) (*url.URL, error) {
+				return nil, err
+			}
+			return tr, err
+		}
+		tr.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	return tr, nil
+}
@@ -793,10 +793,10 @@ func TestProvider_NewProvider(t *testing.T) {
 		}
 
 		tokenTests := []struct {
-			Limit             int
-			ExpectedCount     int
-			StartingToken     string
-			ExpectedNextToken string
+			Limit            int
+			ExpectedCount    int
+			StartingToken    string
+			ExpectedLastPath string // last filesystem in the result if next token is expected
 		}{
 			{count + 1, count, "", ""},
 			{5, 5, "", getFilesystemChildName(c.filesystem, 5)},
@@ -818,8 +818,15 @@ func TestProvider_NewProvider(t *testing.T) {
 			} else if len(filesystems) != v.ExpectedCount {
 				t.Errorf("%s: returned %d filesystems, but expected %d", f, len(filesystems), v.ExpectedCount)
 				return
-			} else if nextToken != v.ExpectedNextToken {
-				t.Errorf("%s: returned '%s' next token, but expected '%s'", f, nextToken, v.ExpectedNextToken)
+			} else if (nextToken != "") != (v.ExpectedLastPath != "") {
+				t.Errorf("%s: returned '%s' next token, but expected it after '%s'", f, nextToken, v.ExpectedLastPath)
+			} else if v.ExpectedLastPath != "" && filesystems[len(filesystems)-1].Path != v.ExpectedLastPath {
+				t.Errorf(
+					"%s: returned '%s' as the last filesystem, but expected '%s'",
+					f,
+					filesystems[len(filesystems)-1].Path,
+					v.ExpectedLastPath,
+				)
 			}
 		}
 
@@ -876,15 +883,18 @@ func getFilesystemChildName(parent string, id int) string {
 }
 
 func createFilesystemChildren(nsp ns.ProviderInterface, parent string, count int) error {
-	jobs := make([]func() error, count)
+	params := make([]ns.CreateFilesystemParams, count)
 	for i := 0; i < count; i++ {
-		i := i
-		jobs[i] = func() error {
-			return nsp.CreateFilesystem(ns.CreateFilesystemParams{Path: getFilesystemChildName(parent, i+1)})
+		params[i] = ns.CreateFilesystemParams{Path: getFilesystemChildName(parent, i+1)}
+	}
+
+	for _, result := range ns.NewBatch(nsp, concurrentProcesses).CreateFilesystems(params) {
+		if result.Err != nil {
+			return fmt.Errorf("create filesystem '%s' failed: %s", result.Path, result.Err)
 		}
 	}
 
-	return runConcurrentJobs("create filesystem", jobs)
+	return nil
 }
 
 func destroyFilesystemWithDependents(nsp ns.ProviderInterface, filesystem string) error {
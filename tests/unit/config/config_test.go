@@ -0,0 +1,199 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Nexenta/go-nexentastor/pkg/config"
+	"github.com/Nexenta/go-nexentastor/pkg/logger"
+)
+
+const testConfig = `
+defaultProfile: single
+profiles:
+  single:
+    addresses: [https://10.3.199.254:8443]
+    username: admin
+    password: pass
+    timeout: 45s
+    defaultPool: poolA
+    defaultDataset: poolA/datasetA
+  cluster:
+    addresses: [https://10.3.199.252:8443, https://10.3.199.253:8443]
+    credentialsFile: credentials.yaml
+    tls:
+      insecureSkipVerify: true
+`
+
+func writeTestConfig(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "nexentastor-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"config.yaml":      testConfig,
+		"credentials.yaml": "username: operator\npassword: secret\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return filepath.Join(dir, "config.yaml")
+}
+
+func TestConfig_Profile(t *testing.T) {
+	file := writeTestConfig(t)
+	defer os.RemoveAll(filepath.Dir(file))
+
+	cfg, err := config.Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("default profile", func(t *testing.T) {
+		profile, err := cfg.Profile("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if profile.IsCluster() || profile.Timeout != 45*time.Second || profile.DefaultPool != "poolA" {
+			t.Errorf("unexpected profile: %+v", profile)
+		}
+
+		args, err := profile.ProviderArgs(logger.Nop{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if args.Address != "https://10.3.199.254:8443" || args.Username != "admin" || args.Timeout != 45*time.Second {
+			t.Errorf("unexpected provider args: %+v", args)
+		}
+		if args.Logger != (logger.Nop{}) {
+			t.Errorf("logger is not passed to provider args: %+v", args.Logger)
+		}
+
+		if redacted := logger.Redact(*profile).(config.Profile); redacted.Password == "pass" {
+			t.Errorf("profile password is not redacted: %+v", redacted)
+		}
+	})
+
+	t.Run("credentials file relative to config", func(t *testing.T) {
+		profile, err := cfg.Profile("cluster")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if profile.Username != "operator" || profile.Password != "secret" {
+			t.Errorf("credentials file is not applied: %+v", profile)
+		}
+
+		if _, err := profile.ProviderArgs(nil); err == nil {
+			t.Error("provider args must not be created for a cluster profile")
+		}
+
+		args, err := profile.ResolverArgs(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if args.Address != "https://10.3.199.252:8443,https://10.3.199.253:8443" || !args.InsecureSkipVerify {
+			t.Errorf("unexpected resolver args: %+v", args)
+		}
+	})
+
+	t.Run("environment overrides", func(t *testing.T) {
+		env := map[string]string{
+			config.EnvProfile: "cluster",
+			config.EnvAddress: "https://10.3.199.251:8443",
+			config.EnvTimeout: "10s",
+		}
+		for key, value := range env {
+			os.Setenv(key, value)
+			defer os.Unsetenv(key)
+		}
+
+		profile, err := cfg.Profile("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(profile.Addresses, []string{"https://10.3.199.251:8443"}) ||
+			profile.Timeout != 10*time.Second ||
+			profile.Username != "operator" {
+			t.Errorf("environment variables are not applied: %+v", profile)
+		}
+
+		// overrides must not change the loaded config
+		if len(cfg.Profiles["cluster"].Addresses) != 2 {
+			t.Errorf("config is changed by profile overrides: %+v", cfg.Profiles["cluster"])
+		}
+	})
+
+	t.Run("overrides after environment", func(t *testing.T) {
+		os.Setenv(config.EnvUsername, "env-user")
+		defer os.Unsetenv(config.EnvUsername)
+
+		profile, err := cfg.ProfileWithOverrides("single", func(p *config.Profile) {
+			if p.Username != "env-user" {
+				t.Errorf("expected override to get environment values, got username '%s'", p.Username)
+			}
+			p.Addresses = []string{"https://10.3.199.250:8443"}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(profile.Addresses, []string{"https://10.3.199.250:8443"}) {
+			t.Errorf("override is not applied: %+v", profile)
+		}
+
+		if _, err := cfg.ProfileWithOverrides("single", func(p *config.Profile) { p.Addresses = nil }); err == nil {
+			t.Error("expected overridden profile to be validated")
+		}
+	})
+
+	t.Run("unknown profile", func(t *testing.T) {
+		if _, err := cfg.Profile("unknown"); err == nil {
+			t.Error("expected an error for unknown profile")
+		}
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	for name, data := range map[string]string{
+		"unknown default profile": "defaultProfile: a\nprofiles: {b: {addresses: [https://h:8443]}}",
+		"unknown field":           "profiles: {a: {address: https://h:8443}}",
+	} {
+		if _, err := config.Parse([]byte(data)); err == nil {
+			t.Errorf("%s: expected parse error", name)
+		}
+	}
+
+	for name, data := range map[string]string{
+		"no address":       "profiles: {a: {username: u, password: p}}",
+		"invalid address":  "profiles: {a: {addresses: [10.3.199.254], username: u, password: p}}",
+		"no password":      "profiles: {a: {addresses: ['https://h:8443'], username: u}}",
+		"cert without key": "profiles: {a: {addresses: ['https://h:8443'], username: u, password: p, tls: {certFile: c}}}",
+	} {
+		cfg, err := config.Parse([]byte(data))
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if _, err := cfg.Profile("a"); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestSplitAddresses(t *testing.T) {
+	for address, expected := range map[string][]string{
+		"":                              nil,
+		"https://h1:8443":               {"https://h1:8443"},
+		" https://h1:8443, ,https://h2": {"https://h1:8443", "https://h2"},
+	} {
+		if addresses := config.SplitAddresses(address); !reflect.DeepEqual(addresses, expected) {
+			t.Errorf("'%s': expected %v, got: %v", address, expected, addresses)
+		}
+	}
+}
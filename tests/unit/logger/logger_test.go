@@ -0,0 +1,183 @@
+package logger_test
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Nexenta/go-nexentastor/pkg/logger"
+)
+
+type chapCredentials struct {
+	User   string `json:"user"`
+	Secret string `json:"secret" secret:"true"`
+}
+
+type target struct {
+	Name        string
+	Credentials *chapCredentials
+	Portals     []chapCredentials
+	Options     map[string]interface{}
+}
+
+func TestRedact(t *testing.T) {
+	tests := map[string]struct {
+		value    interface{}
+		expected interface{}
+	}{
+		"no secrets": {
+			value:    struct{ Name string }{Name: "fs"},
+			expected: struct{ Name string }{Name: "fs"},
+		},
+		"tagged field": {
+			value:    chapCredentials{User: "u", Secret: "s"},
+			expected: chapCredentials{User: "u", Secret: logger.Mask},
+		},
+		"empty tagged field stays empty": {
+			value:    chapCredentials{User: "u"},
+			expected: chapCredentials{User: "u"},
+		},
+		"nested values": {
+			value: &target{
+				Name:        "t",
+				Credentials: &chapCredentials{Secret: "s"},
+				Portals:     []chapCredentials{{User: "a", Secret: "s1"}},
+				Options:     map[string]interface{}{"chapSecret": "s2", "mode": "rw"},
+			},
+			expected: &target{
+				Name:        "t",
+				Credentials: &chapCredentials{Secret: logger.Mask},
+				Portals:     []chapCredentials{{User: "a", Secret: logger.Mask}},
+				Options:     map[string]interface{}{"chapSecret": logger.Mask, "mode": "rw"},
+			},
+		},
+		"map with secret keys": {
+			value:    map[string]string{"username": "admin", "password": "pass", "authToken": "t"},
+			expected: map[string]string{"username": "admin", "password": logger.Mask, "authToken": logger.Mask},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if redacted := logger.Redact(test.value); !reflect.DeepEqual(redacted, test.expected) {
+				t.Errorf("expected: %+v, got: %+v", test.expected, redacted)
+			}
+		})
+	}
+
+	t.Run("original value is not changed", func(t *testing.T) {
+		value := &chapCredentials{User: "u", Secret: "s"}
+		logger.Redact(value)
+		if value.Secret != "s" {
+			t.Errorf("expected original secret to be kept, got: '%s'", value.Secret)
+		}
+	})
+}
+
+func TestNewRedacting(t *testing.T) {
+	var buf bytes.Buffer
+	l := logrus.New()
+	l.SetOutput(&buf)
+	l.SetLevel(logrus.DebugLevel)
+
+	log := logger.NewRedacting(logger.NewLogrus(l))
+	log.WithFields(logger.Fields{"password": "fieldpass", "user": "admin"}).
+		Debugf("data: %+v", &chapCredentials{User: "u", Secret: "argsecret"})
+
+	out := buf.String()
+	for _, secret := range []string{"fieldpass", "argsecret"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("expected '%s' to be masked, got: %s", secret, out)
+		}
+	}
+	if !strings.Contains(out, "user=admin") || !strings.Contains(out, "User:u") {
+		t.Errorf("expected non-secret values to be logged, got: %s", out)
+	}
+}
+
+// argsLogger - Logger which keeps message arguments without formatting them, like a disabled level does
+type argsLogger struct {
+	logger.Nop
+	args []interface{}
+}
+
+func (l *argsLogger) Debugf(format string, args ...interface{}) {
+	l.args = args
+}
+
+func TestNewRedacting_Lazy(t *testing.T) {
+	recorder := &argsLogger{}
+	credentials := &chapCredentials{User: "u", Secret: "argsecret"}
+	logger.NewRedacting(recorder).Debugf("%s %+v %d", "name", credentials, 42)
+
+	if recorder.args[0] != "name" || recorder.args[2] != 42 {
+		t.Errorf("expected values without secrets to be passed as is, got: %v", recorder.args)
+	}
+	if _, ok := recorder.args[1].(fmt.Formatter); !ok {
+		t.Errorf("expected value with secrets to be redacted on formatting, got: %T", recorder.args[1])
+	}
+
+	redacted := logger.Redact(credentials)
+	out := fmt.Sprintf("%+v|%12v", recorder.args[1], recorder.args[1])
+	if expected := fmt.Sprintf("%+v|%12v", redacted, redacted); out != expected || strings.Contains(out, "argsecret") {
+		t.Errorf("expected redacted value with the same format, got: %s", out)
+	}
+}
+
+func TestNewSlog(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.NewSlog(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	l.Debugf("hidden")
+	l.WithFields(logger.Fields{"b": 2, "a": 1}).WithField("cmp", "test").Warnf("job %s", "j1")
+
+	if out := buf.String(); !strings.Contains(out, `level=WARN msg="job j1" a=1 b=2 cmp=test`) {
+		t.Errorf("unexpected slog output: %s", out)
+	}
+	if strings.Contains(buf.String(), "hidden") {
+		t.Errorf("expected debug line to be dropped, got: %s", buf.String())
+	}
+}
+
+// testSugaredLogger - fake with zap.SugaredLogger method set
+type testSugaredLogger struct {
+	fields []interface{}
+	lines  *[]string
+}
+
+func (l *testSugaredLogger) log(level, template string, args []interface{}) {
+	*l.lines = append(*l.lines, fmt.Sprintf("%s %s %v", level, fmt.Sprintf(template, args...), l.fields))
+}
+
+func (l *testSugaredLogger) Debugf(template string, args ...interface{}) {
+	l.log("debug", template, args)
+}
+func (l *testSugaredLogger) Infof(template string, args ...interface{}) {
+	l.log("info", template, args)
+}
+func (l *testSugaredLogger) Warnf(template string, args ...interface{}) {
+	l.log("warn", template, args)
+}
+func (l *testSugaredLogger) Errorf(template string, args ...interface{}) {
+	l.log("error", template, args)
+}
+
+func (l *testSugaredLogger) With(args ...interface{}) *testSugaredLogger {
+	return &testSugaredLogger{fields: append(append([]interface{}{}, l.fields...), args...), lines: l.lines}
+}
+
+func TestNewZap(t *testing.T) {
+	lines := []string{}
+	l := logger.NewZap(&testSugaredLogger{lines: &lines})
+
+	l.WithField("cmp", "test").Errorf("failed: %d", 1)
+
+	if len(lines) != 1 || lines[0] != "error failed: 1 [cmp test]" {
+		t.Errorf("unexpected zap output: %v", lines)
+	}
+}
@@ -0,0 +1,89 @@
+package metrics_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/Nexenta/go-nexentastor/pkg/metrics"
+	"github.com/Nexenta/go-nexentastor/pkg/metrics/prometheus"
+)
+
+func TestEndpointTemplate(t *testing.T) {
+	tests := map[string]string{
+		"auth/login":                              "/auth/login",
+		"/storage/pools?fields=poolName":          "/storage/pools",
+		"/storage/filesystems/pool%2Ffs":          "/storage/filesystems/{id}",
+		"/storage/filesystems/pool%2Ffs/acl":      "/storage/filesystems/{id}/acl",
+		"/storage/snapshots/p%2Fd%40s/clone":      "/storage/snapshots/{id}/clone",
+		"/storage/volumeGroups/pool%2Fvg?force=1": "/storage/volumeGroups/{id}",
+		"/jobStatus/3a6a9cde-3b1c-4d31-8f6b":      "/jobStatus/{id}",
+		"/san/fc/ports/21000024ff4b81a2":          "/san/fc/ports/{id}",
+		"/san/hostgroups/hg1":                     "/san/hostgroups/{id}",
+		"/san/iscsi/targets":                      "/san/iscsi/targets",
+		"/hpr/services/12/start":                  "/hpr/services/{id}/start",
+	}
+
+	for path, expected := range tests {
+		if template := metrics.EndpointTemplate(path); template != expected {
+			t.Errorf("EndpointTemplate('%s'): expected '%s', got: '%s'", path, expected, template)
+		}
+	}
+}
+
+func TestPrometheusMetrics(t *testing.T) {
+	registry := prom.NewRegistry()
+	m, err := prometheus.NewMetrics(prometheus.MetricsArgs{Registerer: registry})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.ObserveRequest("https://ns:8443", "GET", "/storage/pools", "200", 10*time.Millisecond)
+	m.ObserveRequest("https://ns:8443", "GET", "/storage/pools", "200", 20*time.Millisecond)
+	m.ObserveJob("https://ns:8443", metrics.JobSucceeded, 3*time.Second)
+	m.ObserveLogin("https://ns:8443", nil)
+	m.ObserveLogin("https://ns:8443", fmt.Errorf("EAUTH"))
+	m.ObserveRetry("https://ns:8443", metrics.RetryAuth)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string]float64{}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			key := family.GetName()
+			for _, label := range metric.GetLabel() {
+				if label.GetName() != "address" {
+					key += fmt.Sprintf(" %s=%s", label.GetName(), label.GetValue())
+				}
+			}
+			if metric.GetCounter() != nil {
+				values[key] = metric.GetCounter().GetValue()
+			} else if metric.GetHistogram() != nil {
+				values[key] = float64(metric.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+
+	expected := map[string]float64{
+		"nexentastor_requests_total code=200 endpoint=/storage/pools method=GET":           2,
+		"nexentastor_request_duration_seconds code=200 endpoint=/storage/pools method=GET": 2,
+		"nexentastor_job_wait_duration_seconds outcome=succeeded":                          1,
+		"nexentastor_logins_total result=success":                                          1,
+		"nexentastor_logins_total result=failure":                                          1,
+		"nexentastor_request_retries_total reason=auth":                                    1,
+	}
+	for key, value := range expected {
+		if values[key] != value {
+			t.Errorf("expected '%s' to be %v, got: %v (all values: %v)", key, value, values[key], values)
+		}
+	}
+
+	if _, err := prometheus.NewMetrics(prometheus.MetricsArgs{Registerer: registry}); err == nil {
+		t.Error("expected error on duplicate registration, got none")
+	}
+}
@@ -0,0 +1,150 @@
+package provider_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/ns/nsfake"
+)
+
+func TestProvider_Alerts(t *testing.T) {
+	var requests []string
+	nsp, closeServer := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimLeft(r.URL.Path, "/")
+		requests = append(requests, fmt.Sprintf("%s %s", r.Method, path))
+		switch {
+		case r.Method == http.MethodGet && path == "faults":
+			if r.URL.Query().Get("acknowledged") != "false" || r.URL.Query().Get("severity") != "critical" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `{"data": [{
+				"id": "f1",
+				"severity": "critical",
+				"component": "pool/pool1",
+				"message": "Pool is degraded",
+				"time": "2020-01-01T00:00:00Z"
+			}]}`)
+		case r.Method == http.MethodPost && path == "alerts/a1/acknowledge",
+			r.Method == http.MethodDelete && path == "faults/f1":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer closeServer()
+
+	acknowledged := false
+	faults, err := nsp.ListFaults(ns.ListAlertsParams{Severity: ns.AlertSeverityCritical, Acknowledged: &acknowledged})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(faults) != 1 || faults[0].Source != ns.AlertSourceFault || faults[0].Component != "pool/pool1" ||
+		!faults[0].Time.Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected faults: %+v", faults)
+	}
+
+	if err := nsp.AcknowledgeAlert(ns.AlertSourceAlert, "a1"); err != nil {
+		t.Error(err)
+	}
+	if err := nsp.ClearAlert(ns.AlertSourceFault, "f1"); err != nil {
+		t.Error(err)
+	}
+	if err := nsp.ClearAlert("event", "e1"); err == nil {
+		t.Error("expected error for unknown alert source")
+	}
+
+	expectedRequests := "GET faults,POST alerts/a1/acknowledge,DELETE faults/f1"
+	if strings.Join(requests, ",") != expectedRequests {
+		t.Errorf("expected requests '%s', got: %v", expectedRequests, requests)
+	}
+}
+
+func receiveAlertEvent(t *testing.T, events <-chan ns.AlertEvent) ns.AlertEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no alert event received")
+	}
+	return ns.AlertEvent{}
+}
+
+func TestAlertWatcher_Watch(t *testing.T) {
+	p := nsfake.NewProvider(nsfake.ProviderArgs{})
+	degraded := ns.Alert{Id: "f1", Source: ns.AlertSourceFault, Component: "pool/pool1", Message: "Pool is degraded"}
+	failover := ns.Alert{Id: "a1", Source: ns.AlertSourceAlert, Component: "rsf/cluster", Message: "Service failover"}
+	if err := p.AddAlert(degraded); err != nil {
+		t.Fatal(err)
+	}
+
+	watcher, err := ns.NewAlertWatcher(ns.AlertWatcherArgs{Provider: p, Interval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := watcher.Watch(ctx)
+
+	if event := receiveAlertEvent(t, events); event.Err != nil || event.Alert.Id != "f1" {
+		t.Errorf("expected existing fault f1, got: %+v", event)
+	}
+
+	// already seen fault must not be sent again
+	if err := p.AddAlert(failover); err != nil {
+		t.Fatal(err)
+	}
+	if event := receiveAlertEvent(t, events); event.Err != nil || event.Alert.Id != "a1" {
+		t.Errorf("expected new alert a1, got: %+v", event)
+	}
+
+	injected := errors.New("connection refused")
+	p.SetError("ListFaults", injected)
+	if event := receiveAlertEvent(t, events); event.Err != injected {
+		t.Errorf("expected poll error, got: %+v", event)
+	}
+	p.SetError("ListFaults", nil)
+
+	// cleared fault is forgotten and sent again when it reappears
+	if err := p.ClearAlert(ns.AlertSourceFault, "f1"); err != nil {
+		t.Fatal(err)
+	}
+	p.ResetCalls()
+	for p.CallCount("ListFaults") < 2 {
+		select {
+		case event := <-events:
+			// poll errors may still come before the injected error is removed
+			if event.Err != injected {
+				t.Errorf("unexpected event: %+v", event)
+			}
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	if err := p.AddAlert(degraded); err != nil {
+		t.Fatal(err)
+	}
+	if event := receiveAlertEvent(t, events); event.Err != nil || event.Alert.Id != "f1" {
+		t.Errorf("expected reappeared fault f1, got: %+v", event)
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestAlertWatcher_InvalidSource(t *testing.T) {
+	_, err := ns.NewAlertWatcher(ns.AlertWatcherArgs{
+		Provider: nsfake.NewProvider(nsfake.ProviderArgs{}),
+		Sources:  []ns.AlertSource{"event"},
+	})
+	if err == nil {
+		t.Error("expected error for unknown alert source")
+	}
+}
@@ -0,0 +1,238 @@
+package provider_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+)
+
+func TestProvider_GetDatasetStats(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	nsp, closeServer := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if strings.TrimLeft(r.URL.EscapedPath(), "/") != "analytics/datasets/pool%2Ffs" ||
+			query.Get("startTime") != "2020-01-01T00:00:00Z" ||
+			query.Get("endTime") != "2020-01-01T01:00:00Z" ||
+			query.Get("resolution") != "300" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"data": [
+			{"time": "2020-01-01T00:05:00Z", "readIops": 300, "writeIops": 10, "readLatency": 4},
+			{"time": "2020-01-01T00:00:00Z", "readIops": 100, "writeIops": 30, "readLatency": 2}
+		]}`)
+	})
+	defer closeServer()
+
+	series, err := nsp.GetDatasetStats("pool/fs", ns.AnalyticsQuery{
+		Start:      start,
+		End:        start.Add(time.Hour),
+		Resolution: 299500 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if series.Resource != "pool/fs" || series.Resolution != 5*time.Minute || len(series.Points) != 2 {
+		t.Fatalf("unexpected series: %+v", series)
+	} else if !series.Points[0].Time.Equal(start) {
+		t.Errorf("expected points to be ordered by time, got: %+v", series.Points)
+	}
+
+	if average := series.Average(); average.ReadIOPS != 200 || average.WriteIOPS != 20 || average.ReadLatency != 3 {
+		t.Errorf("unexpected average: %+v", average)
+	}
+	if peak := series.Peak(); peak.ReadIOPS != 300 || peak.WriteIOPS != 30 || peak.ReadLatency != 4 {
+		t.Errorf("unexpected peak: %+v", peak)
+	}
+}
+
+func TestProvider_IOStats(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	query := ns.AnalyticsQuery{Start: start, End: start.Add(time.Hour)}
+
+	var requestedPath string
+	nsp, closeServer := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		requestedPath = strings.TrimLeft(r.URL.EscapedPath(), "/")
+		if r.URL.Query().Get("resolution") != "60" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"data": [{"time": "2020-01-01T00:00:00Z", "readIops": 100, "writeBandwidth": 4096}]}`)
+	})
+	defer closeServer()
+
+	tests := []struct {
+		name             string
+		get              func() (ns.IOTimeSeries, error)
+		expectedPath     string
+		expectedResource string
+	}{
+		{
+			name:             "pool",
+			get:              func() (ns.IOTimeSeries, error) { return nsp.GetPoolStats("pool", query) },
+			expectedPath:     "analytics/pools/pool",
+			expectedResource: "pool",
+		},
+		{
+			name:             "vdev",
+			get:              func() (ns.IOTimeSeries, error) { return nsp.GetVdevStats("pool", "mirror-0", query) },
+			expectedPath:     "analytics/pools/pool/vdevs/mirror-0",
+			expectedResource: "pool/mirror-0",
+		},
+		{
+			name:             "vdev names are escaped",
+			get:              func() (ns.IOTimeSeries, error) { return nsp.GetVdevStats("my pool", "c1t0d0/p1", query) },
+			expectedPath:     "analytics/pools/my%20pool/vdevs/c1t0d0%2Fp1",
+			expectedResource: "my pool/c1t0d0/p1",
+		},
+		{
+			name:             "dataset",
+			get:              func() (ns.IOTimeSeries, error) { return nsp.GetDatasetStats("pool/vg/v", query) },
+			expectedPath:     "analytics/datasets/pool%2Fvg%2Fv",
+			expectedResource: "pool/vg/v",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			series, err := test.get()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if requestedPath != test.expectedPath {
+				t.Errorf("expected request to '%s', but got '%s'", test.expectedPath, requestedPath)
+			}
+			if series.Resource != test.expectedResource || series.Resolution != time.Minute ||
+				len(series.Points) != 1 || series.Points[0].ReadIOPS != 100 || series.Points[0].WriteBandwidth != 4096 {
+				t.Errorf("unexpected series: %+v", series)
+			}
+		})
+	}
+
+	t.Run("names are required", func(t *testing.T) {
+		requestedPath = ""
+		if _, err := nsp.GetPoolStats("", query); err == nil {
+			t.Error("expected error for empty pool name")
+		}
+		if _, err := nsp.GetVdevStats("pool", "", query); err == nil {
+			t.Error("expected error for empty vdev name")
+		}
+		if _, err := nsp.GetDatasetStats("", query); err == nil {
+			t.Error("expected error for empty dataset path")
+		}
+		if _, err := nsp.GetNICStats("", query); err == nil {
+			t.Error("expected error for empty interface name")
+		}
+		if requestedPath != "" {
+			t.Errorf("expected no requests, but got one to '%s'", requestedPath)
+		}
+	})
+}
+
+func TestProvider_GetNICStats(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	nsp, closeServer := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if strings.TrimLeft(r.URL.EscapedPath(), "/") != "analytics/network/interfaces/ixgbe0" ||
+			query.Get("startTime") != "2020-01-01T00:00:00Z" ||
+			query.Get("resolution") != "10" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"data": [
+			{"time": "2020-01-01T00:00:10Z", "receiveBandwidth": 2048, "transmitPackets": 20, "errors": 1},
+			{"time": "2020-01-01T00:00:00Z", "receiveBandwidth": 1024, "transmitPackets": 10}
+		]}`)
+	})
+	defer closeServer()
+
+	series, err := nsp.GetNICStats("ixgbe0", ns.AnalyticsQuery{
+		Start:      start,
+		End:        start.Add(time.Minute),
+		Resolution: 10 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if series.Interface != "ixgbe0" || series.Resolution != 10*time.Second || len(series.Points) != 2 {
+		t.Fatalf("unexpected series: %+v", series)
+	}
+	first := series.Points[0]
+	if !first.Time.Equal(start) || first.ReceiveBandwidth != 1024 || first.TransmitPackets != 10 {
+		t.Errorf("expected points to be ordered by time, got: %+v", series.Points)
+	}
+	if last := series.Points[1]; last.Errors != 1 {
+		t.Errorf("unexpected point: %+v", last)
+	}
+}
+
+func TestIOTimeSeries_AverageAndPeak(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		points          []ns.IOStats
+		expectedAverage ns.IOStats
+		expectedPeak    ns.IOStats
+	}{
+		{
+			name: "no points",
+		},
+		{
+			name:            "one point",
+			points:          []ns.IOStats{{Time: start, ReadIOPS: 10, WriteLatency: 2}},
+			expectedAverage: ns.IOStats{Time: start, ReadIOPS: 10, WriteLatency: 2},
+			expectedPeak:    ns.IOStats{Time: start, ReadIOPS: 10, WriteLatency: 2},
+		},
+		{
+			name: "counters peak at different points",
+			points: []ns.IOStats{
+				{Time: start, ReadIOPS: 10, WriteIOPS: 40, ReadBandwidth: 100, WriteLatency: 1},
+				{Time: start.Add(time.Minute), ReadIOPS: 30, WriteIOPS: 0, ReadBandwidth: 300, WriteLatency: 5},
+			},
+			expectedAverage: ns.IOStats{Time: start, ReadIOPS: 20, WriteIOPS: 20, ReadBandwidth: 200, WriteLatency: 3},
+			expectedPeak:    ns.IOStats{Time: start, ReadIOPS: 30, WriteIOPS: 40, ReadBandwidth: 300, WriteLatency: 5},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			series := ns.IOTimeSeries{Resource: "pool", Points: test.points}
+			if average := series.Average(); average != test.expectedAverage {
+				t.Errorf("expected average %+v, but got %+v", test.expectedAverage, average)
+			}
+			if peak := series.Peak(); peak != test.expectedPeak {
+				t.Errorf("expected peak %+v, but got %+v", test.expectedPeak, peak)
+			}
+		})
+	}
+}
+
+func TestAnalyticsQuery_Validate(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	query, err := ns.AnalyticsQuery{Start: start}.Validate()
+	if err != nil {
+		t.Fatal(err)
+	} else if query.End.IsZero() || query.Resolution != time.Minute {
+		t.Errorf("expected end time and resolution defaults, got: %+v", query)
+	}
+
+	for _, invalid := range []ns.AnalyticsQuery{
+		{},
+		{Start: start, End: start},
+		{Start: start, End: start.Add(-time.Hour)},
+	} {
+		if _, err := invalid.Validate(); err == nil {
+			t.Errorf("expected error for query %+v", invalid)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package provider_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+)
+
+func TestBatch_CreateFilesystems(t *testing.T) {
+	var mux sync.Mutex
+	inFlight, maxInFlight := 0, 0
+
+	nsp, closeServer := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mux.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mux.Lock()
+		inFlight--
+		mux.Unlock()
+
+		params := ns.CreateFilesystemParams{}
+		json.NewDecoder(r.Body).Decode(&params)
+		if params.Path == "p/d/fs3" {
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, `{"name": "ExistsError", "message": "already exists", "code": "EEXIST"}`)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{}`)
+	})
+	defer closeServer()
+
+	params := make([]ns.CreateFilesystemParams, 10)
+	for i := range params {
+		params[i].Path = fmt.Sprintf("p/d/fs%d", i)
+	}
+
+	results := ns.NewBatch(nsp, 3).CreateFilesystems(params)
+
+	if maxInFlight > 3 {
+		t.Errorf("expected at most 3 concurrent requests, but got %d", maxInFlight)
+	}
+	for i, result := range results {
+		if result.Index != i || result.Path != params[i].Path {
+			t.Errorf("result %d doesn't match params: %+v", i, result)
+		} else if i == 3 && !ns.IsAlreadyExistNefError(result.Err) {
+			t.Errorf("expected EEXIST error for '%s', but got: %v", result.Path, result.Err)
+		} else if i != 3 && result.Err != nil {
+			t.Errorf("unexpected error for '%s': %s", result.Path, result.Err)
+		}
+	}
+}
+
+func TestBatch_AsyncJobs(t *testing.T) {
+	var mux sync.Mutex
+	jobChecks := map[string]int{}
+
+	nsp, closeServer := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			params := ns.CreateSnapshotParams{}
+			json.NewDecoder(r.Body).Decode(&params)
+			w.WriteHeader(http.StatusAccepted)
+			fmt.Fprintf(w, `{"links": [{"rel": "monitor", "href": "/jobStatus/%s"}]}`, params.Path)
+			return
+		}
+
+		mux.Lock()
+		jobChecks[r.URL.Path]++
+		mux.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{}`)
+	})
+	defer closeServer()
+
+	params := make([]ns.CreateSnapshotParams, 5)
+	for i := range params {
+		params[i].Path = fmt.Sprintf("p/d/fs@snap%d", i)
+	}
+
+	startTime := time.Now()
+	results := ns.NewBatch(nsp, 2).CreateSnapshots(params)
+
+	// completed jobs should be found by the first check, without waiting for the status check interval
+	if duration := time.Since(startTime); duration > time.Second {
+		t.Errorf("expected completed jobs to be checked at once, but batch took %s", duration)
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			t.Errorf("unexpected error for '%s': %s", result.Path, result.Err)
+		}
+	}
+	if len(jobChecks) != len(params) {
+		t.Errorf("expected %d jobs to be checked, but got: %v", len(params), jobChecks)
+	}
+}
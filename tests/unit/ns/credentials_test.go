@@ -0,0 +1,244 @@
+package provider_test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+)
+
+func TestCredentials_FileCredentialSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nexentastor-credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFile := func(name, content string, modTime time.Time) {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expectCredentials := func(source ns.CredentialSource, username, password string) {
+		t.Helper()
+		credentials, err := source.GetCredentials()
+		if err != nil {
+			t.Fatal(err)
+		} else if credentials.Username != username || credentials.Password != password {
+			t.Errorf("expected '%s:%s', got: '%s:%s'", username, password, credentials.Username, credentials.Password)
+		}
+	}
+
+	now := time.Now()
+
+	t.Run("file is re-read when it changes", func(t *testing.T) {
+		writeFile("credentials.yaml", "username: admin\npassword: pass1\n", now.Add(-time.Minute))
+		source := ns.NewFileCredentialSource(filepath.Join(dir, "credentials.yaml"))
+		expectCredentials(source, "admin", "pass1")
+
+		writeFile("credentials.yaml", `{"username": "admin", "password": "pass2"}`, now)
+		expectCredentials(source, "admin", "pass2")
+	})
+
+	t.Run("directory with username and password files", func(t *testing.T) {
+		if err := os.Mkdir(filepath.Join(dir, "secret"), 0700); err != nil {
+			t.Fatal(err)
+		}
+		writeFile("secret/username", "operator\n", now)
+		writeFile("secret/password", "secret\n", now)
+		expectCredentials(ns.NewFileCredentialSource(filepath.Join(dir, "secret")), "operator", "secret")
+	})
+
+	t.Run("exec command output", func(t *testing.T) {
+		source := ns.NewExecCredentialSource("sh", "-c", `echo '{"username": "exec", "password": "pass"}'`)
+		expectCredentials(source, "exec", "pass")
+	})
+}
+
+// authTestServer - NexentaStor stand-in which accepts only tokens issued by its /auth/login endpoint
+type authTestServer struct {
+	*httptest.Server
+	logins int64
+
+	issueToken func(n int64) string
+	loginDelay time.Duration
+
+	mux    sync.Mutex
+	tokens map[string]bool
+}
+
+func newAuthTestServer(issueToken func(n int64) string) *authTestServer {
+	s := &authTestServer{
+		issueToken: issueToken,
+		tokens:     map[string]bool{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+func (s *authTestServer) handle(w http.ResponseWriter, r *http.Request) {
+	if strings.TrimLeft(r.URL.Path, "/") == "auth/login" {
+		time.Sleep(s.loginDelay)
+		token := s.issueToken(atomic.AddInt64(&s.logins, 1))
+		s.mux.Lock()
+		s.tokens[token] = true
+		s.mux.Unlock()
+		fmt.Fprintf(w, `{"token": "%s"}`, token)
+		return
+	}
+
+	s.mux.Lock()
+	valid := s.tokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+	s.mux.Unlock()
+	if !valid {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"name": "AuthError", "message": "Unauthorized", "code": "EAUTH"}`)
+		return
+	}
+
+	fmt.Fprint(w, `{"data": [{"poolName": "pool"}]}`)
+}
+
+// expireTokens makes all issued tokens invalid
+func (s *authTestServer) expireTokens() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.tokens = map[string]bool{}
+}
+
+func (s *authTestServer) loginCount() int64 {
+	return atomic.LoadInt64(&s.logins)
+}
+
+func newAuthTestProvider(t *testing.T, address string) ns.ProviderInterface {
+	l := logrus.New().WithField("test", t.Name())
+	l.Logger.SetLevel(logrus.PanicLevel)
+
+	nsp, err := ns.NewProvider(ns.ProviderArgs{
+		Address:     address,
+		Credentials: ns.NewStaticCredentialSource("admin", "pass"),
+		Log:         l,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return nsp
+}
+
+func TestProvider_TokenCache(t *testing.T) {
+	t.Run("token is shared between providers of the same endpoint and user", func(t *testing.T) {
+		server := newAuthTestServer(func(n int64) string { return fmt.Sprintf("token-%d", n) })
+		defer server.Close()
+
+		for i := 0; i < 3; i++ {
+			if _, err := newAuthTestProvider(t, server.URL).GetPools(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if server.loginCount() != 1 {
+			t.Errorf("expected 1 login, got: %d", server.loginCount())
+		}
+	})
+
+	t.Run("token is not shared between providers with different passwords", func(t *testing.T) {
+		server := newAuthTestServer(func(n int64) string { return fmt.Sprintf("token-%d", n) })
+		defer server.Close()
+
+		for _, password := range []string{"pass", "wrongpass", "pass"} {
+			l := logrus.New().WithField("test", t.Name())
+			l.Logger.SetLevel(logrus.PanicLevel)
+			nsp, err := ns.NewProvider(ns.ProviderArgs{
+				Address:     server.URL,
+				Credentials: ns.NewStaticCredentialSource("admin", password),
+				Log:         l,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := nsp.GetPools(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if server.loginCount() != 2 {
+			t.Errorf("expected 2 logins, one per password, got: %d", server.loginCount())
+		}
+	})
+
+	t.Run("token is refreshed before it expires", func(t *testing.T) {
+		// JWT which expires sooner than the refresh margin
+		server := newAuthTestServer(func(n int64) string {
+			claims := fmt.Sprintf(`{"exp": %d, "n": %d}`, time.Now().Add(5*time.Second).Unix(), n)
+			return "e30." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".sig"
+		})
+		defer server.Close()
+
+		nsp := newAuthTestProvider(t, server.URL)
+		for i := 0; i < 2; i++ {
+			if _, err := nsp.GetPools(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if server.loginCount() != 2 {
+			t.Errorf("expected 2 logins, got: %d", server.loginCount())
+		}
+	})
+}
+
+func TestProvider_ConcurrentLogIn(t *testing.T) {
+	const concurrentRequests = 20
+
+	server := newAuthTestServer(func(n int64) string { return fmt.Sprintf("token-%d", n) })
+	server.loginDelay = 50 * time.Millisecond
+	defer server.Close()
+
+	nsp := newAuthTestProvider(t, server.URL)
+
+	getPoolsConcurrently := func() {
+		t.Helper()
+		var wg sync.WaitGroup
+		errs := make(chan error, concurrentRequests)
+		for i := 0; i < concurrentRequests; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := nsp.GetPools(); err != nil {
+					errs <- err
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Error(err)
+		}
+	}
+
+	for expiry := 1; expiry <= 3; expiry++ {
+		getPoolsConcurrently()
+		if server.loginCount() != int64(expiry) {
+			t.Fatalf("expected exactly %d login(s) after %d token expiration(s), got: %d",
+				expiry, expiry, server.loginCount())
+		}
+		server.expireTokens()
+	}
+}
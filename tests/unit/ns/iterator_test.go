@@ -0,0 +1,93 @@
+package provider_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+)
+
+// fakeFilesystemList serves filesystem pages as NS does: parent goes first, then its children
+type fakeFilesystemList struct {
+	filesystems []ns.Filesystem
+	requests    int
+}
+
+func newFakeFilesystemList(parent string, count int) *fakeFilesystemList {
+	list := &fakeFilesystemList{filesystems: []ns.Filesystem{{Path: parent}}}
+	for i := 0; i < count; i++ {
+		list.filesystems = append(list.filesystems, ns.Filesystem{Path: fmt.Sprintf("%s/fs%03d", parent, i)})
+	}
+	return list
+}
+
+func (l *fakeFilesystemList) fetch(limit, offset int) ([]ns.Filesystem, error) {
+	l.requests++
+	if offset >= len(l.filesystems) {
+		return []ns.Filesystem{}, nil
+	}
+	end := offset + limit
+	if end > len(l.filesystems) {
+		end = len(l.filesystems)
+	}
+	return append([]ns.Filesystem{}, l.filesystems[offset:end]...), nil
+}
+
+func TestIterator_FilesystemIterator(t *testing.T) {
+	parent := "pool/dataset"
+	list := newFakeFilesystemList(parent, 250)
+	ctx := context.Background()
+
+	t.Run("Next() should return all filesystems except parent", func(t *testing.T) {
+		it := ns.NewFilesystemIterator(parent, ns.IteratorParams{PageSize: 40}, list.fetch)
+
+		var filesystems []ns.Filesystem
+		for !it.Done() {
+			page, err := it.Next(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			filesystems = append(filesystems, page...)
+		}
+
+		if len(filesystems) != 250 {
+			t.Errorf("expected 250 filesystems, but got %d", len(filesystems))
+		} else if filesystems[0].Path != parent+"/fs000" || filesystems[249].Path != parent+"/fs249" {
+			t.Errorf("unexpected first/last filesystems: %s, %s", filesystems[0].Path, filesystems[249].Path)
+		} else if it.Token() != "" {
+			t.Errorf("token should be empty when iterator is done, got '%s'", it.Token())
+		}
+	})
+
+	t.Run("resumed iterator should continue with one request", func(t *testing.T) {
+		it := ns.NewFilesystemIterator(parent, ns.IteratorParams{PageSize: 50}, list.fetch)
+		if _, err := it.Next(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := it.Next(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		list.requests = 0
+		resumed := ns.NewFilesystemIterator(parent, ns.IteratorParams{PageSize: 50, StartingToken: it.Token()}, list.fetch)
+		page, err := resumed.Next(ctx)
+		if err != nil {
+			t.Fatal(err)
+		} else if list.requests != 1 {
+			t.Errorf("expected 1 request, but got %d", list.requests)
+		} else if len(page) != 50 || page[0].Path != parent+"/fs099" {
+			t.Errorf("expected 50 filesystems starting with fs099, got %d: %v", len(page), page)
+		}
+	})
+
+	t.Run("path token should be supported", func(t *testing.T) {
+		it := ns.NewFilesystemIterator(parent, ns.IteratorParams{StartingToken: parent + "/fs200"}, list.fetch)
+		page, err := it.Next(ctx)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(page) == 0 || page[0].Path != parent+"/fs201" {
+			t.Errorf("expected filesystems starting with fs201, got: %v", page)
+		}
+	})
+}
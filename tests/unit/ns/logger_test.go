@@ -0,0 +1,96 @@
+package provider_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Nexenta/go-nexentastor/pkg/logger"
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/rest"
+)
+
+func TestProvider_LogRedaction(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.TrimLeft(r.URL.Path, "/") == "auth/login" {
+			fmt.Fprint(w, `{"token": "secrettoken"}`)
+			return
+		}
+		fmt.Fprint(w, `{"data": []}`)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	l := logrus.New()
+	l.SetOutput(&buf)
+	l.SetLevel(logrus.DebugLevel)
+
+	nsp, err := ns.NewProvider(ns.ProviderArgs{
+		Address:  server.URL,
+		Username: "admin",
+		Password: "secretpass",
+		Log:      logrus.NewEntry(l),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := nsp.LogIn(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "Username:admin") {
+		t.Fatalf("expected login request payload to be logged, got: %s", out)
+	}
+	if strings.Contains(out, "secretpass") {
+		t.Errorf("expected password to be masked in log, got: %s", out)
+	}
+}
+
+func TestProvider_LogEntry(t *testing.T) {
+	l := logrus.New()
+	l.SetOutput(ioutil.Discard)
+
+	nsp, err := ns.NewProvider(ns.ProviderArgs{Address: "https://10.3.199.254:8443", Log: logrus.NewEntry(l)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if log := nsp.(*ns.Provider).Log; log == nil || log.Data["cmp"] != "NSProvider" {
+		t.Errorf("expected provider logrus entry with provider fields, got: %v", log)
+	}
+
+	nsp, err = ns.NewProvider(ns.ProviderArgs{Address: "https://10.3.199.254:8443", Logger: logger.Nop{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if log := nsp.(*ns.Provider).Log; log != nil {
+		t.Errorf("expected no logrus entry when only Logger is set, got: %v", log)
+	}
+
+	// provider created without NewProvider() writes to its Log
+	var buf bytes.Buffer
+	l.SetOutput(&buf)
+	l.SetLevel(logrus.DebugLevel)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"token": "token"}`)
+	}))
+	defer server.Close()
+	provider := &ns.Provider{
+		Address:    server.URL,
+		RestClient: rest.NewClient(rest.ClientArgs{Address: server.URL}),
+		Log:        logrus.NewEntry(l).WithField("cmp", "custom"),
+	}
+	if err := provider.LogIn(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "cmp=custom") {
+		t.Errorf("expected provider Log to be used, got: %s", buf.String())
+	}
+}
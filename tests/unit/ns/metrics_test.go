@@ -0,0 +1,77 @@
+package provider_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Nexenta/go-nexentastor/pkg/metrics"
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+)
+
+// testMetrics - metrics.Metrics which counts observations
+type testMetrics struct {
+	mux    sync.Mutex
+	counts map[string]int
+}
+
+func (m *testMetrics) inc(key string) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.counts[key]++
+}
+
+func (m *testMetrics) ObserveRequest(address, method, endpoint, code string, duration time.Duration) {
+	m.inc(fmt.Sprintf("request %s %s %s", method, endpoint, code))
+}
+
+func (m *testMetrics) ObserveJob(address, outcome string, duration time.Duration) {
+	m.inc("job " + outcome)
+}
+
+func (m *testMetrics) ObserveLogin(address string, err error) {
+	m.inc(fmt.Sprintf("login %t", err == nil))
+}
+
+func (m *testMetrics) ObserveRetry(address, reason string) {
+	m.inc("retry " + reason)
+}
+
+func TestProvider_Metrics(t *testing.T) {
+	server := newAuthTestServer(func(n int64) string { return fmt.Sprintf("metrics-token-%d", n) })
+	defer server.Close()
+
+	l := logrus.New().WithField("test", t.Name())
+	l.Logger.SetLevel(logrus.PanicLevel)
+
+	m := &testMetrics{counts: map[string]int{}}
+	nsp, err := ns.NewProvider(ns.ProviderArgs{
+		Address:     server.URL,
+		Credentials: ns.NewStaticCredentialSource("metrics", "pass"),
+		Log:         l,
+		Metrics:     m,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := nsp.GetPools(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]int{
+		"request GET /storage/pools 401": 1,
+		"request POST /auth/login 200":   1,
+		"request GET /storage/pools 200": 1,
+		"login true":                     1,
+		"retry " + metrics.RetryAuth:     1,
+	}
+	for key, count := range expected {
+		if m.counts[key] != count {
+			t.Errorf("expected %d '%s' observation(s), got: %d (all: %v)", count, key, m.counts[key], m.counts)
+		}
+	}
+}
@@ -1,9 +1,21 @@
 package provider_test
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/sirupsen/logrus"
+
 	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/rest"
 )
 
 func TestProvider_Filesystem(t *testing.T) {
@@ -19,3 +31,628 @@ func TestProvider_Filesystem(t *testing.T) {
 		}
 	})
 }
+
+func newTestProvider(t *testing.T, handler http.HandlerFunc) (ns.ProviderInterface, func()) {
+	server := httptest.NewServer(handler)
+
+	l := logrus.New().WithField("test", t.Name())
+	l.Logger.SetLevel(logrus.PanicLevel)
+
+	nsp, err := ns.NewProvider(ns.ProviderArgs{
+		Address:  server.URL,
+		Username: "admin",
+		Password: "pass",
+		Log:      l,
+	})
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+
+	return nsp, server.Close
+}
+
+func TestProvider_GetNextFreeLun(t *testing.T) {
+	nsp, closeServer := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.TrimLeft(r.URL.Path, "/") != "san/lunMappings" || r.URL.Query().Get("hostGroup") != "hg1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"data": [{"id": "a", "lun": 0}, {"id": "b", "lun": 2}, {"id": "c", "lun": 1}, {"id": "d", "lun": 5}]}`)
+	})
+	defer closeServer()
+
+	lun, err := nsp.GetNextFreeLun("hg1")
+	if err != nil {
+		t.Fatal(err)
+	} else if lun != 3 {
+		t.Errorf("expected LUN 3, but got %d instead", lun)
+	}
+}
+
+func TestProvider_CreateLunMapping_AlreadyExists(t *testing.T) {
+	nsp, closeServer := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprint(w, `{"name": "ExistsError", "message": "LUN is in use", "code": "EEXIST"}`)
+	})
+	defer closeServer()
+
+	params := ns.CreateLunMappingParams{Volume: "p/vg/v", HostGroup: "hg", TargetGroup: "tg"}
+	if err := nsp.CreateLunMapping(params); err != nil {
+		t.Errorf("existing mapping with allocated LUN should not be an error, but got: %v", err)
+	}
+
+	lun := 3
+	params.Lun = &lun
+	if err := nsp.CreateLunMapping(params); !ns.IsAlreadyExistNefError(err) {
+		t.Errorf("expected EEXIST error for explicit LUN, but got: %v", err)
+	}
+}
+
+func TestProvider_NormalizeWWPN(t *testing.T) {
+	expected := "wwn.21000024FF4C5A10"
+
+	for _, wwpn := range []string{
+		"21:00:00:24:ff:4c:5a:10",
+		"21-00-00-24-FF-4C-5A-10",
+		"0x21000024ff4c5a10",
+		"naa.21000024ff4c5a10",
+		"wwn.21000024FF4C5A10",
+	} {
+		normalized, err := ns.NormalizeWWPN(wwpn)
+		if err != nil {
+			t.Errorf("'%s': %s", wwpn, err)
+		} else if normalized != expected {
+			t.Errorf("'%s': expected '%s', but got '%s' instead", wwpn, expected, normalized)
+		}
+	}
+
+	for _, wwpn := range []string{"", "21:00:00:24:ff:4c:5a", "21:00:00:24:ff:4c:5a:zz", "iqn.2005-07.com.nexenta:01"} {
+		if _, err := ns.NormalizeWWPN(wwpn); err == nil {
+			t.Errorf("'%s': expected an error", wwpn)
+		}
+	}
+}
+
+func TestProvider_ValidateVolumeBlockSize(t *testing.T) {
+	for _, size := range []int64{512, 4096, 8192, 131072} {
+		if err := ns.ValidateVolumeBlockSize(size); err != nil {
+			t.Errorf("%d: %s", size, err)
+		}
+	}
+
+	for _, size := range []int64{0, 256, 1000, 12288, 262144} {
+		if err := ns.ValidateVolumeBlockSize(size); err == nil {
+			t.Errorf("%d: expected an error", size)
+		}
+	}
+}
+
+func TestProvider_UpdateVolume_ReservationSize(t *testing.T) {
+	var sent map[string]interface{}
+	nsp, closeServer := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		sent = nil
+		json.NewDecoder(r.Body).Decode(&sent)
+		fmt.Fprint(w, `{}`)
+	})
+	defer closeServer()
+
+	zero := int64(0)
+	if err := nsp.UpdateVolume("p/vg/v", ns.UpdateVolumeParams{ReservationSize: &zero}); err != nil {
+		t.Fatal(err)
+	}
+	if value, ok := sent["reservationSize"]; !ok || value != float64(0) {
+		t.Errorf("reservation should be cleared with reservationSize 0, but sent: %v", sent)
+	}
+
+	if err := nsp.UpdateVolume("p/vg/v", ns.UpdateVolumeParams{LogBias: "latency"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sent["reservationSize"]; ok {
+		t.Errorf("reservationSize should not be sent if not set, but sent: %v", sent)
+	}
+}
+
+func TestProvider_GetVolumeGroups(t *testing.T) {
+	nsp, closeServer := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if strings.TrimLeft(r.URL.Path, "/") != "storage/volumeGroups" || query.Get("pool") != "pool" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		// the first page is full, so the second one is requested
+		if query.Get("offset") == "0" {
+			fmt.Fprint(w, `{"data": [`)
+			for i := 0; i < 100; i++ {
+				if i > 0 {
+					fmt.Fprint(w, `,`)
+				}
+				fmt.Fprintf(w, `{"path": "pool/vg%d"}`, i)
+			}
+			fmt.Fprint(w, `]}`)
+			return
+		}
+		fmt.Fprint(w, `{"data": [{"path": "pool/fs/vg", "bytesUsed": 1024}]}`)
+	})
+	defer closeServer()
+
+	volumeGroups, err := nsp.GetVolumeGroups("pool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(volumeGroups) != 101 || volumeGroups[100].Path != "pool/fs/vg" || volumeGroups[100].BytesUsed != 1024 {
+		t.Errorf("expected 101 volumeGroups of both pages, got: %+v", volumeGroups)
+	}
+
+	if _, err := nsp.GetVolumeGroups(""); err == nil {
+		t.Error("expected error for empty pool name")
+	}
+}
+
+func TestProvider_ResizeVolume(t *testing.T) {
+	var updatedSize int64
+	nsp, closeServer := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		switch path := strings.TrimLeft(r.URL.Path, "/"); {
+		case r.Method == http.MethodGet && path == "storage/volumes":
+			fmt.Fprint(w, `{"data": [{"path": "p/vg/v", "volumeSize": 1048576, "volumeBlockSize": 8192}]}`)
+		case r.Method == http.MethodGet && path == "san/lunMappings":
+			fmt.Fprint(w, `{"data": [{"id": "m1", "volume": "p/vg/v", "lun": 1}]}`)
+		case r.Method == http.MethodPut && path == "storage/volumes/p/vg/v":
+			params := ns.UpdateVolumeParams{}
+			json.NewDecoder(r.Body).Decode(&params)
+			updatedSize = params.VolumeSize
+			fmt.Fprint(w, `{}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer closeServer()
+
+	t.Run("shrink should be rejected unless forced", func(t *testing.T) {
+		_, err := nsp.ResizeVolume("p/vg/v", 8192, ns.ResizeVolumeParams{})
+		if !ns.IsBadArgNefError(err) {
+			t.Errorf("expected EBADARG error, but got: %v", err)
+		} else if updatedSize != 0 {
+			t.Errorf("volume should not be updated, but got size %d", updatedSize)
+		}
+	})
+
+	t.Run("size should be rounded up to block size", func(t *testing.T) {
+		result, err := nsp.ResizeVolume("p/vg/v", 2*1048576+1, ns.ResizeVolumeParams{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectedSize := int64(2*1048576 + 8192)
+		if result.VolumeSize != expectedSize || updatedSize != expectedSize {
+			t.Errorf("expected size %d, but got %d (sent: %d)", expectedSize, result.VolumeSize, updatedSize)
+		} else if !result.IsLunMapped() {
+			t.Errorf("volume should be reported as LUN mapped: %+v", result)
+		}
+	})
+}
+
+func TestProvider_ListFilesystems(t *testing.T) {
+	var query url.Values
+	nsp, closeServer := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		fmt.Fprint(w, `{"data": [{"path": "p/d"}, {"path": "p/d/fs1", "sharedOverNfs": true}]}`)
+	})
+	defer closeServer()
+
+	filesystems, err := nsp.ListFilesystems("p/d", ns.ListOptions{
+		Fields:    []string{"sharedOverNfs"},
+		Filters:   map[string]string{"sharedOverNfs": "true"},
+		SortBy:    "path",
+		SortOrder: "desc",
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(filesystems) != 1 || !filesystems[0].SharedOverNfs {
+		t.Errorf("expected only 'p/d/fs1' filesystem, but got: %+v", filesystems)
+	}
+
+	expectedQuery := map[string]string{
+		"parent":         "p/d",
+		"fields":         "path,sharedOverNfs",
+		"sharedOverNfs":  "true",
+		"orderBy":        "path",
+		"orderDirection": "desc",
+	}
+	for key, value := range expectedQuery {
+		if query.Get(key) != value {
+			t.Errorf("expected query parameter '%s=%s', but got '%s'", key, value, query.Get(key))
+		}
+	}
+}
+
+func TestNewProvider_InvalidTLS(t *testing.T) {
+	_, err := ns.NewProvider(ns.ProviderArgs{
+		Address: "https://nexentastor.local:8443",
+		TLS:     rest.TLSOptions{CAFile: "/nonexistent/ca.pem"},
+	})
+	if err == nil {
+		t.Error("expected provider creation to fail with invalid TLS configuration, got none")
+	}
+}
+
+func TestProvider_NfsShareAndACL(t *testing.T) {
+	var deleted string
+	nsp, closeServer := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		switch path := strings.TrimLeft(r.URL.EscapedPath(), "/"); {
+		case r.Method == http.MethodGet && path == "nas/nfs/pool%2Ffs":
+			fmt.Fprint(w, `{"filesystem": "pool/fs", "anon": "root", "securityContexts": [{
+				"securityModes": ["sys"],
+				"readWriteList": [{"etype": "network", "entity": "10.3.0.0", "mask": 16}],
+				"readOnlyList": [{"etype": "fqdn", "entity": "none"}]
+			}]}`)
+		case r.Method == http.MethodGet && path == "storage/filesystems/pool%2Ffs/acl":
+			fmt.Fprint(w, `{"data": [
+				{"index": 0, "type": "allow", "principal": "owner@", "permissions": ["full_set"]},
+				{"index": 1, "type": "allow", "principal": "everyone@", "permissions": ["read_set"]}
+			]}`)
+		case r.Method == http.MethodDelete && strings.HasPrefix(path, "storage/filesystems/pool%2Ffs/acl/"):
+			deleted = path
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer closeServer()
+
+	share, err := nsp.GetNfsShare("pool/fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(share.SecurityContexts) != 1 || share.SecurityContexts[0].ReadWriteList[0].Mask != 16 {
+		t.Errorf("unexpected NFS share: %+v", share)
+	}
+
+	rules, err := nsp.GetFilesystemACL("pool/fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("expected 2 ACL entries, got: %+v", rules)
+	}
+	if _, ok := rules[0].RuleSet(); ok {
+		t.Errorf("expected owner@ entry not to be a rule set, got: %+v", rules[0])
+	}
+	if ruleSet, ok := rules[1].RuleSet(); !ok || ruleSet != ns.ACLReadOnly {
+		t.Errorf("expected everyone@ entry to be read only rule set, got: %+v", rules[1])
+	}
+
+	if err := nsp.DeleteFilesystemACLRule("pool/fs", 1); err != nil {
+		t.Fatal(err)
+	}
+	if deleted != "storage/filesystems/pool%2Ffs/acl/1" {
+		t.Errorf("unexpected ACL entry deletion URI: '%s'", deleted)
+	}
+}
+
+func TestCreateNfsShareParams_SecurityContext(t *testing.T) {
+	network := ns.NfsRuleList{Etype: "network", Entity: "10.3.0.0", Mask: 16}
+	none := ns.NfsRuleList{Etype: "fqdn", Entity: "none"}
+	all := ns.NfsRuleList{Etype: "fqdn", Entity: "*"}
+
+	tests := map[string]struct {
+		params   ns.CreateNfsShareParams
+		expected ns.NfsSecurityContext
+	}{
+		"no rules": {
+			params:   ns.CreateNfsShareParams{},
+			expected: ns.NfsSecurityContext{ReadWriteList: []ns.NfsRuleList{all}, ReadOnlyList: []ns.NfsRuleList{none}},
+		},
+		"read-write rules": {
+			params:   ns.CreateNfsShareParams{ReadWriteList: []ns.NfsRuleList{network}},
+			expected: ns.NfsSecurityContext{ReadWriteList: []ns.NfsRuleList{network}, ReadOnlyList: []ns.NfsRuleList{none}},
+		},
+		"read-only rules": {
+			params:   ns.CreateNfsShareParams{ReadOnlyList: []ns.NfsRuleList{network}},
+			expected: ns.NfsSecurityContext{ReadWriteList: []ns.NfsRuleList{none}, ReadOnlyList: []ns.NfsRuleList{network}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test.expected.SecurityModes = []string{"sys"}
+			if context := test.params.SecurityContext(); !reflect.DeepEqual(context, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, context)
+			}
+		})
+	}
+}
+
+// requestLog - "METHOD path" of requests received by test server, paths are escaped and have no leading slash
+type requestLog struct {
+	mux      sync.Mutex
+	requests []string
+}
+
+func (l *requestLog) add(r *http.Request) string {
+	request := fmt.Sprintf("%s %s", r.Method, strings.TrimLeft(r.URL.EscapedPath(), "/"))
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.requests = append(l.requests, request)
+	return request
+}
+
+func (l *requestLog) get() []string {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return append([]string{}, l.requests...)
+}
+
+func TestProvider_CloneVolumeSnapshot(t *testing.T) {
+	log := &requestLog{}
+	nsp, closeServer := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		switch request := log.add(r); request {
+		case "GET storage/volumes":
+			// clone has the size of the snapshot
+			fmt.Fprint(w, `{"data": [{"path": "pool/vg/clone", "volumeSize": 2048}]}`)
+		case "POST storage/snapshots/pool%2Fvg%2Fv@s/clone":
+			w.WriteHeader(http.StatusCreated)
+		case "PUT storage/volumes/pool%2Fvg%2Fclone":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["volumeSize"] == float64(8192) {
+				w.WriteHeader(http.StatusInsufficientStorage)
+				fmt.Fprint(w, `{"name": "NoSpaceError", "message": "out of space", "code": "ENOSPC"}`)
+			}
+		case "DELETE storage/volumes/pool%2Fvg%2Fclone":
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer closeServer()
+
+	tests := []struct {
+		size     int64
+		ok       bool
+		requests []string
+	}{
+		{
+			size:     0,
+			ok:       true,
+			requests: []string{"POST storage/snapshots/pool%2Fvg%2Fv@s/clone"},
+		},
+		{
+			size: 2048,
+			ok:   true,
+			requests: []string{
+				"POST storage/snapshots/pool%2Fvg%2Fv@s/clone",
+				"GET storage/volumes",
+			},
+		},
+		{
+			size: 4096,
+			ok:   true,
+			requests: []string{
+				"POST storage/snapshots/pool%2Fvg%2Fv@s/clone",
+				"GET storage/volumes",
+				"PUT storage/volumes/pool%2Fvg%2Fclone",
+			},
+		},
+		{
+			// clone is never shrunk
+			size: 1024,
+			ok:   false,
+			requests: []string{
+				"POST storage/snapshots/pool%2Fvg%2Fv@s/clone",
+				"GET storage/volumes",
+				"DELETE storage/volumes/pool%2Fvg%2Fclone",
+			},
+		},
+		{
+			// clone is destroyed if it cannot be resized
+			size: 8192,
+			ok:   false,
+			requests: []string{
+				"POST storage/snapshots/pool%2Fvg%2Fv@s/clone",
+				"GET storage/volumes",
+				"PUT storage/volumes/pool%2Fvg%2Fclone",
+				"DELETE storage/volumes/pool%2Fvg%2Fclone",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("size %d", test.size), func(t *testing.T) {
+			log.requests = nil
+			err := nsp.CloneVolumeSnapshot("pool/vg/v@s", ns.CloneVolumeSnapshotParams{
+				TargetPath: "pool/vg/clone",
+				VolumeSize: test.size,
+			})
+			if test.ok && err != nil {
+				t.Errorf("expected clone to succeed, got: %s", err)
+			} else if !test.ok && (err == nil || !strings.Contains(err.Error(), "the clone is destroyed")) {
+				t.Errorf("expected an error about destroyed clone, got: %v", err)
+			}
+			if requests := log.get(); !reflect.DeepEqual(requests, test.requests) {
+				t.Errorf("expected requests %q, got %q", test.requests, requests)
+			}
+		})
+	}
+}
+
+func TestProvider_DestroyVolume_PromoteMostRecentClone(t *testing.T) {
+	log := &requestLog{}
+	destroyed := false
+	nsp, closeServer := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		switch request := log.add(r); request {
+		case "DELETE storage/volumes/pool%2Fvg%2Fv":
+			if !destroyed {
+				destroyed = true
+				w.WriteHeader(http.StatusConflict)
+				fmt.Fprint(w, `{"name": "ExistsError", "message": "volume has dependent clones", "code": "EEXIST"}`)
+			}
+		case "GET storage/snapshots":
+			if r.URL.Query().Get("parent") != "pool/vg/v" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			fmt.Fprint(w, `{"data": [{"path": "pool/vg/v@s1"}, {"path": "pool/vg/v@s2"}]}`)
+		case "GET storage/snapshots/pool%2Fvg%2Fv@s1":
+			fmt.Fprint(w, `{"path": "pool/vg/v@s1", "clones": ["pool/vg/old"], "creationTxg": "10"}`)
+		case "GET storage/snapshots/pool%2Fvg%2Fv@s2":
+			fmt.Fprint(w, `{"path": "pool/vg/v@s2", "clones": ["pool/vg/new"], "creationTxg": "20"}`)
+		case "POST storage/volumes/pool%2Fvg%2Fnew/promote":
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer closeServer()
+
+	err := nsp.DestroyVolume("pool/vg/v", ns.DestroyVolumeParams{PromoteMostRecentCloneIfExists: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedRequests := []string{
+		"DELETE storage/volumes/pool%2Fvg%2Fv",
+		"GET storage/snapshots",
+		"GET storage/snapshots/pool%2Fvg%2Fv@s1",
+		"GET storage/snapshots/pool%2Fvg%2Fv@s2",
+		"POST storage/volumes/pool%2Fvg%2Fnew/promote",
+		"DELETE storage/volumes/pool%2Fvg%2Fv",
+	}
+	if requests := log.get(); !reflect.DeepEqual(requests, expectedRequests) {
+		t.Errorf("expected requests %q, got %q", expectedRequests, requests)
+	}
+}
+
+func TestProvider_DestroyFilesystem_PromoteMostRecentClone(t *testing.T) {
+	log := &requestLog{}
+	destroyed := false
+	nsp, closeServer := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		switch request := log.add(r); request {
+		case "DELETE storage/filesystems/pool%2Ffs":
+			if !destroyed {
+				destroyed = true
+				w.WriteHeader(http.StatusConflict)
+				fmt.Fprint(w, `{"name": "ExistsError", "message": "filesystem has dependent clones", "code": "EEXIST"}`)
+			}
+		case "GET storage/snapshots":
+			if r.URL.Query().Get("parent") != "pool/fs" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			fmt.Fprint(w, `{"data": [{"path": "pool/fs@s1"}, {"path": "pool/fs@s2"}]}`)
+		case "GET storage/snapshots/pool%2Ffs@s1":
+			fmt.Fprint(w, `{"path": "pool/fs@s1", "clones": ["pool/new"], "creationTxg": "30"}`)
+		case "GET storage/snapshots/pool%2Ffs@s2":
+			fmt.Fprint(w, `{"path": "pool/fs@s2", "clones": [], "creationTxg": "40"}`)
+		case "POST storage/filesystems/pool%2Fnew/promote":
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer closeServer()
+
+	err := nsp.DestroyFilesystem("pool/fs", ns.DestroyFilesystemParams{PromoteMostRecentCloneIfExists: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// snapshot without clones is skipped even if it's more recent
+	expectedRequests := []string{
+		"DELETE storage/filesystems/pool%2Ffs",
+		"GET storage/snapshots",
+		"GET storage/snapshots/pool%2Ffs@s1",
+		"GET storage/snapshots/pool%2Ffs@s2",
+		"POST storage/filesystems/pool%2Fnew/promote",
+		"DELETE storage/filesystems/pool%2Ffs",
+	}
+	if requests := log.get(); !reflect.DeepEqual(requests, expectedRequests) {
+		t.Errorf("expected requests %q, got %q", expectedRequests, requests)
+	}
+}
+
+func TestProvider_VolumeRequests(t *testing.T) {
+	type call struct {
+		request string
+		query   url.Values
+		body    map[string]interface{}
+	}
+	var calls []call
+	nsp, closeServer := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		c := call{request: fmt.Sprintf("%s %s", r.Method, strings.TrimLeft(r.URL.EscapedPath(), "/")), query: r.URL.Query()}
+		if r.Method == http.MethodPost {
+			if err := json.NewDecoder(r.Body).Decode(&c.body); err != nil && err != io.EOF {
+				t.Error(err)
+			}
+		}
+		calls = append(calls, c)
+	})
+	defer closeServer()
+
+	tests := map[string]struct {
+		run      func() error
+		expected call
+	}{
+		"PromoteVolume": {
+			run:      func() error { return nsp.PromoteVolume("pool/vg/clone") },
+			expected: call{request: "POST storage/volumes/pool%2Fvg%2Fclone/promote"},
+		},
+		"CreateVolumeGroup": {
+			run: func() error {
+				return nsp.CreateVolumeGroup(ns.CreateVolumeGroupParams{Path: "pool/vg", VolumeBlockSize: 32768})
+			},
+			expected: call{
+				request: "POST storage/volumeGroups",
+				body:    map[string]interface{}{"path": "pool/vg", "volumeBlockSize": float64(32768)},
+			},
+		},
+		"CreateVolumeGroup without block size": {
+			run: func() error { return nsp.CreateVolumeGroup(ns.CreateVolumeGroupParams{Path: "pool/vg"}) },
+			expected: call{
+				request: "POST storage/volumeGroups",
+				body:    map[string]interface{}{"path": "pool/vg"},
+			},
+		},
+		"DestroyVolumeGroup": {
+			run: func() error { return nsp.DestroyVolumeGroup("pool/vg", ns.DestroyVolumeGroupParams{}) },
+			expected: call{
+				request: "DELETE storage/volumeGroups/pool%2Fvg",
+				query:   url.Values{"force": {"false"}, "snapshots": {"false"}},
+			},
+		},
+		"DestroyVolumeGroup recursive": {
+			run: func() error {
+				return nsp.DestroyVolumeGroup("pool/vg", ns.DestroyVolumeGroupParams{Recursive: true})
+			},
+			expected: call{
+				request: "DELETE storage/volumeGroups/pool%2Fvg",
+				query:   url.Values{"force": {"true"}, "snapshots": {"true"}},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			calls = nil
+			if err := test.run(); err != nil {
+				t.Fatal(err)
+			}
+			if test.expected.query == nil {
+				test.expected.query = url.Values{}
+			}
+			if len(calls) != 1 || !reflect.DeepEqual(calls[0], test.expected) {
+				t.Errorf("expected request %+v, got %+v", test.expected, calls)
+			}
+		})
+	}
+
+	for name, run := range map[string]func() error{
+		"PromoteVolume":     func() error { return nsp.PromoteVolume("") },
+		"CreateVolumeGroup": func() error { return nsp.CreateVolumeGroup(ns.CreateVolumeGroupParams{}) },
+		"CreateVolumeGroup invalid": func() error {
+			return nsp.CreateVolumeGroup(ns.CreateVolumeGroupParams{Path: "pool/vg", VolumeBlockSize: 1000})
+		},
+		"DestroyVolumeGroup": func() error { return nsp.DestroyVolumeGroup("", ns.DestroyVolumeGroupParams{}) },
+	} {
+		calls = nil
+		if err := run(); err == nil || len(calls) != 0 {
+			t.Errorf("%s: expected validation error without requests, got: %v, requests: %+v", name, err, calls)
+		}
+	}
+}
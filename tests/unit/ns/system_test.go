@@ -0,0 +1,58 @@
+package provider_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+)
+
+func TestVersion_Parse(t *testing.T) {
+	for version, expected := range map[string]ns.Version{
+		"5":           {Major: 5},
+		"5.3":         {Major: 5, Minor: 3},
+		"5.3.1.0-b12": {Major: 5, Minor: 3, Patch: 1},
+	} {
+		parsed, err := ns.ParseVersion(version)
+		if err != nil {
+			t.Errorf("%s: %s", version, err)
+		} else if parsed.Major != expected.Major || parsed.Minor != expected.Minor || parsed.Patch != expected.Patch {
+			t.Errorf("%s: expected %+v, got: %+v", version, expected, parsed)
+		} else if parsed.String() != version {
+			t.Errorf("%s: expected original version string, got: %s", version, parsed)
+		}
+	}
+
+	if _, err := ns.ParseVersion("unknown"); err == nil {
+		t.Error("expected error for invalid version")
+	}
+
+	v53, _ := ns.ParseVersion("5.3")
+	v531, _ := ns.ParseVersion("5.3.1")
+	v6, _ := ns.ParseVersion("6.0")
+	if !v53.Less(v531) || !v531.Less(v6) || v6.Less(v53) || v53.Less(v53) {
+		t.Error("unexpected version order")
+	}
+}
+
+func TestProvider_GetSystemInfo(t *testing.T) {
+	nsp, closeServer := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.TrimLeft(r.URL.Path, "/") != "system/info" || r.URL.Query().Get("fields") != "version,hostName,guid" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"version": "5.3.1.0-b12", "hostName": "ns1", "guid": "guid1"}`)
+	})
+	defer closeServer()
+
+	info, err := nsp.GetSystemInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := ns.SystemInfo{Version: "5.3.1.0-b12", Hostname: "ns1", GUID: "guid1"}
+	if info != expected {
+		t.Errorf("expected %+v, got: %+v", expected, info)
+	}
+}
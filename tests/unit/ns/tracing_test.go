@@ -0,0 +1,227 @@
+package provider_test
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+)
+
+// testTracerProvider - trace.TracerProvider which keeps all ended spans
+type testTracerProvider struct {
+	embedded.TracerProvider
+
+	mux   sync.Mutex
+	spans []*testSpan
+}
+
+func (tp *testTracerProvider) Tracer(name string, options ...trace.TracerOption) trace.Tracer {
+	return &testTracer{provider: tp}
+}
+
+func (tp *testTracerProvider) endedSpans() []*testSpan {
+	tp.mux.Lock()
+	defer tp.mux.Unlock()
+	return append([]*testSpan{}, tp.spans...)
+}
+
+type testTracer struct {
+	embedded.Tracer
+	provider *testTracerProvider
+}
+
+func (t *testTracer) Start(ctx context.Context, name string, options ...trace.SpanStartOption) (
+	context.Context,
+	trace.Span,
+) {
+	parent := trace.SpanContextFromContext(ctx)
+
+	traceID := parent.TraceID()
+	if !traceID.IsValid() {
+		rand.Read(traceID[:])
+	}
+	var spanID trace.SpanID
+	rand.Read(spanID[:])
+
+	span := &testSpan{
+		provider: t.provider,
+		name:     name,
+		parentID: parent.SpanID(),
+		spanContext: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    traceID,
+			SpanID:     spanID,
+			TraceFlags: trace.FlagsSampled,
+		}),
+		attributes: map[attribute.Key]attribute.Value{},
+	}
+	config := trace.NewSpanStartConfig(options...)
+	span.SetAttributes(config.Attributes()...)
+
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+// testSpan - recorded span, only methods used by the library are implemented
+type testSpan struct {
+	noop.Span
+	provider *testTracerProvider
+
+	name        string
+	parentID    trace.SpanID
+	spanContext trace.SpanContext
+	attributes  map[attribute.Key]attribute.Value
+	status      codes.Code
+}
+
+func (s *testSpan) SpanContext() trace.SpanContext { return s.spanContext }
+
+func (s *testSpan) IsRecording() bool { return true }
+
+func (s *testSpan) SetAttributes(attributes ...attribute.KeyValue) {
+	for _, kv := range attributes {
+		s.attributes[kv.Key] = kv.Value
+	}
+}
+
+func (s *testSpan) SetStatus(code codes.Code, description string) { s.status = code }
+
+func (s *testSpan) End(options ...trace.SpanEndOption) {
+	s.provider.mux.Lock()
+	defer s.provider.mux.Unlock()
+	s.provider.spans = append(s.provider.spans, s)
+}
+
+func TestProvider_Tracing(t *testing.T) {
+	var traceparentMux sync.Mutex
+	traceparents := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparentMux.Lock()
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		traceparentMux.Unlock()
+
+		if strings.TrimLeft(r.URL.Path, "/") == "storage/pools" {
+			fmt.Fprint(w, `{"data": [{"poolName": "pool"}]}`)
+		} else {
+			fmt.Fprint(w, `{"data": []}`)
+		}
+	}))
+	defer server.Close()
+
+	l := logrus.New().WithField("test", t.Name())
+	l.Logger.SetLevel(logrus.PanicLevel)
+
+	tracerProvider := &testTracerProvider{}
+	nsp, err := ns.NewProvider(ns.ProviderArgs{
+		Address:        server.URL,
+		Username:       "admin",
+		Password:       "pass",
+		Log:            l,
+		TracerProvider: tracerProvider,
+		Propagator:     propagation.TraceContext{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("request spans are children of method span", func(t *testing.T) {
+		if _, err := nsp.GetPools(); err != nil {
+			t.Fatal(err)
+		}
+
+		spans := tracerProvider.endedSpans()
+		if len(spans) != 2 {
+			t.Fatalf("expected 2 spans, got: %d", len(spans))
+		}
+
+		requestSpan, methodSpan := spans[0], spans[1]
+		if methodSpan.name != "ns.GetPools" || methodSpan.parentID.IsValid() {
+			t.Errorf("expected root 'ns.GetPools' span, got: '%s'", methodSpan.name)
+		}
+		if requestSpan.name != "GET /storage/pools" || requestSpan.parentID != methodSpan.spanContext.SpanID() {
+			t.Errorf("expected 'GET /storage/pools' child span, got: '%s'", requestSpan.name)
+		}
+		if requestSpan.attributes["nexentastor.request_id"].AsInt64() == 0 {
+			t.Errorf("expected request span to have request ID, got attributes: %v", requestSpan.attributes)
+		}
+
+		expectedTraceparent := fmt.Sprintf("00-%s-%s-01", methodSpan.spanContext.TraceID(), requestSpan.spanContext.SpanID())
+		if traceparents[0] != expectedTraceparent {
+			t.Errorf("expected 'traceparent: %s' header, got: '%s'", expectedTraceparent, traceparents[0])
+		}
+	})
+
+	t.Run("NEF error code is recorded", func(t *testing.T) {
+		if _, err := nsp.GetFilesystem("pool/fs"); !ns.IsNotExistNefError(err) {
+			t.Fatalf("expected ENOENT error, got: %v", err)
+		}
+
+		spans := tracerProvider.endedSpans()
+		methodSpan := spans[len(spans)-1]
+		if methodSpan.name != "ns.GetFilesystem" {
+			t.Fatalf("expected 'ns.GetFilesystem' span, got: '%s'", methodSpan.name)
+		}
+		if methodSpan.status != codes.Error {
+			t.Errorf("expected span error status, got: %v", methodSpan.status)
+		}
+		if code := methodSpan.attributes["nexentastor.error_code"].AsString(); code != "ENOENT" {
+			t.Errorf("expected 'ENOENT' error code attribute, got: '%s'", code)
+		}
+		if path := methodSpan.attributes["nexentastor.path"].AsString(); path != "pool/fs" {
+			t.Errorf("expected 'pool/fs' path attribute, got: '%s'", path)
+		}
+	})
+
+	t.Run("mutating methods record path", func(t *testing.T) {
+		if err := nsp.CreateFilesystem(ns.CreateFilesystemParams{Path: "pool/fs"}); err != nil {
+			t.Fatal(err)
+		}
+
+		spans := tracerProvider.endedSpans()
+		methodSpan := spans[len(spans)-1]
+		if methodSpan.name != "ns.CreateFilesystem" {
+			t.Fatalf("expected 'ns.CreateFilesystem' span, got: '%s'", methodSpan.name)
+		}
+		if path := methodSpan.attributes["nexentastor.path"].AsString(); path != "pool/fs" {
+			t.Errorf("expected 'pool/fs' path attribute, got: '%s'", path)
+		}
+	})
+
+	t.Run("spans of nested provider methods are children of the caller span", func(t *testing.T) {
+		ended := len(tracerProvider.endedSpans())
+		// GetVolumeSnapshots -> GetSnapshots -> ListSnapshots -> snapshot list request
+		if _, err := nsp.GetVolumeSnapshots("pool/vg/v"); err != nil {
+			t.Fatal(err)
+		}
+
+		spans := tracerProvider.endedSpans()[ended:]
+		expectedNames := []string{"GET /storage/snapshots", "ns.ListSnapshots", "ns.GetSnapshots", "ns.GetVolumeSnapshots"}
+		if len(spans) != len(expectedNames) {
+			t.Fatalf("expected %d spans, got: %d", len(expectedNames), len(spans))
+		}
+		for i, span := range spans {
+			if span.name != expectedNames[i] {
+				t.Errorf("expected '%s' span, got: '%s'", expectedNames[i], span.name)
+			}
+			if i > 0 && spans[i-1].parentID != span.spanContext.SpanID() {
+				t.Errorf("expected '%s' span to be a child of '%s'", spans[i-1].name, span.name)
+			}
+		}
+		if path := spans[3].attributes["nexentastor.path"].AsString(); path != "pool/vg/v" {
+			t.Errorf("expected 'pool/vg/v' path attribute, got: '%s'", path)
+		}
+	})
+}
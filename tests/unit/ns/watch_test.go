@@ -0,0 +1,126 @@
+package provider_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/ns/nsfake"
+)
+
+func receiveFilesystemEvent(t *testing.T, events <-chan ns.FilesystemEvent) ns.FilesystemEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no filesystem event received")
+	}
+	return ns.FilesystemEvent{}
+}
+
+func TestDatasetWatcher_WatchFilesystems(t *testing.T) {
+	p := nsfake.NewProvider(nsfake.ProviderArgs{Pools: []string{"pool"}})
+	for _, path := range []string{"pool/a", "pool/b"} {
+		if err := p.CreateFilesystem(ns.CreateFilesystemParams{Path: path}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	watcher, err := ns.NewDatasetWatcher(ns.DatasetWatcherArgs{Provider: p})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := watcher.WatchFilesystems(ctx, "pool", 10*time.Millisecond)
+
+	for _, path := range []string{"pool/a", "pool/b"} {
+		if event := receiveFilesystemEvent(t, events); event.Type != ns.WatchEventAdded || event.Filesystem.Path != path {
+			t.Errorf("expected %s to be added on the first list, got: %+v", path, event)
+		}
+	}
+
+	if err := p.UpdateFilesystem("pool/a", ns.UpdateFilesystemParams{ReferencedQuotaSize: 1024}); err != nil {
+		t.Fatal(err)
+	}
+	event := receiveFilesystemEvent(t, events)
+	if event.Type != ns.WatchEventModified || event.Filesystem.Path != "pool/a" ||
+		event.Filesystem.ReferencedQuotaSize != 1024 || event.Previous.ReferencedQuotaSize != 0 {
+		t.Errorf("expected pool/a to be modified, got: %+v", event)
+	}
+
+	if err := p.DestroyFilesystem("pool/b", ns.DestroyFilesystemParams{}); err != nil {
+		t.Fatal(err)
+	}
+	if event := receiveFilesystemEvent(t, events); event.Type != ns.WatchEventDeleted || event.Filesystem.Path != "pool/b" {
+		t.Errorf("expected pool/b to be deleted, got: %+v", event)
+	}
+
+	injected := errors.New("connection refused")
+	p.SetError("ListFilesystems", injected)
+	if event := receiveFilesystemEvent(t, events); event.Type != ns.WatchEventError || event.Err != injected {
+		t.Errorf("expected list error, got: %+v", event)
+	}
+	p.SetError("ListFilesystems", nil)
+
+	// known filesystems are kept on errors, so only the new one is added
+	if err := p.CreateFilesystem(ns.CreateFilesystemParams{Path: "pool/c"}); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		event := receiveFilesystemEvent(t, events)
+		if event.Type == ns.WatchEventError {
+			continue
+		}
+		if event.Type != ns.WatchEventAdded || event.Filesystem.Path != "pool/c" {
+			t.Errorf("expected pool/c to be added, got: %+v", event)
+		}
+		break
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestDatasetWatcher_Resync(t *testing.T) {
+	p := nsfake.NewProvider(nsfake.ProviderArgs{Pools: []string{"pool"}})
+	if err := p.CreateVolumeGroup(ns.CreateVolumeGroupParams{Path: "pool/vg"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.CreateVolume(ns.CreateVolumeParams{Path: "pool/vg/v1", VolumeSize: 8192}); err != nil {
+		t.Fatal(err)
+	}
+
+	watcher, err := ns.NewDatasetWatcher(ns.DatasetWatcherArgs{Provider: p, ResyncInterval: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := watcher.WatchVolumes(ctx, "pool/vg", 10*time.Millisecond)
+
+	for _, expectedType := range []ns.WatchEventType{ns.WatchEventAdded, ns.WatchEventSynced, ns.WatchEventSynced} {
+		select {
+		case event := <-events:
+			if event.Type != expectedType || event.Volume.Path != "pool/vg/v1" {
+				t.Errorf("expected %s event of pool/vg/v1, got: %+v", expectedType, event)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no %s event received", expectedType)
+		}
+	}
+
+	cancel()
+	for range events {
+	}
+
+	if _, err := ns.NewDatasetWatcher(ns.DatasetWatcherArgs{Provider: p, ResyncInterval: -1}); err == nil {
+		t.Error("expected error for negative resync interval")
+	}
+}
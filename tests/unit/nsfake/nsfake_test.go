@@ -0,0 +1,256 @@
+package nsfake_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/ns/nsfake"
+)
+
+func filesystemPaths(filesystems []ns.Filesystem) []string {
+	paths := []string{}
+	for _, fs := range filesystems {
+		paths = append(paths, fs.Path)
+	}
+	return paths
+}
+
+func mustNot(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProvider_Filesystems(t *testing.T) {
+	p := nsfake.NewProvider(nsfake.ProviderArgs{Pools: []string{"pool"}, PoolSize: 1000})
+
+	for _, path := range []string{"pool/a", "pool/b", "pool/a/c"} {
+		mustNot(t, p.CreateFilesystem(ns.CreateFilesystemParams{Path: path}))
+	}
+
+	t.Run("NEF error codes", func(t *testing.T) {
+		if err := p.CreateFilesystem(ns.CreateFilesystemParams{Path: "pool/a"}); !ns.IsAlreadyExistNefError(err) {
+			t.Errorf("expected EEXIST error, got: %v", err)
+		}
+		if err := p.CreateFilesystem(ns.CreateFilesystemParams{Path: "pool/x/y"}); !ns.IsNotExistNefError(err) {
+			t.Errorf("expected ENOENT error for missing parent, got: %v", err)
+		}
+		if _, err := p.GetFilesystem("pool/x"); !ns.IsNotExistNefError(err) {
+			t.Errorf("expected ENOENT error, got: %v", err)
+		}
+		if err := p.DestroyFilesystem("pool/a", ns.DestroyFilesystemParams{}); !ns.IsBusyNefError(err) {
+			t.Errorf("expected EBUSY error for filesystem with children, got: %v", err)
+		}
+	})
+
+	t.Run("list children", func(t *testing.T) {
+		filesystems, err := p.GetFilesystems("pool")
+		mustNot(t, err)
+		if paths := filesystemPaths(filesystems); !reflect.DeepEqual(paths, []string{"pool/a", "pool/b"}) {
+			t.Errorf("unexpected children: %v", paths)
+		}
+
+		filesystems, err = p.ListFilesystems("pool", ns.ListOptions{Recursive: true, SortBy: "path", SortOrder: "desc"})
+		mustNot(t, err)
+		if paths := filesystemPaths(filesystems); !reflect.DeepEqual(paths, []string{"pool/b", "pool/a/c", "pool/a"}) {
+			t.Errorf("unexpected descendants: %v", paths)
+		}
+	})
+
+	t.Run("iterator", func(t *testing.T) {
+		it := p.GetFilesystemIterator("pool", ns.IteratorParams{PageSize: 1})
+		paths := []string{}
+		for !it.Done() {
+			page, err := it.Next(context.Background())
+			mustNot(t, err)
+			paths = append(paths, filesystemPaths(page)...)
+		}
+		if !reflect.DeepEqual(paths, []string{"pool/a", "pool/b"}) {
+			t.Errorf("unexpected iterated filesystems: %v", paths)
+		}
+	})
+
+	t.Run("shares and quota", func(t *testing.T) {
+		mustNot(t, p.CreateNfsShare(ns.CreateNfsShareParams{Filesystem: "pool/b"}))
+		mustNot(t, p.UpdateFilesystem("pool/b", ns.UpdateFilesystemParams{ReferencedQuotaSize: 100}))
+		mustNot(t, p.SetBytesUsed("pool/b", 30))
+
+		fs, err := p.GetFilesystem("pool/b")
+		mustNot(t, err)
+		if !fs.SharedOverNfs || fs.BytesAvailable != 70 || fs.BytesUsed != 30 {
+			t.Errorf("unexpected filesystem state: %+v", fs)
+		}
+
+		shared, err := p.ListFilesystems("pool", ns.ListOptions{Filters: map[string]string{"sharedOverNfs": "true"}})
+		mustNot(t, err)
+		if paths := filesystemPaths(shared); !reflect.DeepEqual(paths, []string{"pool/b"}) {
+			t.Errorf("expected only shared filesystem to be listed, got: %v", paths)
+		}
+
+		if err := p.DeleteSmbShare("pool/b"); !ns.IsNotExistNefError(err) {
+			t.Errorf("expected ENOENT error, got: %v", err)
+		}
+	})
+}
+
+func TestProvider_SnapshotsAndClones(t *testing.T) {
+	p := nsfake.NewProvider(nsfake.ProviderArgs{Pools: []string{"pool"}})
+
+	mustNot(t, p.CreateFilesystem(ns.CreateFilesystemParams{Path: "pool/src"}))
+	for _, name := range []string{"s1", "s2", "s3"} {
+		mustNot(t, p.CreateSnapshot(ns.CreateSnapshotParams{Path: "pool/src@" + name}))
+	}
+	mustNot(t, p.CloneSnapshot("pool/src@s2", ns.CloneSnapshotParams{TargetPath: "pool/clone"}))
+
+	if err := p.DestroySnapshot("pool/src@s2"); !ns.IsBusyNefError(err) {
+		t.Errorf("expected EBUSY error for snapshot with clones, got: %v", err)
+	}
+	if err := p.DestroyFilesystem("pool/src", ns.DestroyFilesystemParams{}); !ns.IsBusyNefError(err) {
+		t.Errorf("expected EBUSY error for filesystem with snapshots, got: %v", err)
+	}
+	err := p.DestroyFilesystem("pool/src", ns.DestroyFilesystemParams{DestroySnapshots: true})
+	if !ns.IsAlreadyExistNefError(err) {
+		t.Errorf("expected EEXIST error for filesystem with clones, got: %v", err)
+	}
+
+	mustNot(t, p.DestroyFilesystem("pool/src", ns.DestroyFilesystemParams{
+		DestroySnapshots:               true,
+		PromoteMostRecentCloneIfExists: true,
+	}))
+
+	snapshots, err := p.GetSnapshots("pool/clone", false)
+	mustNot(t, err)
+	if len(snapshots) != 2 || snapshots[0].Path != "pool/clone@s1" || snapshots[1].Path != "pool/clone@s2" {
+		t.Errorf("expected promoted clone to take over snapshots s1 and s2, got: %v", snapshots)
+	}
+	if _, err := p.GetSnapshot("pool/src@s3"); !ns.IsNotExistNefError(err) {
+		t.Errorf("expected snapshot s3 to be destroyed, got: %v", err)
+	}
+	if snapshot, err := p.GetSnapshot("pool/clone@s2"); err != nil || len(snapshot.Clones) != 0 {
+		t.Errorf("expected no clones left after source deletion, got: %+v, %v", snapshot, err)
+	}
+}
+
+func TestProvider_VolumesAndLunMappings(t *testing.T) {
+	p := nsfake.NewProvider(nsfake.ProviderArgs{Pools: []string{"pool"}, PoolSize: 1 << 20})
+
+	mustNot(t, p.CreateVolumeGroup(ns.CreateVolumeGroupParams{Path: "pool/vg"}))
+	mustNot(t, p.CreateVolume(ns.CreateVolumeParams{Path: "pool/vg/v1", VolumeSize: 8192}))
+	if err := p.CreateVolume(ns.CreateVolumeParams{Path: "pool/vg/v2", VolumeSize: 2 << 20}); ns.GetNefErrorCode(err) != "ENOSPC" {
+		t.Errorf("expected ENOSPC error for thick volume larger than pool, got: %v", err)
+	}
+
+	mustNot(t, p.CreateUpdateHostGroup(ns.CreateHostGroupParams{Name: "hg", Members: []string{"iqn.host"}}))
+	mustNot(t, p.CreateUpdateTargetGroup(ns.CreateTargetGroupParams{Name: "tg", Members: []string{"iqn.target"}}))
+	mapping := ns.CreateLunMappingParams{Volume: "pool/vg/v1", HostGroup: "hg", TargetGroup: "tg"}
+	mustNot(t, p.CreateLunMapping(mapping))
+	mustNot(t, p.CreateLunMapping(mapping))
+
+	lunMappings, err := p.ListLunMappings(ns.ListLunMappingsParams{HostGroup: "hg"})
+	mustNot(t, err)
+	if len(lunMappings) != 1 || lunMappings[0].Lun != 0 {
+		t.Fatalf("expected single mapping with LUN 0, got: %v", lunMappings)
+	}
+	if lun, err := p.GetNextFreeLun("hg"); err != nil || lun != 1 {
+		t.Errorf("expected next free LUN 1, got: %d, %v", lun, err)
+	}
+
+	result, err := p.ResizeVolume("pool/vg/v1", 10000, ns.ResizeVolumeParams{})
+	mustNot(t, err)
+	if result.VolumeSize != 16384 || !result.IsLunMapped() {
+		t.Errorf("expected size rounded up to block size and LUN mapped result, got: %+v", result)
+	}
+
+	if err := p.DestroyVolume("pool/vg/v1", ns.DestroyVolumeParams{}); !ns.IsBusyNefError(err) {
+		t.Errorf("expected EBUSY error for mapped volume, got: %v", err)
+	}
+	mustNot(t, p.DestroyLunMapping(lunMappings[0].Id))
+	if err := p.DestroyVolumeGroup("pool/vg", ns.DestroyVolumeGroupParams{}); !ns.IsBusyNefError(err) {
+		t.Errorf("expected EBUSY error for non empty volumeGroup, got: %v", err)
+	}
+	mustNot(t, p.DestroyVolumeGroup("pool/vg", ns.DestroyVolumeGroupParams{Recursive: true}))
+}
+
+type testingT struct {
+	errors []string
+}
+
+func (t *testingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestProvider_CloneVolumeSnapshot(t *testing.T) {
+	p := nsfake.NewProvider(nsfake.ProviderArgs{Pools: []string{"pool"}, PoolSize: 1 << 20})
+
+	mustNot(t, p.CreateVolumeGroup(ns.CreateVolumeGroupParams{Path: "pool/vg"}))
+	mustNot(t, p.CreateVolume(ns.CreateVolumeParams{Path: "pool/vg/v", VolumeSize: 8192}))
+	mustNot(t, p.CreateSnapshot(ns.CreateSnapshotParams{Path: "pool/vg/v@s"}))
+	// volume grows after the snapshot, clones keep the snapshot size
+	mustNot(t, p.UpdateVolume("pool/vg/v", ns.UpdateVolumeParams{VolumeSize: 32768}))
+
+	clone := func(path string, size int64) (ns.Volume, error) {
+		err := p.CloneVolumeSnapshot("pool/vg/v@s", ns.CloneVolumeSnapshotParams{TargetPath: path, VolumeSize: size})
+		if err != nil {
+			return ns.Volume{}, err
+		}
+		return p.GetVolume(path)
+	}
+
+	if volume, err := clone("pool/vg/c1", 0); err != nil || volume.VolumeSize != 8192 {
+		t.Errorf("expected clone of the snapshot size, got: %+v, %v", volume, err)
+	}
+	// smaller than the current volume size, but larger than the snapshot
+	if volume, err := clone("pool/vg/c2", 16384); err != nil || volume.VolumeSize != 16384 {
+		t.Errorf("expected resized clone, got: %+v, %v", volume, err)
+	}
+
+	for path, size := range map[string]int64{"pool/vg/small": 4096, "pool/vg/large": 2 << 20} {
+		if _, err := clone(path, size); err == nil {
+			t.Errorf("expected clone '%s' of size %d to fail", path, size)
+		}
+		if _, err := p.GetVolume(path); !ns.IsNotExistNefError(err) {
+			t.Errorf("expected clone '%s' to be destroyed, got: %v", path, err)
+		}
+	}
+}
+
+func TestProvider_Calls(t *testing.T) {
+	p := nsfake.NewProvider(nsfake.ProviderArgs{Pools: []string{"pool"}})
+
+	// fake is used through the interface, as consumers do
+	var provider ns.ProviderInterface = p
+
+	provider.CreateFilesystem(ns.CreateFilesystemParams{Path: "pool/fs"})
+	provider.GetFilesystem("pool/fs")
+
+	p.AssertCalled(t, "CreateFilesystem", ns.CreateFilesystemParams{Path: "pool/fs"})
+	p.AssertNotCalled(t, "DestroyFilesystem")
+	if count := p.CallCount("GetFilesystem"); count != 1 {
+		t.Errorf("expected 1 GetFilesystem() call, got: %d", count)
+	}
+
+	fakeT := &testingT{}
+	if p.AssertCalled(fakeT, "GetFilesystem", "pool/other") || len(fakeT.errors) != 1 {
+		t.Errorf("expected assertion with other args to fail, got: %v", fakeT.errors)
+	}
+
+	injected := errors.New("connection refused")
+	p.SetError("GetPools", injected)
+	if _, err := provider.GetPools(); err != injected {
+		t.Errorf("expected injected error, got: %v", err)
+	}
+	p.SetError("GetPools", nil)
+	if pools, err := provider.GetPools(); err != nil || len(pools) != 1 {
+		t.Errorf("expected pool list after error removal, got: %v, %v", pools, err)
+	}
+
+	p.ResetCalls()
+	if calls := p.Calls(); len(calls) != 0 {
+		t.Errorf("expected no calls after reset, got: %v", calls)
+	}
+}
@@ -0,0 +1,26 @@
+package nsmock_test
+
+import (
+	"testing"
+
+	"go.uber.org/mock/gomock"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/ns/nsmock"
+)
+
+func TestMockProviderInterface(t *testing.T) {
+	// fails to compile if the mock is not regenerated after ProviderInterface changes
+	var p ns.ProviderInterface = nsmock.NewMockProviderInterface(gomock.NewController(t))
+
+	mock := p.(*nsmock.MockProviderInterface)
+	mock.EXPECT().GetFilesystem("pool/fs").Return(ns.Filesystem{Path: "pool/fs"}, nil)
+
+	filesystem, err := p.GetFilesystem("pool/fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filesystem.Path != "pool/fs" {
+		t.Errorf("expected mocked filesystem, got: %+v", filesystem)
+	}
+}
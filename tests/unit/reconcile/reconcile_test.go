@@ -0,0 +1,290 @@
+package reconcile_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/ns/nsfake"
+	"github.com/Nexenta/go-nexentastor/pkg/reconcile"
+)
+
+const testSpec = `
+parents:
+  - pool/ds
+filesystems:
+  - path: pool/ds/new
+    referencedQuotaSize: 1024
+    acl: readWrite
+    nfs: {}
+  - path: pool/ds/existing
+    referencedQuotaSize: 2048
+`
+
+func TestReconcile_ParseSpec(t *testing.T) {
+	spec, err := reconcile.ParseSpec([]byte(testSpec))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(spec.Filesystems) != 2 || spec.Filesystems[0].Nfs == nil {
+		t.Errorf("unexpected spec: %+v", spec)
+	}
+
+	for _, invalidSpec := range []string{
+		"filesystems: [{path: /pool/fs}]",
+		"filesystems: [{path: pool/fs}, {path: pool/fs}]",
+		"filesystems: [{path: pool/fs, acl: all}]",
+		"filesystems: [{path: pool/fs, unknownField: 1}]",
+	} {
+		if _, err := reconcile.ParseSpec([]byte(invalidSpec)); err == nil {
+			t.Errorf("expected an error for spec: %s", invalidSpec)
+		}
+	}
+}
+
+func TestReconcile_Plan(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch query := r.URL.Query(); {
+		case query.Get("path") == "pool/ds/existing":
+			fmt.Fprint(w, `{"data": [{"path": "pool/ds/existing", "referencedQuotaSize": 1024, "sharedOverNfs": true}]}`)
+		case query.Get("path") != "":
+			fmt.Fprint(w, `{"data": []}`)
+		case query.Get("parent") == "pool/ds":
+			fmt.Fprint(w, `{"data": [{"path": "pool/ds"}, {"path": "pool/ds/existing"}, {"path": "pool/ds/old"}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	l := logrus.New().WithField("test", t.Name())
+	l.Logger.SetLevel(logrus.PanicLevel)
+
+	nsp, err := ns.NewProvider(ns.ProviderArgs{Address: server.URL, Log: l})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := reconcile.ParseSpec([]byte(testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reconciler, err := reconcile.NewReconciler(reconcile.ReconcilerArgs{Provider: nsp, Log: l})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := reconciler.Plan(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedPlan := []string{
+		"~ update filesystem pool/ds/existing (referencedQuotaSize: 1024 -> 2048)",
+		"! delete nfsShare pool/ds/existing: protected, deletion is not allowed",
+		"+ create filesystem pool/ds/new (referencedQuotaSize: 1024)",
+		"+ create acl pool/ds/new (readWrite)",
+		"+ create nfsShare pool/ds/new",
+		"! delete filesystem pool/ds/old (with snapshots): protected, deletion is not allowed",
+		"",
+		"Plan: 3 to create, 1 to update, 0 to delete, 2 protected.",
+		"",
+	}
+	if plan.String() != strings.Join(expectedPlan, "\n") {
+		t.Errorf("unexpected plan:\n%s", plan)
+	}
+}
+
+const testApplySpec = `
+parents:
+  - pool/ds
+filesystems:
+  - path: pool/ds/a
+    referencedQuotaSize: 1048576
+    acl: readWrite
+    nfs:
+      readWriteList: [{etype: network, entity: 10.3.0.0, mask: 16}]
+  - path: pool/ds/b
+    acl: readOnly
+    smb: {shareName: b}
+`
+
+// newTestReconciler returns reconciler of in-memory NexentaStor with "pool/ds" filesystem
+func newTestReconciler(t *testing.T, allowDelete bool) (*reconcile.Reconciler, *nsfake.Provider) {
+	p := nsfake.NewProvider(nsfake.ProviderArgs{Pools: []string{"pool"}})
+	if err := p.CreateFilesystem(ns.CreateFilesystemParams{Path: "pool/ds"}); err != nil {
+		t.Fatal(err)
+	}
+
+	l := logrus.New().WithField("test", t.Name())
+	l.Logger.SetLevel(logrus.PanicLevel)
+
+	reconciler, err := reconcile.NewReconciler(reconcile.ReconcilerArgs{Provider: p, Log: l, AllowDelete: allowDelete})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return reconciler, p
+}
+
+func parseTestSpec(t *testing.T, data string) *reconcile.Spec {
+	spec, err := reconcile.ParseSpec([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return spec
+}
+
+func TestReconcile_Apply(t *testing.T) {
+	reconciler, p := newTestReconciler(t, false)
+	spec := parseTestSpec(t, testApplySpec)
+
+	t.Run("dry run", func(t *testing.T) {
+		plan, err := reconciler.Reconcile(spec, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectedPlan := []string{
+			"+ create filesystem pool/ds/a (referencedQuotaSize: 1048576)",
+			"+ create acl pool/ds/a (readWrite)",
+			"+ create nfsShare pool/ds/a",
+			"+ create filesystem pool/ds/b",
+			"+ create acl pool/ds/b (readOnly)",
+			"+ create smbShare pool/ds/b (b)",
+			"",
+			"Plan: 6 to create, 0 to update, 0 to delete, 0 protected.",
+			"",
+		}
+		if plan.String() != strings.Join(expectedPlan, "\n") {
+			t.Errorf("unexpected plan:\n%s", plan)
+		}
+
+		if _, err := p.GetFilesystem("pool/ds/a"); !ns.IsNotExistNefError(err) {
+			t.Errorf("expected dry run not to create filesystems, got: %v", err)
+		}
+	})
+
+	t.Run("apply", func(t *testing.T) {
+		if _, err := reconciler.Reconcile(spec, false); err != nil {
+			t.Fatal(err)
+		}
+
+		fs, err := p.GetFilesystem("pool/ds/a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fs.ReferencedQuotaSize != 1048576 || !fs.SharedOverNfs || fs.SharedOverSmb {
+			t.Errorf("unexpected filesystem: %+v", fs)
+		}
+		if shareName, err := p.GetSmbShareName("pool/ds/b"); err != nil || shareName != "b" {
+			t.Errorf("expected SMB share 'b', got '%s', error: %v", shareName, err)
+		}
+	})
+
+	t.Run("re-apply", func(t *testing.T) {
+		plan, err := reconciler.Reconcile(spec, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !plan.IsEmpty() {
+			t.Errorf("expected no changes on re-apply, got:\n%s", plan)
+		}
+	})
+
+	t.Run("changed NFS rules and ACL", func(t *testing.T) {
+		changedSpec := parseTestSpec(t, strings.NewReplacer(
+			"acl: readOnly", "acl: readWrite",
+			"readWriteList: [{etype: network, entity: 10.3.0.0, mask: 16}]", "readOnlyList: [{etype: fqdn, entity: '*'}]",
+		).Replace(testApplySpec))
+
+		plan, err := reconciler.Reconcile(changedSpec, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectedPlan := []string{
+			"~ update nfsShare pool/ds/a (sys rw=10.3.0.0/16 ro=none -> sys rw=none ro=*)",
+			"~ update acl pool/ds/b (readOnly -> readWrite)",
+			"",
+			"Plan: 0 to create, 2 to update, 0 to delete, 0 protected.",
+			"",
+		}
+		if plan.String() != strings.Join(expectedPlan, "\n") {
+			t.Errorf("unexpected plan:\n%s", plan)
+		}
+
+		rules, err := p.GetFilesystemACL("pool/ds/b")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rules) != 1 || rules[0].Permissions[0] != "full_set" {
+			t.Errorf("expected readOnly ACL entry to be replaced, got: %+v", rules)
+		}
+
+		if plan, err := reconciler.Plan(changedSpec); err != nil || !plan.IsEmpty() {
+			t.Errorf("expected no changes after update, got: %v, error: %v", plan, err)
+		}
+	})
+}
+
+func TestReconcile_ProtectedDelete(t *testing.T) {
+	spec := parseTestSpec(t, `
+parents: [pool/ds]
+filesystems:
+  - path: pool/ds/kept
+`)
+
+	for _, allowDelete := range []bool{false, true} {
+		t.Run(fmt.Sprintf("allowDelete=%t", allowDelete), func(t *testing.T) {
+			reconciler, p := newTestReconciler(t, allowDelete)
+			for _, path := range []string{"pool/ds/kept", "pool/ds/old"} {
+				if err := p.CreateFilesystem(ns.CreateFilesystemParams{Path: path}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := p.CreateNfsShare(ns.CreateNfsShareParams{Filesystem: "pool/ds/kept"}); err != nil {
+				t.Fatal(err)
+			}
+
+			plan, err := reconciler.Reconcile(spec, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expectedCount := 0
+			if allowDelete {
+				expectedCount = 2
+			}
+			if plan.Count(reconcile.ActionDelete) != expectedCount {
+				t.Errorf("expected %d deletions, got:\n%s", expectedCount, plan)
+			}
+
+			_, err = p.GetFilesystem("pool/ds/old")
+			if deleted := ns.IsNotExistNefError(err); deleted != allowDelete {
+				t.Errorf("expected filesystem deletion: %t, got error: %v", allowDelete, err)
+			}
+			fs, err := p.GetFilesystem("pool/ds/kept")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fs.SharedOverNfs == allowDelete {
+				t.Errorf("expected NFS share deletion: %t, got: %+v", allowDelete, fs)
+			}
+
+			// protected actions stay in the plan until deletion is allowed
+			plan, err = reconciler.Plan(spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if plan.IsEmpty() == !allowDelete {
+				t.Errorf("unexpected plan after apply:\n%s", plan)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package report_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/ns/nsfake"
+	"github.com/Nexenta/go-nexentastor/pkg/report"
+)
+
+const tenantProperty = "com.example:tenant"
+
+var reportTime = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestProvider(t *testing.T) *nsfake.Provider {
+	p := nsfake.NewProvider(nsfake.ProviderArgs{Pools: []string{"pool"}, PoolSize: 10000})
+
+	filesystems := []struct {
+		path       string
+		quota      int64
+		used       int64
+		snapshots  int64
+		ratio      float64
+		properties map[string]string
+	}{
+		{"pool/a", 1000, 950, 100, 2, map[string]string{tenantProperty: "acme"}},
+		{"pool/a/home", 0, 300, 50, 1, map[string]string{tenantProperty: "acme"}},
+		{"pool/b", 0, 500, 0, 1.5, map[string]string{tenantProperty: "globex"}},
+		{"pool/c", 2000, 100, 0, 1, nil},
+	}
+	for _, fs := range filesystems {
+		if err := p.CreateFilesystem(ns.CreateFilesystemParams{Path: fs.path, ReferencedQuotaSize: fs.quota}); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.SetBytesUsed(fs.path, fs.used); err != nil {
+			t.Fatal(err)
+		}
+		err := p.SetProperties(fs.path, nsfake.Properties{
+			BytesUsedBySnapshots: fs.snapshots,
+			CompressionRatio:     fs.ratio,
+			UserProperties:       fs.properties,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := p.CreateVolumeGroup(ns.CreateVolumeGroupParams{Path: "pool/vg"}); err != nil {
+		t.Fatal(err)
+	}
+	err := p.CreateVolume(ns.CreateVolumeParams{Path: "pool/vg/v1", VolumeSize: 8192, SparseVolume: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetBytesUsed("pool/vg/v1", 8000); err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func generate(t *testing.T, args report.ReporterArgs) *report.Report {
+	t.Helper()
+
+	args.Now = func() time.Time { return reportTime }
+	reporter, err := report.NewReporter(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := reporter.Generate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestReporter_Generate(t *testing.T) {
+	p := newTestProvider(t)
+
+	t.Run("datasets and flagged quota utilization", func(t *testing.T) {
+		r := generate(t, report.ReporterArgs{Provider: p, VolumeGroups: []string{"pool/vg"}, Concurrency: 2})
+
+		paths := []string{}
+		for _, dataset := range r.Datasets {
+			paths = append(paths, dataset.Path)
+		}
+		expectedPaths := "pool/a,pool/a/home,pool/b,pool/c,pool/vg/v1"
+		if strings.Join(paths, ",") != expectedPaths {
+			t.Errorf("expected datasets %s, got: %v", expectedPaths, paths)
+		}
+
+		flagged := r.Flagged()
+		if len(flagged) != 2 || flagged[0].Path != "pool/a" || flagged[1].Path != "pool/vg/v1" {
+			t.Errorf("expected pool/a and pool/vg/v1 to be over quota threshold, got: %+v", flagged)
+		}
+		if flagged[1].Type != report.TypeVolume || flagged[1].QuotaSize != 8192 {
+			t.Errorf("expected volume size to be used as quota, got: %+v", flagged[1])
+		}
+		if len(r.Groups) != 0 {
+			t.Errorf("expected no groups without GroupBy, got: %+v", r.Groups)
+		}
+		if len(r.Pools) != 1 || r.Pools[0].Name != "pool" {
+			t.Errorf("unexpected pools: %+v", r.Pools)
+		}
+	})
+
+	t.Run("group by user property", func(t *testing.T) {
+		r := generate(t, report.ReporterArgs{
+			Provider:       p,
+			QuotaThreshold: 0.99,
+			GroupBy:        report.GroupByUserProperty(tenantProperty),
+		})
+
+		if len(r.Flagged()) != 0 {
+			t.Errorf("expected no datasets over 99%% threshold, got: %+v", r.Flagged())
+		}
+		if len(r.Groups) != 2 {
+			t.Fatalf("expected acme and globex groups, got: %+v", r.Groups)
+		}
+
+		// pool/a/home usage is included into pool/a usage, snapshots are counted for each dataset
+		acme := r.Groups[0]
+		if acme.Key != "acme" || acme.Datasets != 2 || acme.BytesUsed != 950 ||
+			acme.BytesUsedBySnapshots != 150 || acme.QuotaSize != 1000 || acme.CompressionRatio != 2 {
+			t.Errorf("unexpected acme group: %+v", acme)
+		}
+	})
+
+	t.Run("group by path prefix", func(t *testing.T) {
+		r := generate(t, report.ReporterArgs{
+			Provider: p,
+			Parents:  []string{"pool"},
+			GroupBy:  report.GroupByPathPrefix("pool", "pool/b/"),
+		})
+
+		if len(r.Groups) != 2 || r.Groups[0].Key != "pool" || r.Groups[1].Key != "pool/b" {
+			t.Fatalf("unexpected groups: %+v", r.Groups)
+		}
+		// volumeGroups under parents are discovered, pool/vg/v1 is in the pool group too
+		if group := r.Groups[0]; group.Datasets != 4 || group.BytesUsed != 9050 || group.CompressionRatio == 0 {
+			t.Errorf("unexpected pool group: %+v", group)
+		}
+	})
+
+	t.Run("volumeGroups of all pools are discovered", func(t *testing.T) {
+		p := newTestProvider(t)
+		if err := p.CreateFilesystem(ns.CreateFilesystemParams{Path: "pool/d"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.CreateVolumeGroup(ns.CreateVolumeGroupParams{Path: "pool/d/vg"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.CreateVolume(ns.CreateVolumeParams{Path: "pool/d/vg/v2", VolumeSize: 8192, SparseVolume: true}); err != nil {
+			t.Fatal(err)
+		}
+
+		volumes := []string{}
+		for _, dataset := range generate(t, report.ReporterArgs{Provider: p}).Datasets {
+			if dataset.Type == report.TypeVolume {
+				volumes = append(volumes, dataset.Path)
+			}
+		}
+		if strings.Join(volumes, ",") != "pool/d/vg/v2,pool/vg/v1" {
+			t.Errorf("expected volumes of all volumeGroups, got: %v", volumes)
+		}
+
+		volumes = []string{}
+		for _, dataset := range generate(t, report.ReporterArgs{Provider: p, Parents: []string{"pool/d"}}).Datasets {
+			if dataset.Type == report.TypeVolume {
+				volumes = append(volumes, dataset.Path)
+			}
+		}
+		if strings.Join(volumes, ",") != "pool/d/vg/v2" {
+			t.Errorf("expected volumes under pool/d only, got: %v", volumes)
+		}
+	})
+
+	t.Run("zero value reporter", func(t *testing.T) {
+		r, err := (&report.Reporter{Provider: p}).Generate(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Time.IsZero() || r.QuotaThreshold != 0.9 || len(r.Datasets) != 5 {
+			t.Errorf("expected defaults to be used, got: %+v", r)
+		}
+
+		if _, err := (&report.Reporter{}).Generate(context.Background()); err == nil {
+			t.Error("expected error for reporter without provider")
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		if _, err := report.NewReporter(report.ReporterArgs{Provider: p, QuotaThreshold: 2}); err == nil {
+			t.Error("expected error for quota threshold over 1")
+		}
+
+		injected := errors.New("connection refused")
+		p.SetError("ListFilesystems", injected)
+		defer p.SetError("ListFilesystems", nil)
+
+		reporter, err := report.NewReporter(report.ReporterArgs{Provider: p})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := reporter.Generate(context.Background()); err != injected {
+			t.Errorf("expected list error, got: %v", err)
+		}
+	})
+}
+
+func TestReport_Write(t *testing.T) {
+	r := generate(t, report.ReporterArgs{
+		Provider: newTestProvider(t),
+		GroupBy:  report.GroupByUserProperty(tenantProperty),
+	})
+
+	var buf bytes.Buffer
+	if err := r.WriteDatasetsCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 6 || lines[1] != "pool/a,filesystem,acme,950,50,950,100,2.00,1000,0.95,true" {
+		t.Errorf("unexpected datasets CSV:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := r.WriteGroupsCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 3 ||
+		lines[2] != "globex,1,0,500,150,0,0,1.50" {
+		t.Errorf("unexpected groups CSV:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := r.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	decoded := report.Report{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	} else if !decoded.Time.Equal(reportTime) || len(decoded.Datasets) != len(r.Datasets) {
+		t.Errorf("unexpected decoded report: %+v", decoded)
+	}
+}
@@ -0,0 +1,187 @@
+package rest_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Nexenta/go-nexentastor/pkg/rest"
+)
+
+func recordTestCassette(t *testing.T, path string) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch strings.TrimLeft(r.URL.Path, "/") {
+		case "auth/login":
+			fmt.Fprint(w, `{"token": "secrettoken"}`)
+		case "storage/pools":
+			fmt.Fprint(w, `{"data": [{"poolName": "pool"}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"code": "ENOENT"}`)
+		}
+	}))
+	defer server.Close()
+
+	recorder, err := rest.NewRecorder(rest.RecorderArgs{CassettePath: path, Mode: rest.RecorderModeRecord})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := newTestClient(t, rest.ClientArgs{Address: server.URL, WrapTransport: recorder.Wrap})
+	requests := []struct {
+		method string
+		path   string
+		data   interface{}
+	}{
+		{http.MethodPost, "auth/login", map[string]string{"username": "admin", "password": "secretpass"}},
+		{http.MethodGet, "storage/pools", nil},
+		{http.MethodGet, "storage/filesystems/pool%2Ffs?fields=path", nil},
+	}
+	for _, req := range requests {
+		if _, _, err := client.Send(req.method, req.path, req.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nexentastor-cassette")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cassettePath := filepath.Join(dir, "cassette.json")
+	recordTestCassette(t, cassettePath)
+
+	t.Run("secrets are scrubbed", func(t *testing.T) {
+		data, err := ioutil.ReadFile(cassettePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, secret := range []string{"secretpass", "secrettoken"} {
+			if strings.Contains(string(data), secret) {
+				t.Errorf("expected '%s' to be scrubbed from cassette, got: %s", secret, data)
+			}
+		}
+	})
+
+	// server is closed, so all responses come from the cassette
+	address := "http://127.0.0.1:1"
+
+	t.Run("strict replay", func(t *testing.T) {
+		recorder, err := rest.NewRecorder(rest.RecorderArgs{CassettePath: cassettePath})
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := newTestClient(t, rest.ClientArgs{Address: address, WrapTransport: recorder.Wrap})
+
+		data := map[string]string{"username": "admin", "password": "otherpass"}
+		if _, _, err := client.Send(http.MethodPost, "auth/login", data); err != nil {
+			t.Fatalf("expected login with any password to match, got: %s", err)
+		}
+		if _, _, err := client.Send(http.MethodGet, "storage/filesystems/pool%2Ffs?fields=path", nil); err == nil {
+			t.Fatal("expected out of order request error, got none")
+		}
+
+		statusCode, body, err := client.Send(http.MethodGet, "storage/pools", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var pools struct {
+			Data []struct {
+				PoolName string `json:"poolName"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(body, &pools); err != nil {
+			t.Fatal(err)
+		}
+		if statusCode != http.StatusOK || len(pools.Data) != 1 || pools.Data[0].PoolName != "pool" {
+			t.Errorf("unexpected replayed response: %d %s", statusCode, body)
+		}
+		if unused := recorder.Unused(); len(unused) != 1 {
+			t.Errorf("expected 1 unused interaction, got: %v", unused)
+		}
+	})
+
+	t.Run("lenient replay", func(t *testing.T) {
+		recorder, err := rest.NewRecorder(rest.RecorderArgs{
+			CassettePath: cassettePath,
+			Matching:     rest.RecorderMatchLenient,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := newTestClient(t, rest.ClientArgs{Address: address, WrapTransport: recorder.Wrap})
+
+		statusCode, _, err := client.Send(http.MethodGet, "storage/filesystems/pool%2Ffs", nil)
+		if err != nil || statusCode != http.StatusNotFound {
+			t.Fatalf("expected recorded 404 response, got: %d %v", statusCode, err)
+		}
+		for i := 0; i < 2; i++ {
+			if _, _, err := client.Send(http.MethodGet, "storage/pools", nil); err != nil {
+				t.Fatalf("expected interaction to be repeated, got: %s", err)
+			}
+		}
+		if _, _, err := client.Send(http.MethodDelete, "storage/pools", nil); err == nil {
+			t.Error("expected unmatched request error, got none")
+		}
+	})
+}
+
+func TestRecorder_LargeNumbers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nexentastor-cassette")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cassettePath := filepath.Join(dir, "cassette.json")
+
+	// above 2^53, rounded to 9007199254740992 as float64
+	const size = 9007199254740993
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"data": [{"path": "pool/vg/v", "volumeSize": %d}]}`, int64(size))
+	}))
+	defer server.Close()
+
+	recorder, err := rest.NewRecorder(rest.RecorderArgs{CassettePath: cassettePath, Mode: rest.RecorderModeRecord})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newTestClient(t, rest.ClientArgs{Address: server.URL, WrapTransport: recorder.Wrap})
+	if _, _, err := client.Send(http.MethodPut, "storage/volumes", map[string]int64{"volumeSize": size}); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(cassettePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := strings.Count(string(data), "9007199254740993"); count != 2 {
+		t.Errorf("expected request and response sizes to be saved exactly, got: %s", data)
+	}
+
+	recorder, err = rest.NewRecorder(rest.RecorderArgs{CassettePath: cassettePath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = newTestClient(t, rest.ClientArgs{Address: "http://127.0.0.1:1", WrapTransport: recorder.Wrap})
+	_, _, err = client.Send(http.MethodPut, "storage/volumes", map[string]int64{"volumeSize": size - 1})
+	if err == nil {
+		t.Error("expected request with different size not to match in strict mode, got no error")
+	}
+}
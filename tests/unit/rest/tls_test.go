@@ -0,0 +1,179 @@
+package rest_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Nexenta/go-nexentastor/pkg/rest"
+)
+
+func newTLSTestServer() *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+}
+
+func certificatePEM(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
+
+// newSelfSignedCertificate returns CA certificate which didn't issue test server certificate
+func newSelfSignedCertificate(t *testing.T) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "other CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestClient_TLS(t *testing.T) {
+	server := newTLSTestServer()
+	defer server.Close()
+
+	serverCert := server.Certificate()
+
+	tests := map[string]struct {
+		options rest.TLSOptions
+		ok      bool
+	}{
+		"untrusted server certificate": {
+			options: rest.TLSOptions{},
+			ok:      false,
+		},
+		"CA PEM": {
+			options: rest.TLSOptions{CAPEM: certificatePEM(serverCert)},
+			ok:      true,
+		},
+		"CA PEM and matching SPKI pin": {
+			options: rest.TLSOptions{
+				CAPEM:    certificatePEM(serverCert),
+				SPKIPins: []string{"d3Jvbmc=", rest.SPKIFingerprint(serverCert)},
+			},
+			ok: true,
+		},
+		"CA PEM and wrong SPKI pin": {
+			options: rest.TLSOptions{
+				CAPEM:    certificatePEM(serverCert),
+				SPKIPins: []string{"d3Jvbmc="},
+			},
+			ok: false,
+		},
+		"wrong server name": {
+			options: rest.TLSOptions{CAPEM: certificatePEM(serverCert), ServerName: "nexentastor.local"},
+			ok:      false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := newTestClient(t, rest.ClientArgs{Address: server.URL, TLS: test.options}).Send(http.MethodGet, "storage/pools", nil)
+			if test.ok && err != nil {
+				t.Errorf("expected request to succeed, got: %s", err)
+			} else if !test.ok && err == nil {
+				t.Error("expected TLS error, got none")
+			}
+		})
+	}
+}
+
+func TestClient_TLSCAFileRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nexentastor-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	server := newTLSTestServer()
+	defer server.Close()
+
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := ioutil.WriteFile(caFile, []byte("not a certificate\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	client := newTestClient(t, rest.ClientArgs{Address: server.URL, TLS: rest.TLSOptions{CAFile: caFile}})
+	if _, _, err := client.Send(http.MethodGet, "storage/pools", nil); err == nil {
+		t.Fatal("expected TLS error with invalid CA file, got none")
+	}
+
+	if err := ioutil.WriteFile(caFile, certificatePEM(server.Certificate()), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := client.Send(http.MethodGet, "storage/pools", nil); err != nil {
+		t.Errorf("expected rotated CA file to be used, got: %s", err)
+	}
+}
+
+func TestClient_TLSBaseRootCAs(t *testing.T) {
+	server := newTLSTestServer()
+	defer server.Close()
+
+	serverCert := server.Certificate()
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCert)
+
+	tests := map[string]rest.TLSOptions{
+		"SPKI pin":             {SPKIPins: []string{rest.SPKIFingerprint(serverCert)}},
+		"CA PEM of another CA": {CAPEM: certificatePEM(newSelfSignedCertificate(t))},
+	}
+
+	for name, options := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := newTestClient(t, rest.ClientArgs{
+				Address:   server.URL,
+				TLSConfig: &tls.Config{RootCAs: roots},
+				TLS:       options,
+			})
+			if _, _, err := client.Send(http.MethodGet, "storage/pools", nil); err != nil {
+				t.Errorf("expected RootCAs of base TLS config to be trusted, got: %s", err)
+			}
+		})
+	}
+}
+
+func TestNewClientE(t *testing.T) {
+	tests := map[string]rest.ClientArgs{
+		"missing CA file":  {TLS: rest.TLSOptions{CAFile: "/nonexistent/ca.pem"}},
+		"invalid CA PEM":   {TLS: rest.TLSOptions{CAPEM: []byte("not a certificate")}},
+		"missing key file": {TLS: rest.TLSOptions{CertFile: "/nonexistent/client.pem"}},
+	}
+
+	for name, args := range tests {
+		t.Run(name, func(t *testing.T) {
+			args.Address = "https://nexentastor.local:8443"
+			if _, err := rest.NewClientE(args); err == nil {
+				t.Error("expected configuration error, got none")
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package rest_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Nexenta/go-nexentastor/pkg/rest"
+)
+
+func newTestClient(t *testing.T, args rest.ClientArgs) rest.ContextClient {
+	l := logrus.New().WithField("test", t.Name())
+	l.Logger.SetLevel(logrus.PanicLevel)
+	args.Log = l
+	return rest.NewClient(args).(rest.ContextClient)
+}
+
+// roundTripperFunc - http.RoundTripper implemented by a function
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestClient_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, rest.ClientArgs{Address: server.URL, Timeout: 50 * time.Millisecond})
+
+	if _, _, err := client.Send(http.MethodGet, "storage/pools", nil); err == nil {
+		t.Error("expected client timeout error, got none")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, _, err := client.SendWithContext(ctx, http.MethodGet, "storage/pools", nil); err != nil {
+		t.Errorf("expected request deadline to override client timeout, got: %s", err)
+	}
+}
+
+func TestClient_Proxy(t *testing.T) {
+	var proxiedHost string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxiedHost = r.URL.Host
+		w.Write([]byte(`{}`))
+	}))
+	defer proxy.Close()
+
+	client := newTestClient(t, rest.ClientArgs{
+		Address:   "http://nexentastor.local:8443",
+		Transport: rest.TransportOptions{Proxy: proxy.URL},
+	})
+
+	if _, _, err := client.Send(http.MethodGet, "storage/pools", nil); err != nil {
+		t.Fatal(err)
+	}
+	if proxiedHost != "nexentastor.local:8443" {
+		t.Errorf("expected request to be sent through the proxy, proxy got host: '%s'", proxiedHost)
+	}
+}
+
+func TestClient_InvalidProxy(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	args := rest.ClientArgs{
+		Address:   server.URL,
+		Transport: rest.TransportOptions{Proxy: "proxy:3128"},
+	}
+
+	if _, err := rest.NewClientE(args); err == nil {
+		t.Error("expected invalid proxy URL error, got none")
+	}
+
+	client := newTestClient(t, args)
+	if _, _, err := client.Send(http.MethodGet, "storage/pools", nil); err == nil {
+		t.Error("expected request to fail with invalid proxy URL, got none")
+	}
+	if atomic.LoadInt32(&requests) != 0 {
+		t.Error("expected request not to be sent directly with invalid proxy URL")
+	}
+}
+
+func TestClient_RoundTripper(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"custom": false}`))
+	}))
+	defer server.Close()
+
+	t.Run("wrapped default transport", func(t *testing.T) {
+		var wrappedRequests int64
+		client := newTestClient(t, rest.ClientArgs{
+			Address: server.URL,
+			WrapTransport: func(rt http.RoundTripper) http.RoundTripper {
+				return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+					atomic.AddInt64(&wrappedRequests, 1)
+					return rt.RoundTrip(req)
+				})
+			},
+		})
+
+		if _, _, err := client.Send(http.MethodGet, "storage/pools", nil); err != nil {
+			t.Fatal(err)
+		}
+		if atomic.LoadInt64(&wrappedRequests) != 1 {
+			t.Errorf("expected 1 request through the wrapper, got: %d", wrappedRequests)
+		}
+	})
+
+	t.Run("custom transport", func(t *testing.T) {
+		client := newTestClient(t, rest.ClientArgs{
+			Address: server.URL,
+			RoundTripper: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				recorder := httptest.NewRecorder()
+				recorder.Write([]byte(`{"custom": true}`))
+				return recorder.Result(), nil
+			}),
+		})
+
+		_, body, err := client.Send(http.MethodGet, "storage/pools", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != `{"custom": true}` {
+			t.Errorf("expected response of custom transport, got: '%s'", body)
+		}
+	})
+}